@@ -0,0 +1,133 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyWeight is the weight of the latest success in the average latency of AdaptiveRetry
+const latencyWeight = 0.2
+
+// AdaptiveRetry adapts the retries of every Retryer and executor sharing it to the recent outcome
+// of their attempts, as the adaptive retry mode of the AWS SDKs does. It tracks the failure rate
+// of the last attempts and the average latency of the successful ones:
+//   - the policy delays are stretched by up to maxFactor times as the failure rate grows, and
+//     are never shorter than the average latency, so a struggling dependency gets more room
+//   - no retry is made while the failure rate is at least stopRate, since they're unlikely to succeed.
+//     The retries only stop once the window is full, so a few failures after a start don't stop them
+//
+// An AdaptiveRetry is safe for concurrent use
+type AdaptiveRetry struct {
+	maxFactor float64
+	stopRate  float64
+
+	mu       sync.Mutex
+	outcomes []bool
+	next     int
+	samples  int
+	failures int
+	latency  time.Duration
+}
+
+// AdaptiveState is the state of an AdaptiveRetry
+type AdaptiveState struct {
+	// Samples is the number of attempts the failure rate is computed from, up to the window
+	Samples int
+	// FailureRate is the ratio of the failed attempts among the Samples
+	FailureRate float64
+	// Latency is the average latency of the successful attempts
+	Latency time.Duration
+	// DelayFactor is what the policy delays are multiplied by
+	DelayFactor float64
+	// Retrying reports whether the retries are made
+	Retrying bool
+}
+
+// NewAdaptiveRetry returns an AdaptiveRetry tracking the last window attempts. The policy delays
+// are multiplied by up to maxFactor, and the retries stop while at least stopRate of the attempts
+// fail, i.e: 0.9. The retries never stop with a stopRate above 1
+func NewAdaptiveRetry(window int, maxFactor, stopRate float64) *AdaptiveRetry {
+	if window < 1 {
+		window = 1
+	}
+	if maxFactor < 1 {
+		maxFactor = 1
+	}
+	return &AdaptiveRetry{maxFactor: maxFactor, stopRate: stopRate, outcomes: make([]bool, window)}
+}
+
+// WithAdaptiveRetry adapts the delays and the retries to the outcome of the recent attempts
+// tracked by a, see AdaptiveRetry. A retry is refused with ReasonAdaptive
+func WithAdaptiveRetry(a *AdaptiveRetry) Option {
+	return func(o *options) {
+		o.adaptive = a
+	}
+}
+
+// State returns the current state of a
+func (a *AdaptiveRetry) State() AdaptiveState {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rate := a.failureRate()
+	return AdaptiveState{
+		Samples:     a.samples,
+		FailureRate: rate,
+		Latency:     a.latency,
+		DelayFactor: a.factor(rate),
+		Retrying:    a.retrying(rate),
+	}
+}
+
+// observe records the outcome of an attempt that took d
+func (a *AdaptiveRetry) observe(failed bool, d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.samples == len(a.outcomes) {
+		if a.outcomes[a.next] {
+			a.failures--
+		}
+	} else {
+		a.samples++
+	}
+	a.outcomes[a.next] = failed
+	a.next = (a.next + 1) % len(a.outcomes)
+	if failed {
+		a.failures++
+		return
+	}
+	if a.latency == 0 {
+		a.latency = d
+	} else {
+		a.latency += time.Duration(latencyWeight * float64(d-a.latency))
+	}
+}
+
+// adapt returns delay adapted to the recent attempts, and false if no retry should be made
+func (a *AdaptiveRetry) adapt(delay time.Duration) (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rate := a.failureRate()
+	if !a.retrying(rate) {
+		return delay, false
+	}
+	delay = time.Duration(float64(delay) * a.factor(rate))
+	if delay < a.latency {
+		delay = a.latency
+	}
+	return delay, true
+}
+
+func (a *AdaptiveRetry) failureRate() float64 {
+	if a.samples == 0 {
+		return 0
+	}
+	return float64(a.failures) / float64(a.samples)
+}
+
+func (a *AdaptiveRetry) retrying(rate float64) bool {
+	return a.samples < len(a.outcomes) || rate < a.stopRate
+}
+
+func (a *AdaptiveRetry) factor(rate float64) float64 {
+	return 1 + rate*(a.maxFactor-1)
+}
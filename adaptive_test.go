@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveRetry(t *testing.T) {
+	a := NewAdaptiveRetry(4, 3, 0.9)
+	assert.Equal(t, AdaptiveState{DelayFactor: 1, Retrying: true}, a.State())
+	a.observe(false, time.Millisecond*10)
+	a.observe(false, time.Millisecond*20)
+	a.observe(true, 0)
+	a.observe(true, 0)
+	state := a.State()
+	assert.Equal(t, 4, state.Samples)
+	assert.Equal(t, 0.5, state.FailureRate)
+	assert.Equal(t, time.Millisecond*12, state.Latency)
+	assert.Equal(t, 2.0, state.DelayFactor)
+	assert.Equal(t, true, state.Retrying)
+
+	delay, ok := a.adapt(time.Second)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, time.Second*2, delay)
+	// the delay is never shorter than the average latency
+	delay, _ = a.adapt(time.Millisecond)
+	assert.Equal(t, time.Millisecond*12, delay)
+
+	// the oldest outcomes leave the window
+	a.observe(true, 0)
+	a.observe(true, 0)
+	state = a.State()
+	assert.Equal(t, 1.0, state.FailureRate)
+	assert.Equal(t, false, state.Retrying)
+	_, ok = a.adapt(time.Second)
+	assert.Equal(t, false, ok)
+}
+
+func TestWithAdaptiveRetry(t *testing.T) {
+	a := NewAdaptiveRetry(2, 2, 1)
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errTestSentinel
+	}, WithAttempts(5), WithDelay(time.Millisecond), WithAdaptiveRetry(a))
+	// the retries stop once both attempts of the window failed
+	assert.Equal(t, errTestSentinel, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, ReasonAdaptive, Explain(errTestSentinel, WithAdaptiveRetry(a)).Reason)
+}
@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"context"
+)
+
+// Future is the outcome of an operation retried in the background by ExecutorAsync or ExecutorTAsync
+type Future[T any] struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	result T
+	err    error
+}
+
+// ExecutorAsync is like Do but runs in a new goroutine, the outcome is collected with the returned Future
+func ExecutorAsync(ctx context.Context, fn FuncContext, opts ...Option) *Future[struct{}] {
+	return ExecutorTAsync(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts...)
+}
+
+// ExecutorTAsync is the generic version of ExecutorAsync, so the value of the operation can be
+// collected with Wait
+func ExecutorTAsync[T any](ctx context.Context, fn FuncTContext[T], opts ...Option) *Future[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	f := &Future[T]{done: make(chan struct{}), cancel: cancel}
+	o := newOptions(opts)
+	go func() {
+		defer close(f.done)
+		defer cancel()
+		f.result, f.err = execute(ctx, o, fn)
+	}()
+	return f
+}
+
+// Wait blocks until the operation is done and returns its outcome
+func (f *Future[T]) Wait() (T, error) {
+	<-f.done
+	return f.result, f.err
+}
+
+// Err blocks until the operation is done and returns its error
+func (f *Future[T]) Err() error {
+	<-f.done
+	return f.err
+}
+
+// Done returns a channel that's closed when the operation is done
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Cancel cancels the context of the operation, so the retry stops. Wait still has to be
+// called to know the outcome, since the operation may have succeeded already
+func (f *Future[T]) Cancel() {
+	f.cancel()
+}
@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorAsync(t *testing.T) {
+	var calls int
+	f := ExecutorAsync(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("something else")
+		}
+		return nil
+	}, WithDelay(time.Millisecond*10))
+	<-f.Done()
+	assert.Equal(t, true, f.Err() == nil)
+	assert.Equal(t, 3, calls)
+}
+
+func TestExecutorTAsync(t *testing.T) {
+	f := ExecutorTAsync(context.Background(), func(ctx context.Context) (string, error) {
+		return "value", nil
+	})
+	v, err := f.Wait()
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, "value", v)
+}
+
+func TestFutureCancel(t *testing.T) {
+	f := ExecutorAsync(context.Background(), func(ctx context.Context) error {
+		return errors.New("something else")
+	}, WithDelay(time.Hour))
+	f.Cancel()
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the future should be done once cancelled")
+	}
+	assert.Equal(t, context.Canceled, f.Err())
+}
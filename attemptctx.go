@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"context"
+)
+
+// AttemptInfo describes the attempt an operation is called for, see AttemptInfoFromContext
+type AttemptInfo struct {
+	// Attempt is the number of the attempt starting at 1
+	Attempt int
+	// Policy is the policy that allowed the retry, valid when PolicyIndex isn't -1
+	Policy Policy
+	// PolicyIndex is the index of Policy in the policies of the executor, -1 for the first attempt
+	PolicyIndex int
+	// RemainingRetries is the number of retries Policy allows after this attempt, -1 for the first attempt
+	RemainingRetries int
+}
+
+type attemptKey struct{}
+
+// attemptContext is a context carrying the AttemptInfo of an attempt. It's a single allocation,
+// context.WithValue would allocate the boxed info too
+type attemptContext struct {
+	context.Context
+	info AttemptInfo
+}
+
+// Value returns a *AttemptInfo for attemptKey
+func (c *attemptContext) Value(key any) any {
+	if _, ok := key.(attemptKey); ok {
+		return &c.info
+	}
+	return c.Context.Value(key)
+}
+
+// withAttemptInfo returns a copy of ctx carrying info
+func withAttemptInfo(ctx context.Context, info AttemptInfo) context.Context {
+	return &attemptContext{Context: ctx, info: info}
+}
+
+// firstAttempt is the AttemptInfo of the first attempt
+var firstAttempt = AttemptInfo{Attempt: 1, PolicyIndex: -1, RemainingRetries: -1}
+
+// backgroundAttempt and todoAttempt are the contexts of the first attempts made with context.Background()
+// and context.TODO(), which are shared since an attemptContext is immutable
+var (
+	backgroundAttempt = &attemptContext{Context: context.Background(), info: firstAttempt}
+	todoAttempt       = &attemptContext{Context: context.TODO(), info: firstAttempt}
+)
+
+// withFirstAttempt returns a copy of ctx carrying the AttemptInfo of a first attempt
+func withFirstAttempt(ctx context.Context) context.Context {
+	switch ctx {
+	case backgroundAttempt.Context:
+		return backgroundAttempt
+	case todoAttempt.Context:
+		return todoAttempt
+	}
+	return withAttemptInfo(ctx, firstAttempt)
+}
+
+// AttemptInfoFromContext returns the attempt the context passed to an operation by the executors
+// is made for, so deep call stacks and middlewares can tag logs or outgoing requests per attempt.
+// It returns false if ctx doesn't come from an executor
+func AttemptInfoFromContext(ctx context.Context) (AttemptInfo, bool) {
+	info, ok := ctx.Value(attemptKey{}).(*AttemptInfo)
+	if !ok {
+		return AttemptInfo{}, false
+	}
+	return *info, true
+}
+
+// AttemptFromContext returns the number of the attempt the context passed to an operation by the
+// executors is made for, starting at 1, i.e: to set an X-Retry-Attempt header. It returns 0 if
+// ctx doesn't come from an executor
+func AttemptFromContext(ctx context.Context) int {
+	info, _ := AttemptInfoFromContext(ctx)
+	return info.Attempt
+}
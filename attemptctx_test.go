@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptInfoFromContext(t *testing.T) {
+	var infos []AttemptInfo
+	err := Do(context.Background(), func(ctx context.Context) error {
+		info, ok := AttemptInfoFromContext(ctx)
+		assert.Equal(t, true, ok)
+		infos = append(infos, info)
+		return errTestSentinel
+	}, WithAttempts(3), WithDelay(time.Millisecond))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, len(infos))
+	assert.Equal(t, AttemptInfo{Attempt: 1, PolicyIndex: -1, RemainingRetries: -1}, infos[0])
+	assert.Equal(t, 2, infos[1].Attempt)
+	assert.Equal(t, 0, infos[1].PolicyIndex)
+	assert.Equal(t, 1, infos[1].RemainingRetries)
+	assert.Equal(t, 3, infos[2].Attempt)
+	assert.Equal(t, 0, infos[2].RemainingRetries)
+
+	_, ok := AttemptInfoFromContext(context.Background())
+	assert.Equal(t, false, ok)
+	assert.Equal(t, 0, AttemptFromContext(context.Background()))
+}
+
+func TestAttemptFromContextHeader(t *testing.T) {
+	var headers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("X-Retry-Attempt"))
+		if len(headers) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	resp, err := ExecutorHTTPResponseWithPoliciesContext(context.Background(), testTransportPolicies, func(ctx context.Context) (*http.Response, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		req.Header.Set("X-Retry-Attempt", strconv.Itoa(AttemptFromContext(ctx)))
+		return http.DefaultClient.Do(req)
+	})
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, []string{"1", "2"}, headers)
+}
@@ -0,0 +1,131 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffType is an enum for how the delay grows between retry attempts
+type BackoffType int
+
+const (
+	// ConstantBackoff waits DelayDuration before every retry
+	ConstantBackoff BackoffType = iota
+
+	// LinearBackoff waits DelayDuration multiplied by the retry attempt
+	LinearBackoff
+
+	// ExponentialBackoff doubles the delay on every retry attempt, starting from DelayDuration.
+	// The growth factor can be changed with Policy.Multiplier
+	ExponentialBackoff
+)
+
+// backoffDelay returns the delay before the given retry attempt (starting at 1),
+// computed from the policy's Backoff and clamped between MinDelay and MaxDelay when they're set
+func (p Policy) backoffDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	var delay time.Duration
+	switch p.Backoff {
+	case LinearBackoff:
+		delay = scaleDuration(p.DelayDuration, float64(attempt))
+	case ExponentialBackoff:
+		multiplier := p.Multiplier
+		if multiplier <= 0 {
+			multiplier = 2
+		}
+		delay = scaleDuration(p.DelayDuration, math.Pow(multiplier, float64(attempt-1)))
+	default:
+		delay = p.DelayDuration
+	}
+	return p.clamp(delay)
+}
+
+// clamp returns delay capped by MaxDelay and floored by MinDelay, the floor wins if it's above the cap
+func (p Policy) clamp(delay time.Duration) time.Duration {
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay < p.MinDelay {
+		delay = p.MinDelay
+	}
+	return delay
+}
+
+// Schedule returns the delays before the first n retries of the policy without the jitter, i.e: to
+// document the configured behavior, plan the capacity, or assert it in tests. The RetryLimit isn't
+// applied. FullJitter and EqualJitter only shorten these delays, while DecorrelatedJitter doesn't
+// use them, see JitteredSchedule
+func (p Policy) Schedule(n int) []time.Duration {
+	delays := make([]time.Duration, 0, max(n, 0))
+	for attempt := 1; attempt <= n; attempt++ {
+		delays = append(delays, p.backoffDelay(attempt))
+	}
+	return delays
+}
+
+// JitteredSchedule is Schedule with the jitter, drawn from src as the executors do with WithRandSource(src)
+func (p Policy) JitteredSchedule(n int, src rand.Source) []time.Duration {
+	r := rand.New(src)
+	delays := make([]time.Duration, 0, max(n, 0))
+	var prev time.Duration
+	for attempt := 1; attempt <= n; attempt++ {
+		prev = p.nextDelayRand(attempt, prev, r.Int63n)
+		delays = append(delays, prev)
+	}
+	return delays
+}
+
+// maxDuration is the longest representable time.Duration
+const maxDuration = time.Duration(math.MaxInt64)
+
+// scaleDuration multiplies d by factor, saturating instead of overflowing
+func scaleDuration(d time.Duration, factor float64) time.Duration {
+	f := float64(d) * factor
+	if f >= math.MaxInt64 {
+		return maxDuration
+	}
+	return time.Duration(f)
+}
+
+// Backoff computes the delays of a retry loop driven by the caller, i.e: a consumer reconnecting
+// for as long as it runs, with the same Backoff, MaxDelay and Jitter math as the executors.
+// It's not safe for concurrent use
+type Backoff struct {
+	policy  Policy
+	attempt int
+	prev    time.Duration
+	int63n  func(int64) int64
+}
+
+// NewBackoff returns a Backoff computing the delays of policy, its RetryLimit and
+// matching fields are ignored. The jitter is drawn from the global source of math/rand
+func NewBackoff(policy Policy) *Backoff {
+	return &Backoff{policy: policy, int63n: rand.Int63n}
+}
+
+// NewBackoffSource is NewBackoff drawing the jitter from src, i.e: rand.NewSource(1) for
+// reproducible delays, as the executors do with WithRandSource(src)
+func NewBackoffSource(policy Policy, src rand.Source) *Backoff {
+	return &Backoff{policy: policy, int63n: rand.New(src).Int63n}
+}
+
+// Next returns the delay to wait before the next retry
+func (b *Backoff) Next() time.Duration {
+	b.attempt++
+	b.prev = b.policy.nextDelayRand(b.attempt, b.prev, b.int63n)
+	return b.prev
+}
+
+// Attempt returns the number of delays returned by Next since the last Reset
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Reset starts the delays over from DelayDuration, i.e: once the operation succeeded
+func (b *Backoff) Reset() {
+	b.attempt = 0
+	b.prev = 0
+}
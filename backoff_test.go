@@ -0,0 +1,134 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelayConstant(t *testing.T) {
+	p := Policy{DelayDuration: time.Second}
+	assert.Equal(t, time.Second, p.backoffDelay(1))
+	assert.Equal(t, time.Second, p.backoffDelay(5))
+}
+
+func TestBackoffDelayLinear(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: LinearBackoff}
+	assert.Equal(t, time.Second, p.backoffDelay(1))
+	assert.Equal(t, time.Second*2, p.backoffDelay(2))
+	assert.Equal(t, time.Second*3, p.backoffDelay(3))
+}
+
+func TestBackoffDelayExponential(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff}
+	assert.Equal(t, time.Second, p.backoffDelay(1))
+	assert.Equal(t, time.Second*2, p.backoffDelay(2))
+	assert.Equal(t, time.Second*4, p.backoffDelay(3))
+	assert.Equal(t, time.Second*8, p.backoffDelay(4))
+}
+
+func TestBackoffDelayMaxDelay(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff, MaxDelay: time.Second * 5}
+	assert.Equal(t, time.Second*4, p.backoffDelay(3))
+	assert.Equal(t, time.Second*5, p.backoffDelay(4))
+	// very large attempts must saturate at the cap instead of overflowing
+	assert.Equal(t, time.Second*5, p.backoffDelay(200))
+}
+
+func TestExecutorWithPoliciesExponentialBackoff(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      3,
+			Backoff:         ExponentialBackoff,
+		},
+	}
+	// 10ms + 20ms + 40ms of waiting before the operation recovers
+	indexTestTimedout = 1
+	start := time.Now()
+	err := ExecutorWithPolicies(policies, func() error {
+		return testTimedout(3)
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, true, time.Since(start) >= time.Millisecond*70)
+}
+
+func TestBackoffDelayMultiplier(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff, Multiplier: 1.5}
+	assert.Equal(t, time.Second, p.backoffDelay(1))
+	assert.Equal(t, time.Millisecond*1500, p.backoffDelay(2))
+	assert.Equal(t, time.Millisecond*2250, p.backoffDelay(3))
+}
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff, MaxDelay: time.Second * 3})
+	assert.Equal(t, time.Second, b.Next())
+	assert.Equal(t, time.Second*2, b.Next())
+	assert.Equal(t, time.Second*3, b.Next())
+	assert.Equal(t, 3, b.Attempt())
+
+	b.Reset()
+	assert.Equal(t, 0, b.Attempt())
+	assert.Equal(t, time.Second, b.Next())
+}
+
+func TestBackoffSource(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff, Jitter: FullJitter}
+	b := NewBackoffSource(p, rand.NewSource(1))
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		delays = append(delays, b.Next())
+	}
+	assert.Equal(t, p.JitteredSchedule(5, rand.NewSource(1)), delays)
+}
+
+func TestBackoffDecorrelatedJitter(t *testing.T) {
+	b := NewBackoff(Policy{DelayDuration: time.Millisecond * 10, Jitter: DecorrelatedJitter, MaxDelay: time.Second})
+	prev := time.Millisecond * 10
+	for i := 0; i < 20; i++ {
+		delay := b.Next()
+		assert.Equal(t, true, delay >= time.Millisecond*10 && delay <= prev*3 && delay <= time.Second)
+		prev = delay
+		if prev < time.Millisecond*10 {
+			prev = time.Millisecond * 10
+		}
+	}
+}
+
+func TestPolicySchedule(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff, MaxDelay: time.Second * 5, Jitter: FullJitter}
+	assert.Equal(t, []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 5}, p.Schedule(4))
+	assert.Equal(t, []time.Duration{}, p.Schedule(0))
+
+	jittered := p.JitteredSchedule(4, rand.NewSource(1))
+	assert.Equal(t, 4, len(jittered))
+	for i, delay := range p.Schedule(4) {
+		assert.Equal(t, true, jittered[i] >= 0 && jittered[i] <= delay)
+	}
+	// the same source draws the same delays
+	assert.Equal(t, jittered, p.JitteredSchedule(4, rand.NewSource(1)))
+}
+
+func TestPolicyMinDelay(t *testing.T) {
+	p := Policy{DelayDuration: time.Millisecond * 100, Backoff: ExponentialBackoff, MinDelay: time.Millisecond * 150, MaxDelay: time.Millisecond * 300}
+	assert.Equal(t, []time.Duration{time.Millisecond * 150, time.Millisecond * 200, time.Millisecond * 300}, p.Schedule(3))
+
+	// the jitter can't go below the floor
+	p.Jitter = FullJitter
+	for _, d := range p.JitteredSchedule(100, rand.NewSource(1)) {
+		assert.Equal(t, true, d >= p.MinDelay && d <= p.MaxDelay, d.String())
+	}
+
+	var delays []time.Duration
+	_ = Do(context.Background(), func(ctx context.Context) error {
+		return errors.New("flaky")
+	}, WithAttempts(3), WithDelay(0), WithJitter(FullJitter), WithMinDelay(time.Millisecond), WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		delays = append(delays, nextDelay)
+	}))
+	assert.Equal(t, []time.Duration{time.Millisecond, time.Millisecond}, delays)
+}
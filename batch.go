@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// BatchResult is the outcome of one operation of ExecutorTBatch
+type BatchResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// ExecutorBatch runs fns with at most concurrency of them at a time, and retries each one as
+// configured by opts, see Do. The error of every operation is returned at its index, nil on success,
+// along with an aggregate of the failures that is nil if every operation succeeded.
+// A concurrency of zero or less runs every operation at once
+func ExecutorBatch(ctx context.Context, concurrency int, fns []FuncContext, opts ...Option) ([]error, error) {
+	tfns := make([]FuncTContext[struct{}], len(fns))
+	for i, fn := range fns {
+		fn := fn
+		tfns[i] = func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, fn(ctx)
+		}
+	}
+	results, err := ExecutorTBatch(ctx, concurrency, tfns, opts...)
+	errs := make([]error, len(results))
+	for i, r := range results {
+		errs[i] = r.Err
+	}
+	return errs, err
+}
+
+// ExecutorTBatch is the generic version of ExecutorBatch, the value of every operation is
+// returned with its error
+func ExecutorTBatch[T any](ctx context.Context, concurrency int, fns []FuncTContext[T], opts ...Option) ([]BatchResult[T], error) {
+	if concurrency <= 0 || concurrency > len(fns) {
+		concurrency = len(fns)
+	}
+	o := newOptions(opts)
+	results := make([]BatchResult[T], len(fns))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i].Value, results[i].Err = execute(ctx, o, fns[i])
+			}
+		}()
+	}
+	for i := range fns {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs []error
+	for i, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("operation %d: %w", i, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorBatch(t *testing.T) {
+	var running, maxRunning int32
+	var calls [5]int32
+	fns := make([]FuncContext, 5)
+	for i := range fns {
+		i := i
+		fns[i] = func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 5)
+			// every operation fails once, the last one always fails
+			if atomic.AddInt32(&calls[i], 1) < 2 || i == 4 {
+				return errTestSentinel
+			}
+			return nil
+		}
+	}
+	errs, err := ExecutorBatch(context.Background(), 2, fns, WithAttempts(3), WithDelay(time.Millisecond))
+	assert.Equal(t, []error{nil, nil, nil, nil, &ExhaustedError{Attempts: 3, LastErr: errTestSentinel}}, errs)
+	assert.Equal(t, true, errors.Is(err, errTestSentinel))
+	assert.Equal(t, "operation 4: sentinel", err.Error())
+	assert.Equal(t, true, atomic.LoadInt32(&maxRunning) <= 2)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls[4]))
+}
+
+func TestExecutorTBatch(t *testing.T) {
+	fns := []FuncTContext[int]{
+		func(ctx context.Context) (int, error) { return 1, nil },
+		func(ctx context.Context) (int, error) { return 2, nil },
+	}
+	results, err := ExecutorTBatch(context.Background(), 0, fns)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, []BatchResult[int]{{Value: 1}, {Value: 2}}, results)
+}
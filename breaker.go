@@ -0,0 +1,161 @@
+package retry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// HostBreakers tracks the requests of a Transport per host, so a failing upstream doesn't use
+// retries, and the retry budget, that the healthy hosts could use. Once Threshold requests in a
+// row to a host failed, its breaker opens and the requests to the host are sent once without
+// retries until Cooldown passes. The next request is then retried again, and either closes
+// the breaker on success or opens it for another Cooldown on failure.
+// A request fails with a transport error, or with a response matching the policies of the Transport.
+// It's safe for concurrent use
+type HostBreakers struct {
+	threshold int
+	cooldown  time.Duration
+	clock     Clock
+
+	mu    sync.Mutex
+	hosts map[string]*HostState
+	// hubs receive the breaker events, see WithBreakerEvents
+	hubs []*eventHub
+}
+
+// HostState is the breaker state of a host
+type HostState struct {
+	// Failures is the number of requests in a row that failed
+	Failures int
+	// OpenUntil is when the breaker lets retries through again, zero if it's closed
+	OpenUntil time.Time
+}
+
+// Open reports whether the requests to the host aren't retried at now
+func (s HostState) Open(now time.Time) bool {
+	return now.Before(s.OpenUntil)
+}
+
+// NewHostBreakers returns HostBreakers opening the breaker of a host for cooldown
+// after threshold requests in a row to it failed
+func NewHostBreakers(threshold int, cooldown time.Duration) *HostBreakers {
+	return &HostBreakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     realClock{},
+		hosts:     map[string]*HostState{},
+	}
+}
+
+// State returns the state of the breaker of host, such as "example.com:8080"
+func (b *HostBreakers) State(host string) HostState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, ok := b.hosts[host]; ok {
+		return *s
+	}
+	return HostState{}
+}
+
+// Hosts returns the hosts with a state, sorted
+func (b *HostBreakers) Hosts() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	hosts := make([]string, 0, len(b.hosts))
+	for host := range b.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Reset closes the breaker of host and forgets its failures
+func (b *HostBreakers) Reset(host string) {
+	b.mu.Lock()
+	s, ok := b.hosts[host]
+	delete(b.hosts, host)
+	hubs := b.hubs
+	b.mu.Unlock()
+	if ok && !s.OpenUntil.IsZero() {
+		emitBreakerEvent(hubs, Event{Type: EventBreakerClosed, Host: host, Time: b.clock.Now()})
+	}
+}
+
+// watch emits the breaker events to h
+func (b *HostBreakers) watch(h *eventHub) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hubs = append(b.hubs, h)
+}
+
+// unwatch stops emitting the breaker events to h
+func (b *HostBreakers) unwatch(h *eventHub) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, hub := range b.hubs {
+		if hub == h {
+			b.hubs = append(b.hubs[:i:i], b.hubs[i+1:]...)
+			return
+		}
+	}
+}
+
+// emitBreakerEvent sends e to hubs
+func emitBreakerEvent(hubs []*eventHub, e Event) {
+	for _, h := range hubs {
+		h.emit(e)
+	}
+}
+
+// allow reports whether the requests to host can be retried
+func (b *HostBreakers) allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.hosts[host]
+	return !ok || !s.Open(b.clock.Now())
+}
+
+// record counts the outcome of a request to host, and emits the event of the breaker if it opens or closes
+func (b *HostBreakers) record(host string, failed bool) {
+	now := b.clock.Now()
+	b.mu.Lock()
+	event := b.update(host, failed, now)
+	hubs := b.hubs
+	b.mu.Unlock()
+	if event != nil {
+		emitBreakerEvent(hubs, *event)
+	}
+}
+
+// update counts the outcome of a request to host at now, it returns the event of the breaker if it opens or closes.
+// It's called with the mutex held
+func (b *HostBreakers) update(host string, failed bool, now time.Time) *Event {
+	s, ok := b.hosts[host]
+	if !failed {
+		delete(b.hosts, host)
+		if ok && !s.OpenUntil.IsZero() {
+			return &Event{Type: EventBreakerClosed, Host: host, Time: now}
+		}
+		return nil
+	}
+	if !ok {
+		s = &HostState{}
+		b.hosts[host] = s
+	}
+	if s.Open(now) {
+		// sent without retries while open, it doesn't extend the cooldown
+		return nil
+	}
+	s.Failures++
+	if s.Failures < b.threshold {
+		return nil
+	}
+	wasOpened := !s.OpenUntil.IsZero()
+	s.OpenUntil = now.Add(b.cooldown)
+	if wasOpened {
+		// the request after the cooldown failed too, the breaker stays open
+		return nil
+	}
+	return &Event{Type: EventBreakerOpened, Host: host, Delay: b.cooldown, Time: now}
+}
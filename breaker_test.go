@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportHostBreakers(t *testing.T) {
+	var failingCalls, healthyCalls int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failingCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&healthyCalls, 1)
+	}))
+	defer healthy.Close()
+
+	clock := &testClock{now: time.Now()}
+	breakers := NewHostBreakers(2, time.Minute)
+	breakers.clock = clock
+	client := &http.Client{Transport: &Transport{Policies: testTransportPolicies, Breakers: breakers}}
+	host := func(rawURL string) string {
+		u, _ := url.Parse(rawURL)
+		return u.Host
+	}
+
+	// two failed requests of 4 attempts each open the breaker
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(failing.URL)
+		assert.Equal(t, true, err == nil)
+		resp.Body.Close()
+	}
+	assert.Equal(t, int32(8), atomic.LoadInt32(&failingCalls))
+	state := breakers.State(host(failing.URL))
+	assert.Equal(t, 2, state.Failures)
+	assert.Equal(t, true, state.Open(clock.Now()))
+
+	// while it's open the requests are sent once
+	resp, err := client.Get(failing.URL)
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(9), atomic.LoadInt32(&failingCalls))
+
+	// the other hosts aren't affected
+	resp, err = client.Get(healthy.URL)
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, []string{host(failing.URL)}, breakers.Hosts())
+
+	// after the cooldown the requests are retried again
+	clock.now = clock.now.Add(time.Minute)
+	resp, err = client.Get(failing.URL)
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, int32(13), atomic.LoadInt32(&failingCalls))
+	assert.Equal(t, true, breakers.State(host(failing.URL)).Open(clock.Now()))
+
+	breakers.Reset(host(failing.URL))
+	assert.Equal(t, HostState{}, breakers.State(host(failing.URL)))
+	assert.Equal(t, 0, len(breakers.Hosts()))
+}
+
+func TestTransportHostBreakersRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	breakers := NewHostBreakers(1, time.Minute)
+	policies := []Policy{{RetryIf: func(err error) bool { return true }, DelayDuration: time.Millisecond, RetryLimit: 1}}
+	client := &http.Client{Transport: &Transport{Policies: policies, Breakers: breakers}}
+	resp, err := client.Get(server.URL)
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	// a redirect isn't a failure of the host
+	assert.Equal(t, 0, len(breakers.Hosts()))
+}
+
+func TestBreakerEvents(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	breakers := NewHostBreakers(2, time.Minute)
+	breakers.clock = clock
+	r := NewRetryer(WithBreakerEvents(breakers))
+	events := r.Subscribe()
+
+	breakers.record("a", true)
+	breakers.record("a", true)
+	// sent while open, then after the cooldown failing again
+	breakers.record("a", true)
+	clock.now = clock.now.Add(time.Minute)
+	breakers.record("a", true)
+	clock.now = clock.now.Add(time.Minute)
+	breakers.record("a", false)
+	breakers.record("b", false)
+	breakers.record("b", true)
+	breakers.record("b", true)
+	breakers.Reset("b")
+
+	assert.Equal(t, nil, r.Close(context.Background()))
+	var got []Event
+	for e := range events {
+		got = append(got, Event{Type: e.Type, Host: e.Host, Delay: e.Delay})
+	}
+	assert.Equal(t, []Event{
+		{Type: EventBreakerOpened, Host: "a", Delay: time.Minute},
+		{Type: EventBreakerClosed, Host: "a"},
+		{Type: EventBreakerOpened, Host: "b", Delay: time.Minute},
+		{Type: EventBreakerClosed, Host: "b"},
+	}, got)
+	assert.Equal(t, "breaker-opened", EventBreakerOpened.String())
+
+	// the closed Retryer doesn't receive them anymore
+	breakers.record("c", true)
+	breakers.record("c", true)
+	assert.Equal(t, 0, len(breakers.hubs))
+}
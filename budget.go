@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrRetryBudgetExhausted is wrapped with the error of the last attempt when a retry is
+// refused because the RetryBudget has no token left
+var ErrRetryBudgetExhausted = errors.New("retry: retry budget exhausted")
+
+// RetryBudget limits the extra load retries can add on a dependency, across every Retryer and
+// executor sharing it. Every operation deposits ratio tokens and every retry withdraws one,
+// so a ratio of 0.2 lets retries add at most 20% of extra requests once the initial tokens are spent.
+// A RetryBudget is safe for concurrent use
+type RetryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	max    float64
+	tokens float64
+}
+
+// NewRetryBudget returns a RetryBudget depositing ratio tokens per operation and holding at most
+// burst tokens. It starts full, so up to burst retries are allowed before any operation ran
+func NewRetryBudget(ratio float64, burst int) *RetryBudget {
+	return &RetryBudget{ratio: ratio, max: float64(burst), tokens: float64(burst)}
+}
+
+// WithRetryBudget makes every retry withdraw a token from b, a retry is refused with
+// ErrRetryBudgetExhausted when none is left
+func WithRetryBudget(b *RetryBudget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
+
+// Tokens returns the number of tokens left
+func (b *RetryBudget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+// deposit is called for every operation
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// withdraw takes a token for a retry, it reports false if none is left
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// budgetExhaustedError wraps err with ErrRetryBudgetExhausted
+func budgetExhaustedError(err error) error {
+	return fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+}
@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBudget(t *testing.T) {
+	b := NewRetryBudget(0.5, 2)
+	assert.Equal(t, float64(2), b.Tokens())
+	assert.Equal(t, true, b.withdraw())
+	assert.Equal(t, true, b.withdraw())
+	assert.Equal(t, false, b.withdraw())
+	b.deposit()
+	assert.Equal(t, false, b.withdraw())
+	b.deposit()
+	assert.Equal(t, true, b.withdraw())
+	// deposits are capped by burst
+	for i := 0; i < 10; i++ {
+		b.deposit()
+	}
+	assert.Equal(t, float64(2), b.Tokens())
+}
+
+func TestWithRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(0.1, 3)
+	r1 := NewRetryer(WithAttempts(3), WithDelay(time.Millisecond), WithRetryBudget(budget))
+	r2 := NewRetryer(WithAttempts(3), WithDelay(time.Millisecond), WithRetryBudget(budget))
+
+	var calls int
+	fn := func(ctx context.Context) error {
+		calls++
+		return errTestSentinel
+	}
+	// the first operation uses 2 retries, leaving a single token
+	err := r1.Run(context.Background(), fn)
+	assert.Equal(t, &ExhaustedError{Attempts: 3, LastErr: errTestSentinel}, err)
+	assert.Equal(t, 3, calls)
+
+	// the second one, on another Retryer, can only retry once
+	calls = 0
+	err = r2.Run(context.Background(), fn)
+	assert.Equal(t, true, errors.Is(err, ErrRetryBudgetExhausted))
+	assert.Equal(t, true, errors.Is(err, errTestSentinel))
+	assert.Equal(t, 2, calls)
+
+	// the budget is spent, retries turn into immediate failures
+	calls = 0
+	err = r1.Run(context.Background(), fn)
+	assert.Equal(t, true, errors.Is(err, ErrRetryBudgetExhausted))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,32 @@
+package retry
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestBuildPlatforms builds the module for the platforms without the unix errnos and signals,
+// see errno_unix.go
+func TestBuildPlatforms(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds for other platforms")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+	for _, platform := range []struct{ goos, goarch string }{
+		{"js", "wasm"},
+		{"wasip1", "wasm"},
+		{"windows", "amd64"},
+		{"plan9", "amd64"},
+	} {
+		cmd := exec.Command(gobin, "build", "./...")
+		cmd.Env = append(os.Environ(), "GOOS="+platform.goos, "GOARCH="+platform.goarch)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("GOOS=%s GOARCH=%s go build ./...: %v\n%s", platform.goos, platform.goarch, err, out)
+		}
+	}
+}
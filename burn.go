@@ -0,0 +1,195 @@
+package retry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// burnBuckets is the number of buckets a burn window is divided in, the window slides by one every size/burnBuckets
+const burnBuckets = 60
+
+// defaultBurnWindows are the windows of Retryer.BurnRates
+var defaultBurnWindows = []time.Duration{time.Minute, time.Minute * 5, time.Hour}
+
+// BurnRate is how much a Retryer's operations burn the error budget of their dependency over a sliding window
+type BurnRate struct {
+	// Window is the duration of the window
+	Window time.Duration
+	// Operations is the number of operations finished within the window
+	Operations int64
+	// Retried is the ratio of the Operations that needed at least one retry
+	Retried float64
+	// Exhausted is the ratio of the Operations given up on while a policy matched their error,
+	// see PolicyStats.Exhaustions
+	Exhausted float64
+}
+
+// BurnAlert reports when the operations of a Retryer burn the error budget of their dependency too
+// fast over a sliding window, i.e: to trip an alarm or to degrade a feature
+type BurnAlert struct {
+	// Window is the duration of the sliding window
+	Window time.Duration
+	// MinOperations is the number of operations within the window below which the alert doesn't fire,
+	// so a few failures of a quiet dependency don't
+	MinOperations int64
+	// RetriedThreshold fires the alert when the Retried ratio reaches it, zero doesn't
+	RetriedThreshold float64
+	// ExhaustedThreshold fires the alert when the Exhausted ratio reaches it, zero doesn't
+	ExhaustedThreshold float64
+	// OnBurn is called with burning true when the alert fires, and with false once it resolves.
+	// It's called synchronously by the operation crossing the threshold, so it must be quick
+	OnBurn func(rate BurnRate, burning bool)
+}
+
+// WithBurnAlert adds alert to a Retryer, it's evaluated whenever an operation finishes.
+// The executors without a Retryer ignore it
+func WithBurnAlert(alert BurnAlert) Option {
+	return func(o *options) {
+		o.burnAlerts = append(o.burnAlerts, alert)
+	}
+}
+
+// BurnRates returns the burn rates of the operations of the Retryer over the last minute,
+// five minutes and hour, and over the windows of its BurnAlert, the shortest window first
+func (r *Retryer) BurnRates() []BurnRate {
+	return r.opts.burn.rates(r.opts.clock.Now())
+}
+
+// burnCounts are the operations finished within a period
+type burnCounts struct {
+	operations int64
+	retried    int64
+	exhausted  int64
+}
+
+func (c *burnCounts) add(other burnCounts) {
+	c.operations += other.operations
+	c.retried += other.retried
+	c.exhausted += other.exhausted
+}
+
+func (c *burnCounts) sub(other burnCounts) {
+	c.operations -= other.operations
+	c.retried -= other.retried
+	c.exhausted -= other.exhausted
+}
+
+// burnWindow counts the operations of a sliding window in buckets
+type burnWindow struct {
+	size    time.Duration
+	buckets [burnBuckets]burnCounts
+	// at is the period of the newest bucket, in bucket widths since the epoch
+	at    int64
+	total burnCounts
+}
+
+// advance slides the window to now, emptying the buckets of the periods that left it
+func (w *burnWindow) advance(now time.Time) {
+	width := int64(w.size) / burnBuckets
+	if width < 1 {
+		width = 1
+	}
+	at := now.UnixNano() / width
+	if at <= w.at {
+		return
+	}
+	if at-w.at >= burnBuckets {
+		w.buckets = [burnBuckets]burnCounts{}
+		w.total = burnCounts{}
+	} else {
+		for i := w.at + 1; i <= at; i++ {
+			b := &w.buckets[i%burnBuckets]
+			w.total.sub(*b)
+			*b = burnCounts{}
+		}
+	}
+	w.at = at
+}
+
+func (w *burnWindow) rate() BurnRate {
+	rate := BurnRate{Window: w.size, Operations: w.total.operations}
+	if rate.Operations > 0 {
+		rate.Retried = float64(w.total.retried) / float64(rate.Operations)
+		rate.Exhausted = float64(w.total.exhausted) / float64(rate.Operations)
+	}
+	return rate
+}
+
+// burnTracker tracks the burn rates of a Retryer and fires its alerts
+type burnTracker struct {
+	mu      sync.Mutex
+	windows map[time.Duration]*burnWindow
+	alerts  []BurnAlert
+	burning []bool
+}
+
+func newBurnTracker(alerts []BurnAlert) *burnTracker {
+	t := &burnTracker{windows: map[time.Duration]*burnWindow{}, alerts: alerts, burning: make([]bool, len(alerts))}
+	for _, size := range defaultBurnWindows {
+		t.windows[size] = &burnWindow{size: size}
+	}
+	for _, alert := range alerts {
+		if _, ok := t.windows[alert.Window]; !ok && alert.Window > 0 {
+			t.windows[alert.Window] = &burnWindow{size: alert.Window}
+		}
+	}
+	return t
+}
+
+// record counts an operation finished at now, then fires or resolves the alerts
+func (t *burnTracker) record(now time.Time, retried, exhausted bool) {
+	c := burnCounts{operations: 1}
+	if retried {
+		c.retried = 1
+	}
+	if exhausted {
+		c.exhausted = 1
+	}
+	type change struct {
+		alert   BurnAlert
+		rate    BurnRate
+		burning bool
+	}
+	var changes []change
+	t.mu.Lock()
+	for _, w := range t.windows {
+		w.advance(now)
+		w.buckets[w.at%burnBuckets].add(c)
+		w.total.add(c)
+	}
+	for i, alert := range t.alerts {
+		w, ok := t.windows[alert.Window]
+		if !ok {
+			continue
+		}
+		rate := w.rate()
+		burning := rate.Operations >= alert.MinOperations &&
+			(alert.RetriedThreshold > 0 && rate.Retried >= alert.RetriedThreshold ||
+				alert.ExhaustedThreshold > 0 && rate.Exhausted >= alert.ExhaustedThreshold)
+		if burning != t.burning[i] {
+			t.burning[i] = burning
+			changes = append(changes, change{alert: alert, rate: rate, burning: burning})
+		}
+	}
+	t.mu.Unlock()
+	for _, c := range changes {
+		if c.alert.OnBurn != nil {
+			c.alert.OnBurn(c.rate, c.burning)
+		}
+	}
+}
+
+func (t *burnTracker) rates(now time.Time) []BurnRate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rates := make([]BurnRate, 0, len(t.windows))
+	for _, w := range t.windows {
+		w.advance(now)
+		rates = append(rates, w.rate())
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Window < rates[j].Window
+	})
+	return rates
+}
@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryerBurnRates(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	type alert struct {
+		rate    BurnRate
+		burning bool
+	}
+	var alerts []alert
+	r := NewRetryer(WithAttempts(2), WithDelay(time.Millisecond), WithClock(clock), WithBurnAlert(BurnAlert{
+		Window:             time.Minute,
+		MinOperations:      2,
+		ExhaustedThreshold: 0.5,
+		OnBurn: func(rate BurnRate, burning bool) {
+			alerts = append(alerts, alert{rate: rate, burning: burning})
+		},
+	}))
+	fail := func(ctx context.Context) error {
+		return errors.New("something else")
+	}
+	succeed := func(ctx context.Context) error {
+		return nil
+	}
+
+	assert.Equal(t, true, r.Run(context.Background(), fail) != nil)
+	// below MinOperations
+	assert.Equal(t, 0, len(alerts))
+	assert.Equal(t, true, r.Run(context.Background(), succeed) == nil)
+	assert.Equal(t, []alert{{rate: BurnRate{Window: time.Minute, Operations: 2, Retried: 0.5, Exhausted: 0.5}, burning: true}}, alerts)
+
+	rates := r.BurnRates()
+	assert.Equal(t, 3, len(rates))
+	assert.Equal(t, time.Minute, rates[0].Window)
+	assert.Equal(t, time.Hour, rates[2].Window)
+	assert.Equal(t, int64(2), rates[2].Operations)
+
+	// the failure leaves the minute window but not the hour
+	clock.now = clock.now.Add(time.Minute * 2)
+	assert.Equal(t, true, r.Run(context.Background(), succeed) == nil)
+	assert.Equal(t, 2, len(alerts))
+	assert.Equal(t, false, alerts[1].burning)
+	rates = r.BurnRates()
+	assert.Equal(t, BurnRate{Window: time.Minute, Operations: 1}, rates[0])
+	assert.Equal(t, int64(3), rates[2].Operations)
+	assert.Equal(t, 1/3.0, rates[2].Exhausted)
+}
@@ -0,0 +1,103 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache keeps the last successful values of the operations run with RunWithCache, i.e: in memory or Redis.
+// MemoryCache is provided. Its methods may be called from multiple goroutines
+type Cache[T any] interface {
+	// Get returns the entry of key, and false if there's none
+	Get(ctx context.Context, key string) (CacheEntry[T], bool, error)
+	// Set inserts or replaces the entry of key
+	Set(ctx context.Context, key string, entry CacheEntry[T]) error
+}
+
+// CacheEntry is a value kept in a Cache
+type CacheEntry[T any] struct {
+	Value T
+	// StoredAt is when the operation returned Value
+	StoredAt time.Time
+}
+
+// Cached is the value returned by RunWithCache
+type Cached[T any] struct {
+	Value T
+	// Stale reports whether Value is the cached value of a previous run because the operation failed
+	Stale bool
+	// Staleness is how old Value is, zero unless it's Stale
+	Staleness time.Duration
+	// Err is the error the operation failed with, nil unless Value is Stale
+	Err error
+}
+
+// WithMaxStaleness keeps RunWithCache from falling back to a cached value older than d, the operation's
+// error is returned instead. Zero, the default, accepts any cached value. The other executors ignore it
+func WithMaxStaleness(d time.Duration) Option {
+	return func(o *options) {
+		o.maxStaleness = d
+	}
+}
+
+// RunWithCache runs fn and retries it as configured by opts, see Do. Its successful value is stored
+// in cache under key and returned. When fn fails for good, the value cached by a previous run is
+// returned instead, as Stale along with the error, see WithMaxStaleness. The error is only returned
+// when there's no cached value to fall back to, when reading the cache fails, or when ctx is done.
+// A failure to store the value in the cache doesn't fail the run
+func RunWithCache[T any](ctx context.Context, key string, fn FuncTContext[T], cache Cache[T], opts ...Option) (Cached[T], error) {
+	o := newOptions(opts)
+	value, err := execute(ctx, o, fn)
+	if err == nil {
+		_ = cache.Set(ctx, key, CacheEntry[T]{Value: value, StoredAt: o.clock.Now()})
+		return Cached[T]{Value: value}, nil
+	}
+	if ctx.Err() != nil {
+		return Cached[T]{}, err
+	}
+	entry, ok, cerr := cache.Get(ctx, key)
+	if cerr != nil || !ok {
+		return Cached[T]{}, err
+	}
+	staleness := o.clock.Now().Sub(entry.StoredAt)
+	if o.maxStaleness > 0 && staleness > o.maxStaleness {
+		return Cached[T]{}, err
+	}
+	return Cached[T]{Value: entry.Value, Stale: true, Staleness: staleness, Err: err}, nil
+}
+
+// MemoryCache is a Cache keeping the values in memory, they don't survive the process.
+// It's safe for concurrent use
+type MemoryCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry[T]
+}
+
+// NewMemoryCache returns an empty MemoryCache
+func NewMemoryCache[T any]() *MemoryCache[T] {
+	return &MemoryCache[T]{entries: map[string]CacheEntry[T]{}}
+}
+
+// Get implements Cache
+func (c *MemoryCache[T]) Get(ctx context.Context, key string) (CacheEntry[T], bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	return entry, ok, nil
+}
+
+// Set implements Cache
+func (c *MemoryCache[T]) Set(ctx context.Context, key string, entry CacheEntry[T]) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return nil
+}
+
+// Delete removes the entry of key, deleting a missing entry is a no-op
+func (c *MemoryCache[T]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
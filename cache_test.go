@@ -0,0 +1,84 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingCache is a Cache whose reads and writes fail
+type failingCache[T any] struct{}
+
+func (failingCache[T]) Get(ctx context.Context, key string) (CacheEntry[T], bool, error) {
+	return CacheEntry[T]{}, false, errors.New("cache down")
+}
+
+func (failingCache[T]) Set(ctx context.Context, key string, entry CacheEntry[T]) error {
+	return errors.New("cache down")
+}
+
+func TestRunWithCache(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := NewMemoryCache[string]()
+	opts := []Option{WithAttempts(2), WithClock(clock)}
+	ok := func(ctx context.Context) (string, error) { return "fresh", nil }
+	fail := func(ctx context.Context) (string, error) { return "", errTestSentinel }
+
+	// nothing to fall back to yet
+	res, err := RunWithCache(context.Background(), "key", fail, cache, opts...)
+	assert.Equal(t, true, errors.Is(err, errTestSentinel))
+	assert.Equal(t, Cached[string]{}, res)
+
+	res, err = RunWithCache(context.Background(), "key", ok, cache, opts...)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Cached[string]{Value: "fresh"}, res)
+	entry, found, _ := cache.Get(context.Background(), "key")
+	assert.Equal(t, true, found)
+	assert.Equal(t, CacheEntry[string]{Value: "fresh", StoredAt: clock.now}, entry)
+
+	// the operation fails for good, the cached value is returned
+	_ = clock.Sleep(context.Background(), time.Minute)
+	res, err = RunWithCache(context.Background(), "key", fail, cache, opts...)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "fresh", res.Value)
+	assert.Equal(t, true, res.Stale)
+	// a minute, and the delay of the retry
+	assert.Equal(t, time.Minute+DefaultDelay, res.Staleness)
+	assert.Equal(t, true, errors.Is(res.Err, errTestSentinel))
+
+	// unless it's too old
+	_, err = RunWithCache(context.Background(), "key", fail, cache, append(opts, WithMaxStaleness(time.Minute))...)
+	assert.Equal(t, true, errors.Is(err, errTestSentinel))
+
+	// or ctx is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = RunWithCache(ctx, "key", fail, cache, opts...)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestRunWithCacheFailingCache(t *testing.T) {
+	res, err := RunWithCache(context.Background(), "key", func(ctx context.Context) (int, error) {
+		return 1, nil
+	}, failingCache[int]{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, res.Value)
+
+	_, err = RunWithCache(context.Background(), "key", func(ctx context.Context) (int, error) {
+		return 0, Unrecoverable(errTestSentinel)
+	}, failingCache[int]{})
+	assert.Equal(t, errTestSentinel, err)
+}
+
+func TestMemoryCacheDelete(t *testing.T) {
+	cache := NewMemoryCache[int]()
+	_ = cache.Set(context.Background(), "key", CacheEntry[int]{Value: 1})
+	cache.Delete("key")
+	cache.Delete("missing")
+	_, found, err := cache.Get(context.Background(), "key")
+	assert.Equal(t, false, found)
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,41 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time and waits between attempts. The executors use it for the delays and
+// the elapsed time accounting, so tests can replace it with retrytest.FakeClock.
+// Context deadlines, such as WithAttemptTimeout, still use the wall clock
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Sleep waits for the given delay, or returns early with ctx.Err() when ctx is done
+	Sleep(ctx context.Context, delay time.Duration) error
+}
+
+// WithClock sets the Clock used by the executor, default is the wall clock
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
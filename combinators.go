@@ -0,0 +1,66 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Matching returns a RetryIf func reporting whether p matches an error, so the criteria of
+// a policy can be composed with AllOf, FirstMatch and Not. The delay and limit of p are ignored
+func Matching(p Policy) func(error) bool {
+	return p.matches
+}
+
+// ResponseIf returns a RetryIf func reporting whether an error is the non 2xx response of
+// an HTTP executor for which fn returns true
+func ResponseIf(fn func(*http.Response) bool) func(error) bool {
+	return func(err error) bool {
+		var se *statusError
+		return errors.As(err, &se) && fn(se.resp)
+	}
+}
+
+// AllOf returns a RetryIf func reporting whether all of conditions are true for an error,
+// i.e: AllOf(Matching(policy), Not(ResponseIf(hasHeader))) to retry the errors matching policy
+// unless the response carries a header. It's true if conditions is empty
+func AllOf(conditions ...func(error) bool) func(error) bool {
+	return func(err error) bool {
+		for _, c := range conditions {
+			if !c(err) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// FirstMatch returns a RetryIf func reporting whether any of conditions is true for an error.
+// They're evaluated in order until one is true, like the policies of an executor.
+// It's false if conditions is empty
+func FirstMatch(conditions ...func(error) bool) func(error) bool {
+	return func(err error) bool {
+		for _, c := range conditions {
+			if c(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a RetryIf func reporting whether condition is false for an error
+func Not(condition func(error) bool) func(error) bool {
+	return func(err error) bool {
+		return !condition(err)
+	}
+}
+
+// PolicyChain returns the policies of every set in order, as a single set of policies where
+// the first one matching an error is used, i.e: PolicyChain(overrides, GetRetryPolicies(HTTPPolicy))
+func PolicyChain(sets ...[]Policy) []Policy {
+	var chain []Policy
+	for _, set := range sets {
+		chain = append(chain, set...)
+	}
+	return chain
+}
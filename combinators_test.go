@@ -0,0 +1,59 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombinators(t *testing.T) {
+	unavailable := Policy{ErrorCodeNumber: http.StatusServiceUnavailable, ErrorCodeString: http.StatusText(http.StatusServiceUnavailable)}
+	noRetry := ResponseIf(func(resp *http.Response) bool {
+		return resp.Header.Get("X-No-Retry") != ""
+	})
+	policies := []Policy{
+		{
+			RetryIf:       AllOf(Matching(unavailable), Not(noRetry)),
+			DelayDuration: time.Millisecond,
+			RetryLimit:    2,
+		},
+	}
+	var calls int
+	err := ExecutorHTTPWithPolicies(policies, func() (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Header: http.Header{}, Body: http.NoBody}, nil
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = ExecutorHTTPWithPolicies(policies, func() (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Header: http.Header{"X-No-Retry": {"1"}}, Body: http.NoBody}, nil
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+}
+
+func TestFirstMatch(t *testing.T) {
+	isSentinel := func(err error) bool { return errors.Is(err, errTestSentinel) }
+	timedOut := Matching(Policy{ErrorCodeString: "timed out"})
+	cond := FirstMatch(isSentinel, timedOut)
+	assert.Equal(t, true, cond(errTestSentinel))
+	assert.Equal(t, true, cond(errors.New("timed out")))
+	assert.Equal(t, false, cond(errors.New("denied")))
+	assert.Equal(t, false, FirstMatch()(errTestSentinel))
+	assert.Equal(t, true, AllOf()(errTestSentinel))
+}
+
+func TestPolicyChain(t *testing.T) {
+	overrides := []Policy{{ErrorCodeNumber: http.StatusServiceUnavailable, ErrorCodeString: http.StatusText(http.StatusServiceUnavailable), RetryLimit: 10}}
+	chain := PolicyChain(overrides, GetRetryPolicies(HTTPPolicy))
+	assert.Equal(t, len(GetRetryPolicies(HTTPPolicy))+1, len(chain))
+	policy, ok := matchPolicy(chain, &statusError{resp: &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}})
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 10, policy.RetryLimit)
+}
@@ -0,0 +1,233 @@
+package retry
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompiledPolicies matches errors against a list of policies as the executors do, see Policy.ErrorCodeString,
+// but prepares the policies once: the status codes the policies match on alone are indexed, and so is the
+// policy matching the standard status of every code such as "503 Service Unavailable", so a failed
+// response is matched with a map lookup, and the patterns are lowercased or compiled beforehand.
+// A Retryer compiles its policies when it's created, the policies of a PolicyType and DefaultPolicies are
+// compiled once for all the executors using them, and the executors given a slice of policies scan it unless
+// they're given WithCompiledPolicies. It's immutable and safe for concurrent use
+type CompiledPolicies struct {
+	policies []Policy
+	compiled []compiledPolicy
+	// byStatus is the index of the first policy matching a failed response on its status code alone
+	byStatus map[int]int
+	// byStandardStatus is the index of the first policy matching a failed response with the standard
+	// status of its code, -1 if none does. A code is missing when the match depends on more than the status
+	byStandardStatus map[int]int
+	// statusChecked are the indexes of the policies a failed response is matched with one by one
+	statusChecked []int
+	// errorChecked are the indexes of the policies any other error is matched with one by one
+	errorChecked []int
+	// lowercase reports whether a policy matches on the lowercased message or status
+	lowercase bool
+	// opts are the options of the executors given the policies and no option, see options
+	optsOnce sync.Once
+	opts     *options
+}
+
+// compiledPolicy is a policy prepared for matching
+type compiledPolicy struct {
+	// substring is set for the MatchSubstring policies without match criteria, lowerCode is their
+	// ErrorCodeString lowercased
+	substring bool
+	lowerCode string
+	// re is the compiled ErrorCodeString of a MatchRegex policy
+	re *regexp.Regexp
+}
+
+// CompilePolicies returns the policies compiled for matching, the slice is copied
+func CompilePolicies(policies []Policy) *CompiledPolicies {
+	c := &CompiledPolicies{
+		policies:         append([]Policy(nil), policies...),
+		compiled:         make([]compiledPolicy, len(policies)),
+		byStatus:         map[int]int{},
+		byStandardStatus: map[int]int{},
+	}
+	index := func(code, i int) {
+		if _, ok := c.byStatus[code]; !ok {
+			c.byStatus[code] = i
+		}
+	}
+	for i, p := range c.policies {
+		switch {
+		case p.hasMatchers() && p.matchesStatusOnly():
+			// a plain error never matches
+			for _, code := range p.StatusCodes {
+				index(code, i)
+			}
+			for _, r := range p.StatusRanges {
+				for code := max(r.From, 100); code <= min(r.To, 999); code++ {
+					index(code, i)
+				}
+			}
+			continue
+		case !p.hasMatchers() && p.ErrorCodeNumber != 0 && (p.MatchMode == MatchExact || p.MatchMode == MatchPrefix):
+			index(p.ErrorCodeNumber, i)
+		default:
+			c.statusChecked = append(c.statusChecked, i)
+		}
+		c.errorChecked = append(c.errorChecked, i)
+		if p.hasMatchers() {
+			continue
+		}
+		switch p.MatchMode {
+		case MatchSubstring:
+			c.compiled[i] = compiledPolicy{substring: true, lowerCode: strings.ToLower(p.ErrorCodeString)}
+			c.lowercase = true
+		case MatchRegex:
+			c.compiled[i].re, _ = compileCodePattern(p.ErrorCodeString)
+		}
+	}
+	for code := 100; code <= 599; code++ {
+		if text := http.StatusText(code); text != "" {
+			if i, ok := c.matchStandardStatus(code, strconv.Itoa(code)+" "+text); ok {
+				c.byStandardStatus[code] = i
+			}
+		}
+	}
+	return c
+}
+
+// matchStandardStatus returns the index of the first policy matching a failed response with code and its
+// standard status, -1 if none does, and false if a policy before it may match on more than the status
+func (c *CompiledPolicies) matchStandardStatus(code int, status string) (int, bool) {
+	err := &statusError{resp: &http.Response{StatusCode: code, Status: status}}
+	for i, p := range c.policies {
+		if p.hasMatchers() && !p.matchesStatusOnly() {
+			return -1, false
+		}
+		if p.matches(err) {
+			return i, true
+		}
+	}
+	return -1, true
+}
+
+// isStandardStatus reports whether status is the standard one of code, as net/http sets it
+func isStandardStatus(code int, status string) bool {
+	text := http.StatusText(code)
+	return len(status) == len(text)+4 && status[4:] == text && status[3] == ' ' &&
+		status[0] == byte('0'+code/100) && status[1] == byte('0'+code/10%10) && status[2] == byte('0'+code%10)
+}
+
+// WithCompiledPolicies sets the policies evaluated on every failed attempt to the compiled ones,
+// like WithPolicies, so an executor called many times doesn't prepare them on every call
+func WithCompiledPolicies(c *CompiledPolicies) Option {
+	return func(o *options) {
+		o.policies = c.policies
+		o.customPolicies = true
+		o.matcher = c
+	}
+}
+
+// options returns the options of the executors given the policies and no option, they're built once
+// and shared so they mustn't be changed
+func (c *CompiledPolicies) options() *options {
+	c.optsOnce.Do(func() {
+		c.opts = newOptions(withCompiled(c, nil))
+	})
+	return c.opts
+}
+
+// optionsWith returns the options of the executors given the policies and opts
+func (c *CompiledPolicies) optionsWith(opts []Option) *options {
+	if len(opts) == 0 {
+		return c.options()
+	}
+	return newOptions(withCompiled(c, opts))
+}
+
+// Policies returns a copy of the compiled policies
+func (c *CompiledPolicies) Policies() []Policy {
+	return append([]Policy(nil), c.policies...)
+}
+
+// Match returns the first policy matching err and its index, as the executors evaluate it
+func (c *CompiledPolicies) Match(err error) (Policy, int, bool) {
+	if i, ok := c.match(err); ok {
+		return c.policies[i], i, true
+	}
+	return Policy{}, -1, false
+}
+
+func (c *CompiledPolicies) match(err error) (int, bool) {
+	se := asStatusError(err)
+	if se != nil && se.resp.StatusCode != 0 {
+		if i, ok := c.byStandardStatus[se.resp.StatusCode]; ok && isStandardStatus(se.resp.StatusCode, se.resp.Status) {
+			return i, i >= 0
+		}
+		first, indexed := c.byStatus[se.resp.StatusCode]
+		if !indexed {
+			first = -1
+		}
+		if len(c.statusChecked) == 0 || indexed && c.statusChecked[0] > first {
+			return first, indexed
+		}
+		status := se.resp.Status
+		lowerStatus := c.lower(status)
+		for _, i := range c.statusChecked {
+			if indexed && i > first {
+				break
+			}
+			if c.matchesAt(i, err, se.resp.StatusCode, status, lowerStatus) {
+				return i, true
+			}
+		}
+		return first, indexed
+	}
+	if se != nil {
+		return matchPolicyIndex(c.policies, err)
+	}
+	msg := err.Error()
+	lowerMsg := c.lower(msg)
+	for _, i := range c.errorChecked {
+		if c.matchesAt(i, err, 0, msg, lowerMsg) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// lower returns s lowercased if a policy matches on it
+func (c *CompiledPolicies) lower(s string) string {
+	if !c.lowercase {
+		return ""
+	}
+	return strings.ToLower(s)
+}
+
+// matchesAt reports whether the policy at i matches err, whose status code and status, or 0 and
+// message are given, along with the lowercased status or message
+func (c *CompiledPolicies) matchesAt(i int, err error, code int, msg, lowerMsg string) bool {
+	p, cp := &c.policies[i], &c.compiled[i]
+	switch {
+	case cp.substring:
+		return p.ErrorCodeNumber == code && p.ErrorCodeString == msg || strings.Contains(lowerMsg, cp.lowerCode)
+	case cp.re != nil:
+		return cp.re.MatchString(msg)
+	}
+	return p.matches(err)
+}
+
+// matchesStatusOnly reports whether the only match criteria of the policy are StatusCodes and StatusRanges
+func (p Policy) matchesStatusOnly() bool {
+	return p.ErrorPattern == nil && p.MatchError == nil && p.MatchErrorType == nil && p.RetryIf == nil && p.RetryIfResponse == nil &&
+		!p.matchesOnBody()
+}
+
+// matchPolicy returns the index of the first policy of o that matches err
+func (o *options) matchPolicy(err error) (int, bool) {
+	if o.matcher != nil {
+		return o.matcher.match(err)
+	}
+	return matchPolicyIndex(o.policies, err)
+}
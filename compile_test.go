@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompiledPoliciesMatch(t *testing.T) {
+	mixed := []Policy{
+		{ErrorCodeString: "Deadlock", MatchMode: MatchExact},
+		{ErrorCodeString: "conn", MatchMode: MatchPrefix},
+		{ErrorCodeString: `^lock [0-9]+$`, MatchMode: MatchRegex},
+		{ErrorCodeString: "Service Unavailable"},
+		{StatusRanges: []StatusRange{{From: 500, To: 502}}},
+		{ErrorCodeNumber: 429, MatchMode: MatchExact},
+		{StatusCodes: []int{404}, RetryIf: func(err error) bool { return false }},
+		{StatusCodes: []int{408, 504}},
+		{ErrorCodeString: "TIMEOUT"},
+	}
+	errs := []error{
+		errors.New("Deadlock"),
+		errors.New("deadlock"),
+		errors.New("connection refused"),
+		errors.New("lock 42"),
+		errors.New("the request timeout expired"),
+		errors.New("service unavailable"),
+		fmt.Errorf("wrapped: %w", errors.New("Deadlock")),
+		errors.New("nothing"),
+	}
+	for _, code := range []int{200, 400, 404, 408, 429, 500, 501, 502, 503, 504, 599} {
+		errs = append(errs, &statusError{resp: &http.Response{StatusCode: code, Status: fmt.Sprintf("%d %s", code, http.StatusText(code))}})
+	}
+	for _, policies := range [][]Policy{
+		GetRetryPolicies(HTTPPolicy),
+		GetRetryPolicies(StrictHTTPPolicy),
+		GetRetryPolicies(StandardPolicy),
+		GetRetryPolicies(CloudThrottlePolicy),
+		mixed,
+	} {
+		c := CompilePolicies(policies)
+		for _, err := range errs {
+			want, wantOK := matchPolicyIndex(policies, err)
+			_, i, ok := c.Match(err)
+			assert.Equal(t, wantOK, ok, err.Error())
+			if wantOK {
+				assert.Equal(t, want, i, err.Error())
+			} else {
+				assert.Equal(t, -1, i, err.Error())
+			}
+		}
+	}
+}
+
+func TestCompiledPoliciesFirstWins(t *testing.T) {
+	c := CompilePolicies([]Policy{
+		{ErrorCodeString: "Unavailable", RetryLimit: 1},
+		{StatusCodes: []int{503}, RetryLimit: 2},
+	})
+	p, i, ok := c.Match(&statusError{resp: &http.Response{StatusCode: 503, Status: "503 Service Unavailable"}})
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 0, i)
+	assert.Equal(t, 1, p.RetryLimit)
+}
+
+func TestWithCompiledPolicies(t *testing.T) {
+	policies := []Policy{{ErrorCodeString: "flaky", DelayDuration: time.Millisecond, RetryLimit: 2}}
+	c := CompilePolicies(policies)
+	policies[0].RetryLimit = 0
+
+	var attempts int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return errors.New("flaky")
+	}, WithCompiledPolicies(c))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, attempts)
+
+	copied := c.Policies()
+	copied[0].RetryLimit = 5
+	assert.Equal(t, 2, c.Policies()[0].RetryLimit)
+}
+
+func TestCompiledPoliciesStandardStatus(t *testing.T) {
+	c := CompilePolicies(GetRetryPolicies(HTTPPolicy))
+	// the presets match a standard status with a lookup, a custom one is still matched one by one
+	assert.Equal(t, 0, c.byStandardStatus[http.StatusServiceUnavailable])
+	assert.Equal(t, -1, c.byStandardStatus[http.StatusNotFound])
+	for _, status := range []string{"503 Service Unavailable", "503 Service Unavailable: upstream", "503", ""} {
+		err := &statusError{resp: &http.Response{StatusCode: 503, Status: status}}
+		want, wantOK := matchPolicyIndex(c.policies, err)
+		i, ok := c.match(err)
+		assert.Equal(t, wantOK, ok, status)
+		assert.Equal(t, want, i, status)
+	}
+	err := &statusError{resp: &http.Response{StatusCode: 503, Status: "503 Service Unavailable"}}
+	assert.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		_, _ = c.match(err)
+	}))
+
+	// a policy matching on more than the status stops the indexing
+	c = CompilePolicies([]Policy{{ErrorCodeString: "Bad Gateway"}, {RetryIf: func(err error) bool { return true }}})
+	assert.Equal(t, 0, c.byStandardStatus[http.StatusBadGateway])
+	_, indexed := c.byStandardStatus[http.StatusServiceUnavailable]
+	assert.Equal(t, false, indexed)
+}
+
+func TestCompiledPolicyType(t *testing.T) {
+	policyType := RegisterPolicyType("test-compiled", []Policy{{ErrorCodeString: "first"}})
+	c := compiledPolicyType(policyType)
+	// the policies of a type are compiled once, until it's registered again
+	assert.Equal(t, true, c == compiledPolicyType(policyType))
+	RegisterPolicyType("test-compiled", []Policy{{ErrorCodeString: "second"}})
+	assert.Equal(t, "second", compiledPolicyType(policyType).policies[0].ErrorCodeString)
+	assert.Equal(t, true, compiledPolicyType(HTTPPolicy) == compiledPolicyType(HTTPPolicy))
+
+	defer SetDefaultPolicyType(StandardPolicy)
+	SetDefaultPolicyType(policyType)
+	assert.Equal(t, true, compiledDefaults() == compiledPolicyType(policyType))
+	SetDefaultPolicies([]Policy{{ErrorCodeString: "custom"}})
+	assert.Equal(t, "custom", compiledDefaults().policies[0].ErrorCodeString)
+}
@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMaxConcurrentRetries is returned along with the error of the last attempt when an operation
+// couldn't start retrying because too many operations already are, see WithMaxConcurrentRetries
+var ErrMaxConcurrentRetries = errors.New("retry: too many concurrent retries")
+
+// WithMaxConcurrentRetries limits to max the operations retrying at the same time among the ones
+// using the option, such as the operations of a Retryer, so a dependency that's down doesn't pile
+// up goroutines waiting for their next attempt. An operation takes a slot when its first attempt
+// fails and keeps it until it returns. When no slot is free, it waits up to queueTimeout for one,
+// or fails right away if queueTimeout is zero, with ErrMaxConcurrentRetries
+func WithMaxConcurrentRetries(max int, queueTimeout time.Duration) Option {
+	slots := &retrySlots{ch: make(chan struct{}, max), queueTimeout: queueTimeout}
+	return func(o *options) {
+		o.retrySlots = slots
+	}
+}
+
+// retrySlots is a semaphore of the operations retrying at the same time
+type retrySlots struct {
+	ch           chan struct{}
+	queueTimeout time.Duration
+}
+
+// acquire takes a slot, waiting up to queueTimeout for one, it reports whether it got one
+func (s *retrySlots) acquire(ctx context.Context) bool {
+	select {
+	case s.ch <- struct{}{}:
+		return true
+	default:
+	}
+	if s.queueTimeout <= 0 {
+		return false
+	}
+	timer := time.NewTimer(s.queueTimeout)
+	defer timer.Stop()
+	select {
+	case s.ch <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *retrySlots) release() {
+	<-s.ch
+}
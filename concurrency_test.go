@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMaxConcurrentRetries(t *testing.T) {
+	r := NewRetryer(WithAttempts(2), WithDelay(time.Millisecond*100), WithMaxConcurrentRetries(1, 0))
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.Run(context.Background(), func(ctx context.Context) error {
+				return errTestSentinel
+			})
+		}()
+	}
+	wg.Wait()
+	// one operation retried while the other one failed fast
+	var failedFast int
+	for _, err := range errs {
+		assert.Equal(t, true, errors.Is(err, errTestSentinel))
+		if errors.Is(err, ErrMaxConcurrentRetries) {
+			failedFast++
+		}
+	}
+	assert.Equal(t, 1, failedFast)
+
+	// the slot is released once the operation returns
+	err := r.Run(context.Background(), func(ctx context.Context) error { return errTestSentinel })
+	assert.Equal(t, false, errors.Is(err, ErrMaxConcurrentRetries))
+}
+
+func TestWithMaxConcurrentRetriesQueue(t *testing.T) {
+	r := NewRetryer(WithAttempts(2), WithDelay(time.Millisecond*20), WithMaxConcurrentRetries(1, time.Second))
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.Run(context.Background(), func(ctx context.Context) error {
+				return errTestSentinel
+			})
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.Equal(t, false, errors.Is(err, ErrMaxConcurrentRetries))
+	}
+}
@@ -0,0 +1,332 @@
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadPolicies reads a JSON array of policies from r, i.e:
+//
+//	[{"errorCodeString": "timed out", "delayDuration": "500ms", "retryLimit": 3, "backoff": "exponential"}]
+//
+// ErrorPattern is written as "errorPattern", a regular expression compiled when it's loaded.
+// StatusRanges are written as strings such as "500-599".
+// MatchMode is one of "substring", "exact", "prefix" and "regex".
+// Durations are strings parsed by time.ParseDuration, or integers in nanoseconds.
+// Backoff is one of "constant", "linear", "exponential", and jitter one of "none", "full",
+// "equal", "decorrelated". See LoadPoliciesYAML for the same format in YAML
+func LoadPolicies(r io.Reader) ([]Policy, error) {
+	var policies []Policy
+	if err := json.NewDecoder(r).Decode(&policies); err != nil {
+		return nil, fmt.Errorf("retry: load policies: %w", err)
+	}
+	return policies, nil
+}
+
+// LoadPoliciesYAML reads a YAML sequence of policies from r, in the format of LoadPolicies, i.e:
+//
+//   - errorCodeString: timed out
+//     delayDuration: 500ms
+//     retryLimit: 3
+func LoadPoliciesYAML(r io.Reader) ([]Policy, error) {
+	var policies []Policy
+	if err := yaml.NewDecoder(r).Decode(&policies); err != nil {
+		return nil, fmt.Errorf("retry: load policies: %w", err)
+	}
+	return policies, nil
+}
+
+// LoadPoliciesFile reads the policies of the file at path, it's decoded as YAML if its extension
+// is .yaml or .yml, and as JSON otherwise
+func LoadPoliciesFile(path string) ([]Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("retry: load policies: %w", err)
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return LoadPoliciesYAML(f)
+	}
+	return LoadPolicies(f)
+}
+
+// policyConfig is the serialized form of a Policy
+type policyConfig struct {
+	Name            string         `json:"name,omitempty" yaml:"name,omitempty"`
+	ErrorCodeNumber int            `json:"errorCodeNumber,omitempty" yaml:"errorCodeNumber,omitempty"`
+	ErrorCodeString string         `json:"errorCodeString,omitempty" yaml:"errorCodeString,omitempty"`
+	DelayDuration   duration       `json:"delayDuration,omitempty" yaml:"delayDuration,omitempty"`
+	MatchMode       MatchMode      `json:"matchMode,omitempty" yaml:"matchMode,omitempty"`
+	RetryLimit      int            `json:"retryLimit,omitempty" yaml:"retryLimit,omitempty"`
+	Backoff         BackoffType    `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	Multiplier      float64        `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	MaxDelay        duration       `json:"maxDelay,omitempty" yaml:"maxDelay,omitempty"`
+	MinDelay        duration       `json:"minDelay,omitempty" yaml:"minDelay,omitempty"`
+	Jitter          JitterType     `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	ErrorPattern    *regexp.Regexp `json:"errorPattern,omitempty" yaml:"errorPattern,omitempty"`
+	StatusCodes     []int          `json:"statusCodes,omitempty" yaml:"statusCodes,omitempty"`
+	StatusRanges    []StatusRange  `json:"statusRanges,omitempty" yaml:"statusRanges,omitempty"`
+	BodyContains    string         `json:"bodyContains,omitempty" yaml:"bodyContains,omitempty"`
+}
+
+func (p Policy) config() policyConfig {
+	return policyConfig{
+		Name:            p.Name,
+		ErrorCodeNumber: p.ErrorCodeNumber,
+		ErrorCodeString: p.ErrorCodeString,
+		DelayDuration:   duration(p.DelayDuration),
+		MatchMode:       p.MatchMode,
+		RetryLimit:      p.RetryLimit,
+		Backoff:         p.Backoff,
+		Multiplier:      p.Multiplier,
+		MaxDelay:        duration(p.MaxDelay),
+		MinDelay:        duration(p.MinDelay),
+		Jitter:          p.Jitter,
+		ErrorPattern:    p.ErrorPattern,
+		StatusCodes:     p.StatusCodes,
+		StatusRanges:    p.StatusRanges,
+		BodyContains:    p.BodyContains,
+	}
+}
+
+func (p *Policy) setConfig(c policyConfig) {
+	p.Name = c.Name
+	p.ErrorCodeNumber = c.ErrorCodeNumber
+	p.ErrorCodeString = c.ErrorCodeString
+	p.DelayDuration = time.Duration(c.DelayDuration)
+	p.MatchMode = c.MatchMode
+	p.RetryLimit = c.RetryLimit
+	p.Backoff = c.Backoff
+	p.Multiplier = c.Multiplier
+	p.MaxDelay = time.Duration(c.MaxDelay)
+	p.MinDelay = time.Duration(c.MinDelay)
+	p.Jitter = c.Jitter
+	p.ErrorPattern = c.ErrorPattern
+	p.StatusCodes = c.StatusCodes
+	p.StatusRanges = c.StatusRanges
+	p.BodyContains = c.BodyContains
+}
+
+// MarshalJSON implements json.Marshaler, durations are written as strings such as "2s"
+func (p Policy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.config())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see LoadPolicies for the format
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var c policyConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	p.setConfig(c)
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler, durations are written as strings such as "2s"
+func (p Policy) MarshalYAML() (interface{}, error) {
+	return p.config(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, see LoadPolicies for the format
+func (p *Policy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var c policyConfig
+	if err := unmarshal(&c); err != nil {
+		return err
+	}
+	p.setConfig(c)
+	return nil
+}
+
+// StatusRange is an inclusive range of HTTP status codes, see Policy.StatusRanges
+type StatusRange struct {
+	From int
+	To   int
+}
+
+// Contains reports whether code is in the range
+func (r StatusRange) Contains(code int) bool {
+	return code >= r.From && code <= r.To
+}
+
+// String returns the range as "500-599"
+func (r StatusRange) String() string {
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r StatusRange) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, a single status code such as "503"
+// is a range of one code
+func (r *StatusRange) UnmarshalText(text []byte) error {
+	from, to, found := strings.Cut(string(text), "-")
+	if !found {
+		to = from
+	}
+	var err error
+	if r.From, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+		return fmt.Errorf("invalid status range %q", text)
+	}
+	if r.To, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+		return fmt.Errorf("invalid status range %q", text)
+	}
+	return nil
+}
+
+// duration is a time.Duration serialized as a string such as "2s"
+type duration time.Duration
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+func (d *duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+func (d *duration) set(v interface{}) error {
+	switch value := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = duration(parsed)
+	case float64:
+		*d = duration(value)
+	case int:
+		*d = duration(value)
+	case int64:
+		*d = duration(value)
+	case uint64:
+		*d = duration(value)
+	default:
+		return fmt.Errorf("invalid duration %v", v)
+	}
+	return nil
+}
+
+var backoffNames = map[BackoffType]string{
+	ConstantBackoff:    "constant",
+	LinearBackoff:      "linear",
+	ExponentialBackoff: "exponential",
+}
+
+// String returns the name of the backoff type as used in configuration files
+func (b BackoffType) String() string {
+	if name, ok := backoffNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("BackoffType(%d)", int(b))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (b BackoffType) MarshalText() ([]byte, error) {
+	if _, ok := backoffNames[b]; !ok {
+		return nil, fmt.Errorf("invalid backoff %d", int(b))
+	}
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *BackoffType) UnmarshalText(text []byte) error {
+	for value, name := range backoffNames {
+		if name == string(text) {
+			*b = value
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid backoff %q", text)
+}
+
+var matchModeNames = map[MatchMode]string{
+	MatchSubstring: "substring",
+	MatchExact:     "exact",
+	MatchPrefix:    "prefix",
+	MatchRegex:     "regex",
+}
+
+// String returns the name of the match mode as used in configuration files
+func (m MatchMode) String() string {
+	if name, ok := matchModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("MatchMode(%d)", int(m))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (m MatchMode) MarshalText() ([]byte, error) {
+	if _, ok := matchModeNames[m]; !ok {
+		return nil, fmt.Errorf("invalid match mode %d", int(m))
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (m *MatchMode) UnmarshalText(text []byte) error {
+	for value, name := range matchModeNames {
+		if name == string(text) {
+			*m = value
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid match mode %q", text)
+}
+
+var jitterNames = map[JitterType]string{
+	NoJitter:           "none",
+	FullJitter:         "full",
+	EqualJitter:        "equal",
+	DecorrelatedJitter: "decorrelated",
+}
+
+// String returns the name of the jitter type as used in configuration files
+func (j JitterType) String() string {
+	if name, ok := jitterNames[j]; ok {
+		return name
+	}
+	return fmt.Sprintf("JitterType(%d)", int(j))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (j JitterType) MarshalText() ([]byte, error) {
+	if _, ok := jitterNames[j]; !ok {
+		return nil, fmt.Errorf("invalid jitter %d", int(j))
+	}
+	return []byte(j.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (j *JitterType) UnmarshalText(text []byte) error {
+	for value, name := range jitterNames {
+		if name == string(text) {
+			*j = value
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid jitter %q", text)
+}
@@ -0,0 +1,142 @@
+package retry
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadPolicies(t *testing.T) {
+	policies, err := LoadPolicies(strings.NewReader(`[
+		{"errorCodeString": "timed out", "delayDuration": "500ms", "retryLimit": 3, "backoff": "exponential", "maxDelay": "5s", "minDelay": "100ms", "jitter": "full"},
+		{"errorCodeNumber": 503, "errorCodeString": "Service Unavailable", "delayDuration": 2000000000, "retryLimit": 2}
+	]`))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 500,
+			RetryLimit:      3,
+			Backoff:         ExponentialBackoff,
+			MaxDelay:        time.Second * 5,
+			MinDelay:        time.Millisecond * 100,
+			Jitter:          FullJitter,
+		},
+		{
+			ErrorCodeNumber: 503,
+			ErrorCodeString: "Service Unavailable",
+			DelayDuration:   time.Second * 2,
+			RetryLimit:      2,
+		},
+	}, policies)
+}
+
+func TestLoadPoliciesInvalid(t *testing.T) {
+	_, err := LoadPolicies(strings.NewReader(`[{"delayDuration": "soon"}]`))
+	assert.Equal(t, true, err != nil)
+
+	_, err = LoadPolicies(strings.NewReader(`[{"backoff": "random"}]`))
+	assert.Equal(t, true, err != nil)
+
+	_, err = LoadPolicies(strings.NewReader(`{`))
+	assert.Equal(t, true, err != nil)
+}
+
+func TestPolicyMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(Policy{ErrorCodeString: "timed out", DelayDuration: time.Second * 2, RetryLimit: 3, Backoff: LinearBackoff})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, `{"errorCodeString":"timed out","delayDuration":"2s","retryLimit":3,"backoff":"linear"}`, string(b))
+}
+
+func TestPolicyYAML(t *testing.T) {
+	var policies []Policy
+	err := yaml.Unmarshal([]byte(`
+- errorCodeString: timed out
+  delayDuration: 2s
+  retryLimit: 3
+  jitter: decorrelated
+`), &policies)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, []Policy{{ErrorCodeString: "timed out", DelayDuration: time.Second * 2, RetryLimit: 3, Jitter: DecorrelatedJitter}}, policies)
+
+	b, err := yaml.Marshal(policies)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, "- errorCodeString: timed out\n  delayDuration: 2s\n  retryLimit: 3\n  jitter: decorrelated\n", string(b))
+}
+
+func TestLoadPoliciesYAML(t *testing.T) {
+	policies, err := LoadPoliciesYAML(strings.NewReader(`
+- errorCodeString: timed out
+  delayDuration: 500ms
+  retryLimit: 3
+  backoff: exponential
+  statusRanges: ["500-599"]
+- errorPattern: "^conn"
+  delayDuration: 2000000000
+`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(policies))
+	assert.Equal(t, Policy{
+		ErrorCodeString: "timed out",
+		DelayDuration:   time.Millisecond * 500,
+		RetryLimit:      3,
+		Backoff:         ExponentialBackoff,
+		StatusRanges:    []StatusRange{{From: 500, To: 599}},
+	}, policies[0])
+	assert.Equal(t, "^conn", policies[1].ErrorPattern.String())
+	assert.Equal(t, time.Second*2, policies[1].DelayDuration)
+
+	_, err = LoadPoliciesYAML(strings.NewReader("- delayDuration: soon\n"))
+	assert.Equal(t, true, err != nil)
+}
+
+func TestLoadPoliciesFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "policies.yml")
+	jsonPath := filepath.Join(dir, "policies.json")
+	assert.Equal(t, nil, os.WriteFile(yamlPath, []byte("- errorCodeString: timed out\n  retryLimit: 2\n"), 0o600))
+	assert.Equal(t, nil, os.WriteFile(jsonPath, []byte(`[{"errorCodeString": "timed out", "retryLimit": 2}]`), 0o600))
+	for _, path := range []string{yamlPath, jsonPath} {
+		policies, err := LoadPoliciesFile(path)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []Policy{{ErrorCodeString: "timed out", RetryLimit: 2}}, policies)
+	}
+	_, err := LoadPoliciesFile(filepath.Join(dir, "missing.yaml"))
+	assert.Equal(t, true, errors.Is(err, os.ErrNotExist))
+}
+
+func TestLoadPoliciesErrorPattern(t *testing.T) {
+	policies, err := LoadPolicies(strings.NewReader(`[{"errorPattern": "(?i)^connection (reset|refused)$", "retryLimit": 1}]`))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, "(?i)^connection (reset|refused)$", policies[0].ErrorPattern.String())
+	assert.Equal(t, true, policies[0].matches(errors.New("Connection Reset")))
+
+	b, err := json.Marshal(policies[0])
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, `{"retryLimit":1,"errorPattern":"(?i)^connection (reset|refused)$"}`, string(b))
+
+	_, err = LoadPolicies(strings.NewReader(`[{"errorPattern": "("}]`))
+	assert.Equal(t, true, err != nil)
+}
+
+func TestLoadPoliciesStatusRanges(t *testing.T) {
+	policies, err := LoadPolicies(strings.NewReader(`[{"statusCodes": [408, 429], "statusRanges": ["500-599"], "retryLimit": 2}]`))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, []Policy{{StatusCodes: []int{408, 429}, StatusRanges: []StatusRange{{500, 599}}, RetryLimit: 2}}, policies)
+
+	b, err := json.Marshal(policies[0])
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, `{"retryLimit":2,"statusCodes":[408,429],"statusRanges":["500-599"]}`, string(b))
+
+	var r StatusRange
+	assert.Equal(t, true, r.UnmarshalText([]byte("503")) == nil)
+	assert.Equal(t, StatusRange{503, 503}, r)
+	assert.Equal(t, true, r.UnmarshalText([]byte("5xx")) != nil)
+}
@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Consumer processes the messages of a queue consumer, i.e: a Kafka, SQS or RabbitMQ handler,
+// with retries. A message that still fails once its retries are exhausted can be redelivered
+// in memory, and is then handed to DeadLetter. The zero Consumer retries as Do does and
+// returns the errors. A Consumer is safe for concurrent use and must not be copied after first use
+type Consumer[M any] struct {
+	// Options configure the retries of every delivery, see Do
+	Options []Option
+	// DeadLetter is called with the message and the error once it's given up on, i.e: to publish
+	// it to a dead-letter queue. Its ctx isn't cancelled with the context of the delivery.
+	// When nil, Process returns the error so the message can be rejected to the broker
+	DeadLetter func(ctx context.Context, msg M, err error)
+	// Redeliveries is how many times a message exhausting the RetryLimit of its policy is
+	// processed again in the background RedeliveryDelay later, before it's handed to DeadLetter.
+	// It requires DeadLetter, since the message is acknowledged when its redelivery is scheduled,
+	// a message without one is never redelivered and its error is returned instead.
+	// The pending redeliveries are lost if the process exits, Wait waits for them
+	Redeliveries int
+	// RedeliveryDelay is the delay before a redelivery
+	RedeliveryDelay time.Duration
+
+	pending sync.WaitGroup
+}
+
+// ProcessWithRetry processes msg with fn, and retries it as configured by opts, see Consumer
+func ProcessWithRetry[M any](ctx context.Context, msg M, fn func(ctx context.Context, msg M) error, opts ...Option) error {
+	c := &Consumer[M]{Options: opts}
+	return c.Process(ctx, msg, fn)
+}
+
+// Process processes msg with fn, and retries it as configured by Options.
+// nil is returned once msg is processed, scheduled for a redelivery or handed to DeadLetter.
+// A message whose delivery is cancelled is neither redelivered nor dead-lettered, ctx's error
+// is returned so the broker delivers it again
+func (c *Consumer[M]) Process(ctx context.Context, msg M, fn func(ctx context.Context, msg M) error) error {
+	return c.process(ctx, msg, fn, 0)
+}
+
+// Wait waits for the pending redeliveries to complete
+func (c *Consumer[M]) Wait() {
+	c.pending.Wait()
+}
+
+// process is Process for the given redelivery of msg, 0 for its delivery
+func (c *Consumer[M]) process(ctx context.Context, msg M, fn func(ctx context.Context, msg M) error, redelivery int) error {
+	err := Do(ctx, func(ctx context.Context) error {
+		return fn(ctx, msg)
+	}, c.Options...)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	ctx = context.WithoutCancel(ctx)
+	var exhausted *ExhaustedError
+	if c.DeadLetter != nil && redelivery < c.Redeliveries && errors.As(err, &exhausted) {
+		c.pending.Add(1)
+		go func() {
+			defer c.pending.Done()
+			timer := time.NewTimer(c.RedeliveryDelay)
+			defer timer.Stop()
+			<-timer.C
+			_ = c.process(ctx, msg, fn, redelivery+1)
+		}()
+		return nil
+	}
+	if c.DeadLetter == nil {
+		return err
+	}
+	c.DeadLetter(ctx, msg, err)
+	return nil
+}
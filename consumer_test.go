@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessWithRetry(t *testing.T) {
+	var calls int
+	err := ProcessWithRetry(context.Background(), "msg", func(ctx context.Context, msg string) error {
+		assert.Equal(t, "msg", msg)
+		calls++
+		return errTestSentinel
+	}, WithAttempts(2), WithDelay(time.Millisecond))
+	// without a dead-letter callback the error is returned
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: errTestSentinel}, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestConsumerRedelivery(t *testing.T) {
+	var calls int32
+	var mu sync.Mutex
+	var dead []error
+	c := &Consumer[int]{
+		Options:         []Option{WithAttempts(2), WithDelay(time.Millisecond)},
+		Redeliveries:    2,
+		RedeliveryDelay: time.Millisecond * 5,
+		DeadLetter: func(ctx context.Context, msg int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, 7, msg)
+			dead = append(dead, err)
+		},
+	}
+	err := c.Process(context.Background(), 7, func(ctx context.Context, msg int) error {
+		atomic.AddInt32(&calls, 1)
+		return errTestSentinel
+	})
+	assert.Equal(t, true, err == nil)
+	c.Wait()
+	// the delivery and the 2 redeliveries make 2 attempts each
+	assert.Equal(t, int32(6), atomic.LoadInt32(&calls))
+	assert.Equal(t, 1, len(dead))
+	assert.Equal(t, true, errors.Is(dead[0], errTestSentinel))
+}
+
+func TestConsumerDeadLetterNotRetried(t *testing.T) {
+	var dead error
+	c := &Consumer[int]{
+		Options:      []Option{WithDelay(time.Millisecond)},
+		Redeliveries: 2,
+		DeadLetter: func(ctx context.Context, msg int, err error) {
+			dead = err
+		},
+	}
+	// an unrecoverable error isn't redelivered
+	err := c.Process(context.Background(), 1, func(ctx context.Context, msg int) error {
+		return Unrecoverable(errTestSentinel)
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, errTestSentinel, dead)
+}
+
+func TestConsumerRedeliveryWithoutDeadLetter(t *testing.T) {
+	var calls int32
+	c := &Consumer[int]{
+		Options:      []Option{WithAttempts(2), WithDelay(time.Millisecond)},
+		Redeliveries: 2,
+	}
+	// the message isn't acknowledged to be lost by a failing redelivery, the broker gets the error
+	err := c.Process(context.Background(), 1, func(ctx context.Context, msg int) error {
+		atomic.AddInt32(&calls, 1)
+		return errTestSentinel
+	})
+	c.Wait()
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: errTestSentinel}, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestConsumerCancelled(t *testing.T) {
+	var called bool
+	c := &Consumer[int]{
+		DeadLetter: func(ctx context.Context, msg int, err error) {
+			called = true
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	err := c.Process(ctx, 1, func(ctx context.Context, msg int) error {
+		cancel()
+		return errTestSentinel
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, false, called)
+}
@@ -0,0 +1,96 @@
+package retry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+// databasePolicies are the policies of DatabasePolicy
+func databasePolicies() []Policy {
+	return []Policy{
+		{
+			MatchErrorType: IsBadConn,
+			DelayDuration:  time.Millisecond * 100,
+			RetryLimit:     3,
+			Backoff:        ExponentialBackoff,
+			MaxDelay:       time.Second * 2,
+			Jitter:         FullJitter,
+		},
+		{
+			MatchErrorType: func(err error) bool {
+				return IsSerializationFailure(err) || IsDeadlock(err)
+			},
+			DelayDuration: time.Millisecond * 50,
+			RetryLimit:    5,
+			Backoff:       ExponentialBackoff,
+			MaxDelay:      time.Second,
+			Jitter:        FullJitter,
+		},
+	}
+}
+
+// sqlStateError is implemented by the errors of the drivers reporting the SQLSTATE code,
+// such as pgx and lib/pq
+type sqlStateError interface {
+	SQLState() string
+}
+
+func sqlState(err error) string {
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
+
+// IsBadConn reports whether err tells the connection to the database is unusable, so the
+// operation can be run again on another one: driver.ErrBadConn, or a reset connection
+func IsBadConn(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || IsConnReset(err)
+}
+
+// IsSerializationFailure reports whether err is a transaction that was rolled back because it
+// couldn't be serialized with concurrent ones, SQLSTATE 40001
+func IsSerializationFailure(err error) bool {
+	return sqlState(err) == "40001"
+}
+
+// IsDeadlock reports whether err is a transaction that was chosen as the victim of a deadlock,
+// SQLSTATE 40P01 for PostgreSQL. Drivers that don't report the SQLSTATE are matched on the message,
+// i.e: "Deadlock found when trying to get lock" of MySQL
+func IsDeadlock(err error) bool {
+	if err == nil {
+		return false
+	}
+	if state := sqlState(err); state != "" {
+		return state == "40P01"
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "deadlock")
+}
+
+// TxBeginner starts transactions, it's implemented by *sql.DB and *sql.Conn
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// ExecutorTx runs fn in a transaction begun by db with txOpts, and commits it if fn succeeds.
+// If fn or the commit fails, the transaction is rolled back and the whole transaction is
+// retried as configured by opts, see Do. Without WithPolicies or WithPolicyType, the
+// DatabasePolicy policies are used. fn must not commit or roll back the transaction
+func ExecutorTx(ctx context.Context, db TxBeginner, txOpts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error, opts ...Option) error {
+	return Do(ctx, func(ctx context.Context) error {
+		tx, err := db.BeginTx(ctx, txOpts)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx, tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}, withCompiled(compiledPolicyType(DatabasePolicy), opts)...)
+}
@@ -0,0 +1,115 @@
+package retry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSQLStateError struct {
+	state string
+}
+
+func (e *testSQLStateError) Error() string {
+	return "ERROR: could not serialize access (SQLSTATE " + e.state + ")"
+}
+func (e *testSQLStateError) SQLState() string { return e.state }
+
+// testDriver records the commits and rollbacks of its transactions
+type testDriver struct {
+	mu        sync.Mutex
+	commits   int
+	rollbacks int
+	commitErr []error
+}
+
+func (d *testDriver) Open(name string) (driver.Conn, error) { return &testConn{d: d}, nil }
+
+type testConn struct {
+	d *testDriver
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *testConn) Close() error              { return nil }
+func (c *testConn) Begin() (driver.Tx, error) { return c, nil }
+
+func (c *testConn) Commit() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.commits++
+	if len(c.d.commitErr) > 0 {
+		err := c.d.commitErr[0]
+		c.d.commitErr = c.d.commitErr[1:]
+		return err
+	}
+	return nil
+}
+
+func (c *testConn) Rollback() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.rollbacks++
+	return nil
+}
+
+var testDriverID int
+
+func openTestDB(t *testing.T, d *testDriver) *sql.DB {
+	testDriverID++
+	name := fmt.Sprintf("retry-test-%d", testDriverID)
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	assert.Equal(t, true, err == nil)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDatabaseErrorHelpers(t *testing.T) {
+	assert.Equal(t, true, IsBadConn(fmt.Errorf("query: %w", driver.ErrBadConn)))
+	assert.Equal(t, true, IsSerializationFailure(&testSQLStateError{state: "40001"}))
+	assert.Equal(t, false, IsSerializationFailure(&testSQLStateError{state: "23505"}))
+	assert.Equal(t, true, IsDeadlock(&testSQLStateError{state: "40P01"}))
+	assert.Equal(t, true, IsDeadlock(errors.New("Error 1213: Deadlock found when trying to get lock")))
+	assert.Equal(t, false, IsDeadlock(nil))
+	assert.Equal(t, "database", DatabasePolicy.String())
+}
+
+func TestExecutorTx(t *testing.T) {
+	d := &testDriver{commitErr: []error{&testSQLStateError{state: "40001"}}}
+	db := openTestDB(t, d)
+	var calls int
+	err := ExecutorTx(context.Background(), db, nil, func(ctx context.Context, tx *sql.Tx) error {
+		calls++
+		if calls == 1 {
+			return &testSQLStateError{state: "40P01"}
+		}
+		return nil
+	}, WithClock(&testClock{}))
+	assert.Equal(t, true, err == nil)
+	// deadlock, then a serialization failure on commit, then success
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 2, d.commits)
+	assert.Equal(t, 1, d.rollbacks)
+}
+
+func TestExecutorTxNotRetryable(t *testing.T) {
+	d := &testDriver{}
+	db := openTestDB(t, d)
+	var calls int
+	err := ExecutorTx(context.Background(), db, nil, func(ctx context.Context, tx *sql.Tx) error {
+		calls++
+		return errTestSentinel
+	}, WithClock(&testClock{}))
+	assert.Equal(t, errTestSentinel, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, d.commits)
+	assert.Equal(t, 1, d.rollbacks)
+}
@@ -0,0 +1,155 @@
+package retry
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DebugVar is the name of the expvar variable the published Retryers, budgets and breakers are exported under
+const DebugVar = "retry"
+
+// published holds the components published for inspection, see DebugHandler
+var published = struct {
+	once     sync.Once
+	mu       sync.RWMutex
+	retryers map[string]*Retryer
+	budgets  map[string]*RetryBudget
+	breakers map[string]*HostBreakers
+}{
+	retryers: map[string]*Retryer{},
+	budgets:  map[string]*RetryBudget{},
+	breakers: map[string]*HostBreakers{},
+}
+
+// PublishRetryer exports the Stats and the BurnRates of r under name through expvar and DebugHandler,
+// for a quick inspection in production without a metrics stack. Publishing another one under the same name replaces it
+func PublishRetryer(name string, r *Retryer) {
+	publish(func() { published.retryers[name] = r })
+}
+
+// PublishBudget exports the tokens left in b under name, see PublishRetryer
+func PublishBudget(name string, b *RetryBudget) {
+	publish(func() { published.budgets[name] = b })
+}
+
+// PublishBreakers exports the state of the breaker of every host of b under name, see PublishRetryer
+func PublishBreakers(name string, b *HostBreakers) {
+	publish(func() { published.breakers[name] = b })
+}
+
+// Unpublish stops exporting the Retryer, budget and breakers published under name
+func Unpublish(name string) {
+	published.mu.Lock()
+	defer published.mu.Unlock()
+	delete(published.retryers, name)
+	delete(published.budgets, name)
+	delete(published.breakers, name)
+}
+
+// DebugHandler returns an http.Handler rendering the published Retryers, budgets and breakers as JSON,
+// the same document expvar exports under DebugVar
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(debugDocument())
+	})
+}
+
+// publish applies fn to the published components, and exports them through expvar the first time
+func publish(fn func()) {
+	published.once.Do(func() {
+		expvar.Publish(DebugVar, expvar.Func(func() any { return debugDocument() }))
+	})
+	published.mu.Lock()
+	defer published.mu.Unlock()
+	fn()
+}
+
+// debugSnapshot is the JSON document of DebugHandler
+type debugSnapshot struct {
+	Retryers map[string]debugRetryer            `json:"retryers"`
+	Budgets  map[string]debugBudget             `json:"budgets"`
+	Breakers map[string]map[string]debugBreaker `json:"breakers"`
+}
+
+type debugRetryer struct {
+	Stats     map[string]debugPolicyStats `json:"stats"`
+	BurnRates []debugBurnRate             `json:"burnRates"`
+}
+
+type debugPolicyStats struct {
+	Matches          int64    `json:"matches"`
+	RetriedSuccesses int64    `json:"retriedSuccesses"`
+	Exhaustions      int64    `json:"exhaustions"`
+	TotalDelay       duration `json:"totalDelay"`
+}
+
+type debugBurnRate struct {
+	Window     duration `json:"window"`
+	Operations int64    `json:"operations"`
+	Retried    float64  `json:"retried"`
+	Exhausted  float64  `json:"exhausted"`
+}
+
+type debugBudget struct {
+	Tokens float64 `json:"tokens"`
+}
+
+type debugBreaker struct {
+	Failures  int        `json:"failures"`
+	Open      bool       `json:"open"`
+	OpenUntil *time.Time `json:"openUntil,omitempty"`
+}
+
+// debugDocument returns the current state of the published components
+func debugDocument() debugSnapshot {
+	published.mu.RLock()
+	defer published.mu.RUnlock()
+	doc := debugSnapshot{
+		Retryers: make(map[string]debugRetryer, len(published.retryers)),
+		Budgets:  make(map[string]debugBudget, len(published.budgets)),
+		Breakers: make(map[string]map[string]debugBreaker, len(published.breakers)),
+	}
+	for name, r := range published.retryers {
+		dr := debugRetryer{Stats: map[string]debugPolicyStats{}}
+		for policy, s := range r.Stats() {
+			dr.Stats[policy] = debugPolicyStats{
+				Matches:          s.Matches,
+				RetriedSuccesses: s.RetriedSuccesses,
+				Exhaustions:      s.Exhaustions,
+				TotalDelay:       duration(s.TotalDelay),
+			}
+		}
+		for _, rate := range r.BurnRates() {
+			dr.BurnRates = append(dr.BurnRates, debugBurnRate{
+				Window:     duration(rate.Window),
+				Operations: rate.Operations,
+				Retried:    rate.Retried,
+				Exhausted:  rate.Exhausted,
+			})
+		}
+		doc.Retryers[name] = dr
+	}
+	for name, b := range published.budgets {
+		doc.Budgets[name] = debugBudget{Tokens: b.Tokens()}
+	}
+	for name, b := range published.breakers {
+		now := b.clock.Now()
+		hosts := map[string]debugBreaker{}
+		for _, host := range b.Hosts() {
+			s := b.State(host)
+			db := debugBreaker{Failures: s.Failures, Open: s.Open(now)}
+			if !s.OpenUntil.IsZero() {
+				db.OpenUntil = &s.OpenUntil
+			}
+			hosts[host] = db
+		}
+		doc.Breakers[name] = hosts
+	}
+	return doc
+}
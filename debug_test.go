@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugHandler(t *testing.T) {
+	r := NewRetryer(WithPolicies([]Policy{{Name: "flaky", ErrorCodeString: "flaky", DelayDuration: time.Millisecond, RetryLimit: 1}}))
+	var calls int
+	_ = r.Run(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	budget := NewRetryBudget(0.1, 5)
+	breakers := NewHostBreakers(1, time.Minute)
+	breakers.record("example.com", true)
+	PublishRetryer("api", r)
+	PublishBudget("api", budget)
+	PublishBreakers("api", breakers)
+	defer Unpublish("api")
+
+	rec := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/retry", nil))
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	var doc struct {
+		Retryers map[string]struct {
+			Stats map[string]struct {
+				Matches          int64
+				RetriedSuccesses int64
+				TotalDelay       string
+			}
+			BurnRates []struct {
+				Window     string
+				Operations int64
+			}
+		}
+		Budgets  map[string]struct{ Tokens float64 }
+		Breakers map[string]map[string]struct {
+			Failures int
+			Open     bool
+		}
+	}
+	assert.Equal(t, nil, json.Unmarshal(rec.Body.Bytes(), &doc))
+	stats := doc.Retryers["api"].Stats["flaky"]
+	assert.Equal(t, int64(1), stats.Matches)
+	assert.Equal(t, int64(1), stats.RetriedSuccesses)
+	assert.Equal(t, "1ms", stats.TotalDelay)
+	assert.Equal(t, "1m0s", doc.Retryers["api"].BurnRates[0].Window)
+	assert.Equal(t, int64(1), doc.Retryers["api"].BurnRates[0].Operations)
+	assert.Equal(t, float64(5), doc.Budgets["api"].Tokens)
+	assert.Equal(t, 1, doc.Breakers["api"]["example.com"].Failures)
+	assert.Equal(t, true, doc.Breakers["api"]["example.com"].Open)
+
+	// expvar exports the same document
+	assert.Equal(t, true, expvar.Get(DebugVar) != nil)
+
+	Unpublish("api")
+	rec = httptest.NewRecorder()
+	DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/retry", nil))
+	var after struct{ Retryers map[string]any }
+	assert.Equal(t, nil, json.Unmarshal(rec.Body.Bytes(), &after))
+	assert.Equal(t, 0, len(after.Retryers))
+}
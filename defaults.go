@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"sync"
+)
+
+// defaults holds the policies used by the executors that aren't given any
+var defaults = struct {
+	sync.RWMutex
+	policyType PolicyType
+	// compiled are the policies used instead of policyType when custom is true
+	compiled *CompiledPolicies
+	custom   bool
+}{
+	policyType: StandardPolicy,
+}
+
+// SetDefaultPolicyType sets the policy type used by Executor, ExecutorHTTP and the other
+// executors that aren't given policies, StandardPolicy by default. Its policies are compiled
+// once, and again when the type is registered again with RegisterPolicyType.
+// It's meant to be called from main, but it's safe to call from multiple goroutines
+func SetDefaultPolicyType(policyType PolicyType) {
+	defaults.Lock()
+	defer defaults.Unlock()
+	defaults.policyType = policyType
+	defaults.compiled = nil
+	defaults.custom = false
+}
+
+// SetDefaultPolicies is like SetDefaultPolicyType for policies that aren't registered as a type
+func SetDefaultPolicies(policies []Policy) {
+	defaults.Lock()
+	defer defaults.Unlock()
+	defaults.compiled = CompilePolicies(policies)
+	defaults.custom = true
+}
+
+// DefaultPolicies returns a copy of the policies used by the executors that aren't given any
+func DefaultPolicies() []Policy {
+	defaults.RLock()
+	defer defaults.RUnlock()
+	if defaults.custom {
+		return defaults.compiled.Policies()
+	}
+	return GetRetryPolicies(defaults.policyType)
+}
+
+// compiledDefaults returns DefaultPolicies compiled, which the executors that aren't given policies use
+func compiledDefaults() *CompiledPolicies {
+	defaults.RLock()
+	custom, compiled, policyType := defaults.custom, defaults.compiled, defaults.policyType
+	defaults.RUnlock()
+	if custom {
+		return compiled
+	}
+	return compiledPolicyType(policyType)
+}
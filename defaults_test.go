@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDefaultPolicies(t *testing.T) {
+	defer SetDefaultPolicyType(StandardPolicy)
+
+	SetDefaultPolicies([]Policy{{ErrorCodeString: "busy", DelayDuration: time.Millisecond, RetryLimit: 2}})
+	var calls int
+	err := Executor(func() error {
+		calls++
+		return errors.New("busy")
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, calls)
+
+	SetDefaultPolicyType(HTTPPolicy)
+	assert.Equal(t, GetRetryPolicies(HTTPPolicy), DefaultPolicies())
+	calls = 0
+	err = ExecutorHTTP(func() (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return &http.Response{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway", Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 2, calls)
+}
+
+func TestSetDefaultPolicyTypeConcurrent(t *testing.T) {
+	defer SetDefaultPolicyType(StandardPolicy)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetDefaultPolicyType(StrictHTTPPolicy)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = DefaultPolicies()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, GetRetryPolicies(StrictHTTPPolicy), DefaultPolicies())
+}
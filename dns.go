@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// The default policy of a Resolver: the lookups failing with a temporary DNS error are retried up to
+// DefaultDNSAttempts times, every DefaultDNSDelay doubled up to DefaultDNSMaxDelay with full jitter
+const (
+	DefaultDNSAttempts = 6
+	DefaultDNSDelay    = time.Millisecond * 100
+	DefaultDNSMaxDelay = time.Second * 2
+)
+
+// DNSResolver makes the lookups of a Resolver, *net.Resolver implements it
+type DNSResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// WithStaleLookups makes a Resolver remember the results of its successful lookups for d, and return
+// them when the same lookup fails after its retries with a temporary DNS error, i.e: while the DNS server
+// of a container is restarting. A missing host isn't covered, nor a cancelled lookup. Zero, the default,
+// doesn't remember anything. The other executors ignore it
+func WithStaleLookups(d time.Duration) Option {
+	return func(o *options) {
+		o.staleLookups = d
+	}
+}
+
+// Resolver resolves host names and SRV records, and retries the lookups that fail with a temporary DNS
+// error, see IsTemporaryNetErr, i.e: the DNS server of a container that isn't ready when the process
+// starts. A missing host fails right away. It's safe for concurrent use
+type Resolver struct {
+	resolver DNSResolver
+	opts     *options
+
+	mu    sync.Mutex
+	stale map[string]lookupResult
+}
+
+// lookupResult is the result of a successful lookup, remembered for WithStaleLookups
+type lookupResult struct {
+	addrs []string
+	cname string
+	srvs  []*net.SRV
+	at    time.Time
+}
+
+// NewResolver returns a Resolver making its lookups with resolver, net.DefaultResolver if nil, and
+// retrying them as configured by opts. Its default policy retries the temporary DNS errors with the
+// DefaultDNSAttempts, DefaultDNSDelay and DefaultDNSMaxDelay, WithAttempts, WithDelay, WithMaxDelay and
+// the other options of the default policy change it, and WithPolicies replaces it
+func NewResolver(resolver DNSResolver, opts ...Option) *Resolver {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	defaults := []Option{
+		WithRetryIf(IsTemporaryNetErr),
+		WithAttempts(DefaultDNSAttempts),
+		WithDelay(DefaultDNSDelay),
+		WithBackoff(ExponentialBackoff),
+		WithMaxDelay(DefaultDNSMaxDelay),
+		WithJitter(FullJitter),
+	}
+	o := newOptions(append(defaults, opts...))
+	if o.matcher == nil {
+		o.matcher = CompilePolicies(o.policies)
+	}
+	return &Resolver{resolver: resolver, opts: o, stale: map[string]lookupResult{}}
+}
+
+// LookupHost looks up the addresses of host, see net.Resolver.LookupHost
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	res, err := r.lookup(ctx, "host "+host, func(ctx context.Context) (lookupResult, error) {
+		addrs, err := r.resolver.LookupHost(ctx, host)
+		return lookupResult{addrs: addrs}, err
+	})
+	return res.addrs, err
+}
+
+// LookupSRV looks up the SRV records of service, see net.Resolver.LookupSRV
+func (r *Resolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	res, err := r.lookup(ctx, "srv "+service+" "+proto+" "+name, func(ctx context.Context) (lookupResult, error) {
+		cname, srvs, err := r.resolver.LookupSRV(ctx, service, proto, name)
+		return lookupResult{cname: cname, srvs: srvs}, err
+	})
+	return res.cname, res.srvs, err
+}
+
+// lookup runs fn with retries, and remembers its result under key or falls back to the remembered one
+func (r *Resolver) lookup(ctx context.Context, key string, fn func(ctx context.Context) (lookupResult, error)) (lookupResult, error) {
+	res, err := execute(ctx, r.opts, fn)
+	if r.opts.staleLookups <= 0 {
+		return res, err
+	}
+	now := r.opts.clock.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		res.at = now
+		r.stale[key] = res
+		return res.copy(), nil
+	}
+	last, ok := r.stale[key]
+	if !ok || now.Sub(last.at) >= r.opts.staleLookups || ctx.Err() != nil || !IsTemporaryNetErr(err) {
+		if ok && now.Sub(last.at) >= r.opts.staleLookups {
+			delete(r.stale, key)
+		}
+		return res, err
+	}
+	return last.copy(), nil
+}
+
+// copy returns a copy of the result the caller can modify
+func (l lookupResult) copy() lookupResult {
+	c := lookupResult{addrs: append([]string(nil), l.addrs...), cname: l.cname}
+	for _, srv := range l.srvs {
+		s := *srv
+		c.srvs = append(c.srvs, &s)
+	}
+	return c
+}
@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testDNSResolver fails the lookups with the errors of fails, then answers them
+type testDNSResolver struct {
+	fails []error
+	calls int
+}
+
+func (r *testDNSResolver) next() error {
+	r.calls++
+	if len(r.fails) == 0 {
+		return nil
+	}
+	err := r.fails[0]
+	r.fails = r.fails[1:]
+	return err
+}
+
+func (r *testDNSResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	return []string{"10.0.0.1"}, nil
+}
+
+func (r *testDNSResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if err := r.next(); err != nil {
+		return "", nil, err
+	}
+	return "_" + service + "._" + proto + "." + name + ".", []*net.SRV{{Target: "db.example.com.", Port: 5432}}, nil
+}
+
+var (
+	errDNSTemporary = &net.DNSError{Err: "server misbehaving", Name: "db.example.com", IsTemporary: true}
+	errDNSNotFound  = &net.DNSError{Err: "no such host", Name: "db.example.com", IsNotFound: true}
+)
+
+func TestResolverRetriesTemporaryErrors(t *testing.T) {
+	dns := &testDNSResolver{fails: []error{errDNSTemporary, errDNSTemporary}}
+	r := NewResolver(dns, WithClock(&testClock{now: time.Now()}))
+	addrs, err := r.LookupHost(context.Background(), "db.example.com")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	assert.Equal(t, 3, dns.calls)
+
+	// a missing host isn't retried
+	dns = &testDNSResolver{fails: []error{errDNSNotFound}}
+	r = NewResolver(dns, WithClock(&testClock{now: time.Now()}))
+	_, _, err = r.LookupSRV(context.Background(), "postgres", "tcp", "example.com")
+	assert.Equal(t, errDNSNotFound, err)
+	assert.Equal(t, 1, dns.calls)
+
+	// the retries are capped
+	dns = &testDNSResolver{fails: []error{errDNSTemporary, errDNSTemporary, errDNSTemporary}}
+	r = NewResolver(dns, WithAttempts(2), WithClock(&testClock{now: time.Now()}))
+	_, err = r.LookupHost(context.Background(), "db.example.com")
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: errDNSTemporary}, err)
+	assert.Equal(t, 2, dns.calls)
+}
+
+func TestResolverStaleLookups(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	dns := &testDNSResolver{}
+	r := NewResolver(dns, WithAttempts(2), WithStaleLookups(time.Minute), WithClock(clock))
+	cname, srvs, err := r.LookupSRV(context.Background(), "postgres", "tcp", "example.com")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "_postgres._tcp.example.com.", cname)
+	srvs[0].Port = 1
+
+	// the DNS server is down, the last records are returned
+	dns.fails = []error{errDNSTemporary, errDNSTemporary}
+	cname, srvs, err = r.LookupSRV(context.Background(), "postgres", "tcp", "example.com")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "_postgres._tcp.example.com.", cname)
+	assert.Equal(t, []*net.SRV{{Target: "db.example.com.", Port: 5432}}, srvs)
+	assert.Equal(t, 3, dns.calls)
+
+	// another lookup has no records to fall back to
+	dns.fails = []error{errDNSTemporary, errDNSTemporary}
+	_, err = r.LookupHost(context.Background(), "example.com")
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: errDNSTemporary}, err)
+
+	// a missing host isn't covered
+	dns.fails = []error{errDNSNotFound}
+	_, _, err = r.LookupSRV(context.Background(), "postgres", "tcp", "example.com")
+	assert.Equal(t, errDNSNotFound, err)
+
+	// nor an outage longer than the stale period
+	_ = clock.Sleep(context.Background(), time.Minute)
+	dns.fails = []error{errDNSTemporary, errDNSTemporary}
+	_, _, err = r.LookupSRV(context.Background(), "postgres", "tcp", "example.com")
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: errDNSTemporary}, err)
+}
+
+func TestNewResolverDefault(t *testing.T) {
+	r := NewResolver(nil)
+	assert.Equal(t, DNSResolver(net.DefaultResolver), r.resolver)
+	addrs, err := r.LookupHost(context.Background(), "localhost")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, len(addrs) > 0)
+}
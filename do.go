@@ -0,0 +1,25 @@
+package retry
+
+import (
+	"context"
+)
+
+// FuncAttempt is a FuncContext that's also given the number of the attempt starting at 1,
+// so it can change its behavior on retries, i.e: switch to another replica
+type FuncAttempt func(ctx context.Context, attempt int) error
+
+// Do executes fn, inspect the error, and do retry as configured by opts.
+// Without WithPolicies or WithPolicyType, any error is retried by a default policy that
+// makes up to DefaultAttempts attempts DefaultDelay apart, which can be tuned with
+// WithAttempts, WithDelay, WithMinDelay, WithMaxDelay, WithBackoff, WithJitter and WithRetryIf.
+// The retry stops as soon as ctx is cancelled or its deadline passes
+func Do(ctx context.Context, fn FuncContext, opts ...Option) error {
+	return executeVoid(ctx, sharedOptions(opts), fn)
+}
+
+// DoAttempt is like Do, but fn is given the number of the attempt it's called for
+func DoAttempt(ctx context.Context, fn FuncAttempt, opts ...Option) error {
+	return Do(ctx, func(ctx context.Context) error {
+		return fn(ctx, AttemptFromContext(ctx))
+	}, opts...)
+}
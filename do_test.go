@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoDefaultPolicy(t *testing.T) {
+	// any error is retried by the default policy
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("something else")
+	}, WithDelay(time.Millisecond*10))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, DefaultAttempts, calls)
+}
+
+func TestDoWithAttempts(t *testing.T) {
+	var calls int
+	var delays []time.Duration
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("something else")
+	},
+		WithAttempts(3),
+		WithDelay(time.Millisecond*10),
+		WithBackoff(ExponentialBackoff),
+		WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+			delays = append(delays, nextDelay)
+		}),
+	)
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Millisecond * 10, time.Millisecond * 20}, delays)
+}
+
+func TestDoWithRetryIf(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTestSentinel
+		}
+		return errors.New("something else")
+	}, WithDelay(time.Millisecond*10), WithRetryIf(func(err error) bool {
+		return errors.Is(err, errTestSentinel)
+	}))
+	assert.Equal(t, "something else", err.Error())
+	assert.Equal(t, 2, calls)
+}
+
+func TestDoWithPolicyType(t *testing.T) {
+	// StandardPolicy only retries timeouts
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("something else")
+	}, WithPolicyType(StandardPolicy))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoWithEmptyPolicies(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("timed out")
+	}, WithPolicies(nil))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoAttempt(t *testing.T) {
+	var attempts []int
+	replicas := []string{"primary", "secondary"}
+	var used string
+	err := DoAttempt(context.Background(), func(ctx context.Context, attempt int) error {
+		attempts = append(attempts, attempt)
+		used = replicas[(attempt-1)%len(replicas)]
+		if used == "primary" {
+			return errors.New("primary is down")
+		}
+		return nil
+	}, WithDelay(time.Millisecond))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, "secondary", used)
+}
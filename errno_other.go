@@ -0,0 +1,17 @@
+//go:build !unix && !plan9
+
+package retry
+
+import "syscall"
+
+// The errnos of the transient I/O and network failures, see IsTransientIOErr and IsTemporaryNetErr.
+// EWOULDBLOCK and ETXTBSY aren't defined on every one of windows, js and wasip1
+var (
+	interruptedErrnos = []error{syscall.EINTR}
+	tryAgainErrnos    = []error{syscall.EAGAIN}
+	busyErrnos        = []error{syscall.EBUSY}
+	staleErrnos       = []error{syscall.ESTALE}
+	connRefusedErrnos = []error{syscall.ECONNREFUSED}
+	connResetErrnos   = []error{syscall.ECONNRESET, syscall.EPIPE}
+	connAbortedErrnos = []error{syscall.ECONNABORTED}
+)
@@ -0,0 +1,17 @@
+//go:build plan9
+
+package retry
+
+import "syscall"
+
+// The errors of the transient I/O and network failures, see IsTransientIOErr and IsTemporaryNetErr.
+// Plan 9 reports most failures as strings rather than errnos, they're only matched by the policies
+var (
+	interruptedErrnos = []error{syscall.EINTR}
+	tryAgainErrnos    []error
+	busyErrnos        = []error{syscall.EBUSY}
+	staleErrnos       []error
+	connRefusedErrnos []error
+	connResetErrnos   []error
+	connAbortedErrnos []error
+)
@@ -0,0 +1,16 @@
+//go:build unix
+
+package retry
+
+import "syscall"
+
+// The errnos of the transient I/O and network failures, see IsTransientIOErr and IsTemporaryNetErr
+var (
+	interruptedErrnos = []error{syscall.EINTR}
+	tryAgainErrnos    = []error{syscall.EAGAIN, syscall.EWOULDBLOCK}
+	busyErrnos        = []error{syscall.EBUSY, syscall.ETXTBSY}
+	staleErrnos       = []error{syscall.ESTALE}
+	connRefusedErrnos = []error{syscall.ECONNREFUSED}
+	connResetErrnos   = []error{syscall.ECONNRESET, syscall.EPIPE}
+	connAbortedErrnos = []error{syscall.ECONNABORTED}
+)
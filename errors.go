@@ -0,0 +1,239 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrAttemptTimeout is the error of an attempt that didn't finish within the WithAttemptTimeout duration.
+// Its message matches the "timed out" criteria of StandardPolicy. It's also the context.Cause of the
+// context of the attempt once it expires
+var ErrAttemptTimeout = errors.New("retry: attempt timed out")
+
+// ErrMaxElapsedTime is the context.Cause of the context of the attempts once the WithMaxElapsedTime
+// budget is spent, the context's error is still context.DeadlineExceeded
+var ErrMaxElapsedTime = errors.New("retry: max elapsed time exceeded")
+
+// ErrHedgeWon is the context.Cause of the context of the hedged attempts still running when
+// another one succeeded, see Hedge
+var ErrHedgeWon = errors.New("retry: another hedged attempt succeeded")
+
+// ErrRaceWon is the context.Cause of the context of the alternatives still running when another
+// one succeeded, see ExecutorRace
+var ErrRaceWon = errors.New("retry: another alternative succeeded")
+
+// ErrRetryerClosed is returned by the operations started on a Retryer after Close, it's also the
+// context.Cause of the context of the operations cancelled by Close
+var ErrRetryerClosed = errors.New("retry: retryer closed")
+
+// ErrInvalidOption is wrapped by the error of an executor given an option it can't apply, see WithRetryIfResult
+var ErrInvalidOption = errors.New("retry: invalid option")
+
+// ErrRetryableResult is the error of an attempt whose result is retried, see WithRetryIfResult
+var ErrRetryableResult = errors.New("retry: result is retryable")
+
+// ErrStopped is returned when the retries are stopped by WithStopChannel or WithStopFunc
+var ErrStopped = errors.New("retry: stopped")
+
+// ErrBreakerOpen is wrapped with the error of a request a Transport didn't retry because the breaker
+// of its host is open, see HostBreakers
+var ErrBreakerOpen = errors.New("retry: breaker open")
+
+// ErrPoolClosed is returned by Pool.Submit after Close
+var ErrPoolClosed = errors.New("retry: pool closed")
+
+// ErrDeadlineWouldExceed is returned along with the error of the last attempt when the next attempt
+// couldn't finish before the context deadline, see WithDeadlineCheck
+var ErrDeadlineWouldExceed = errors.New("retry: next attempt would exceed the context deadline")
+
+// TransportError is the error of an attempt of an HTTP executor or Transport that got no response,
+// i.e: a refused connection or a timeout, as opposed to a failed response. It's matched against the
+// policies like any other error, on the message of Err, or with IsTransportError as a MatchErrorType,
+// and the policies of NetworkPolicy match the transient ones
+type TransportError struct {
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransportError reports whether an error in err's chain is a *TransportError
+func IsTransportError(err error) bool {
+	var te *TransportError
+	return errors.As(err, &te)
+}
+
+// statusError reports an HTTP response whose status code is not 2xx
+type statusError struct {
+	resp *http.Response
+	// body is the start of the body of resp peeked at, see WithBodyPeek
+	body []byte
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("ERROR: httpStatusCode: %d, httpStatus: %s", e.resp.StatusCode, e.resp.Status)
+}
+
+// StatusCode implements HTTPError
+func (e *statusError) StatusCode() int {
+	return e.resp.StatusCode
+}
+
+// Response implements HTTPError
+func (e *statusError) Response() *http.Response {
+	return e.resp
+}
+
+// asStatusError returns the *statusError in the chain of err, nil if there's none. Unlike errors.As
+// it doesn't allocate when err is the *statusError itself
+func asStatusError(err error) *statusError {
+	if se, ok := err.(*statusError); ok {
+		return se
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se
+	}
+	return nil
+}
+
+// HTTPError is the error of a failed response of the HTTP executors. errors.As finds it in the chain
+// of the error they return, whether the response was retried or not, i.e: a 404 no policy matches
+type HTTPError interface {
+	error
+	// StatusCode returns the status code of the response
+	StatusCode() int
+	// Response returns the response, its body is already drained and closed
+	Response() *http.Response
+}
+
+// StatusCodeOf returns the status code of the failed response err is the error of, see HTTPError,
+// and false if it isn't
+func StatusCodeOf(err error) (int, bool) {
+	var httpErr HTTPError
+	if !errors.As(err, &httpErr) {
+		return 0, false
+	}
+	return httpErr.StatusCode(), true
+}
+
+// Unrecoverable wraps err so the executors return it right away without retrying it, even
+// when it matches one of the policies, i.e: an authentication failure reported as a timeout.
+// The executors return err itself, or the error wrapping the unrecoverable one as is
+func Unrecoverable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &stopError{err: err}
+}
+
+// IsUnrecoverable reports whether an error in err's chain was wrapped by Unrecoverable
+func IsUnrecoverable(err error) bool {
+	var stop *stopError
+	return errors.As(err, &stop)
+}
+
+// stopError wraps an error that must be returned right away without being retried
+type stopError struct {
+	err error
+}
+
+func (e *stopError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stopError) Unwrap() error {
+	return e.err
+}
+
+// unwrapStop returns the error wrapped by err if it's a *stopError, or err itself otherwise
+func unwrapStop(err error) error {
+	for {
+		stop, ok := err.(*stopError)
+		if !ok {
+			return err
+		}
+		err = stop.err
+	}
+}
+
+// ExhaustedError is returned by the executors when the operation still fails after all the
+// retries allowed by the RetryLimit of the matched policy. An error that isn't retried at all,
+// i.e: no policy matches it, is returned as is, see HTTPError for the status code of a response
+type ExhaustedError struct {
+	// StatusCode is the status code of the last response of an HTTP executor, zero otherwise
+	StatusCode int
+	// Attempts is the number of attempts made
+	Attempts int
+	// LastErr is the error of the last attempt
+	LastErr error
+}
+
+// Error returns the message of LastErr, so the error reads the same as the one of the last attempt
+func (e *ExhaustedError) Error() string {
+	return e.LastErr.Error()
+}
+
+func (e *ExhaustedError) Unwrap() error {
+	return e.LastErr
+}
+
+// exhaustedError returns the ExhaustedError of err, the error of the last of attempts
+func exhaustedError(err error, attempts int) error {
+	exhausted := &ExhaustedError{Attempts: attempts, LastErr: err}
+	var se *statusError
+	if errors.As(err, &se) {
+		exhausted.StatusCode = se.resp.StatusCode
+	}
+	return exhausted
+}
+
+// AttemptError is the error of a single failed attempt
+type AttemptError struct {
+	// Attempt is the number of the attempt starting at 1
+	Attempt int
+	// Err is the error returned by the attempt
+	Err error
+	// Start is when the attempt started
+	Start time.Time
+	// Duration is how long the attempt took
+	Duration time.Duration
+}
+
+// AttemptsError is returned by the executors when WithCollectErrors is set and the retry gives up
+type AttemptsError struct {
+	// Err is the error the executor would have returned without WithCollectErrors,
+	// usually the error of the last attempt, or ctx.Err() if the context is done
+	Err error
+	// Attempts holds every failed attempt in order
+	Attempts []AttemptError
+}
+
+func (e *AttemptsError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v (%d failed attempts", e.Err, len(e.Attempts))
+	for _, a := range e.Attempts {
+		fmt.Fprintf(&sb, "; #%d after %v: %v", a.Attempt, a.Duration, a.Err)
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// Unwrap returns Err followed by the error of every attempt, so errors.Is and errors.As
+// can match any of them
+func (e *AttemptsError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts)+1)
+	errs = append(errs, e.Err)
+	for _, a := range e.Attempts {
+		errs = append(errs, a.Err)
+	}
+	return errs
+}
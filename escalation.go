@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// EscalationStage is a stage of an EscalationPolicy, its retries are delayed like the ones of a Policy
+type EscalationStage struct {
+	// Retries is the number of retries of the stage
+	Retries int
+	// DelayDuration, Backoff, Multiplier, MaxDelay and Jitter compute the delays of the retries of the stage
+	// as the fields of a Policy do, the backoff starts over with every stage
+	DelayDuration time.Duration
+	Backoff       BackoffType
+	Multiplier    float64
+	MaxDelay      time.Duration
+	Jitter        JitterType
+	// Fallback, if set, is called by the retries of the stage instead of the operation, i.e: another
+	// replica or region. The next stages keep calling it unless they have a Fallback of their own.
+	// An executor returning a value returns its zero value for a Fallback attempt
+	Fallback AttemptFunc
+}
+
+// policy returns the Policy computing the delays of the stage
+func (s EscalationStage) policy() Policy {
+	return Policy{DelayDuration: s.DelayDuration, Backoff: s.Backoff, Multiplier: s.Multiplier, MaxDelay: s.MaxDelay, Jitter: s.Jitter}
+}
+
+// EscalationPolicy retries an operation through ordered stages changing the strategy along the sequence,
+// i.e: 2 immediate retries, then 3 with an exponential backoff, then 2 against a fallback target
+type EscalationPolicy struct {
+	// Name identifies the policy in the Stats of a Retryer, see Policy.Name
+	Name string
+	// RetryIf reports whether an error is retried, nil retries any error
+	RetryIf func(error) bool
+	// Stages are walked through in order, the operation is given up on after the retries of the last one
+	Stages []EscalationStage
+}
+
+// WithEscalation retries the operations through the stages of p. It replaces the policies, as if
+// WithPolicies was given a single policy matching the errors of RetryIf, and the WithDelayFunc
+func WithEscalation(p EscalationPolicy) Option {
+	retryIf := p.RetryIf
+	if retryIf == nil {
+		retryIf = func(error) bool { return true }
+	}
+	var limit int
+	for _, s := range p.Stages {
+		limit += s.Retries
+	}
+	return func(o *options) {
+		WithPolicies([]Policy{{Name: p.Name, RetryIf: retryIf, RetryLimit: limit}})(o)
+		o.delayFunc = func(attempt int, err error) time.Duration {
+			s, n, ok := p.stage(attempt)
+			if !ok {
+				return -1
+			}
+			policy := s.policy()
+			return policy.nextDelayRand(n, policy.backoffDelay(n-1), o.int63n)
+		}
+		o.middlewares = append(o.middlewares, func(next AttemptFunc) AttemptFunc {
+			return func(ctx context.Context) error {
+				if fallback := p.fallback(AttemptFromContext(ctx) - 1); fallback != nil {
+					return fallback(ctx)
+				}
+				return next(ctx)
+			}
+		})
+	}
+}
+
+// stage returns the stage of the given retry, starting at 1, and the number of the retry within the stage
+func (p EscalationPolicy) stage(retry int) (EscalationStage, int, bool) {
+	for _, s := range p.Stages {
+		if retry <= s.Retries {
+			return s, retry, true
+		}
+		retry -= s.Retries
+	}
+	return EscalationStage{}, 0, false
+}
+
+// fallback returns the Fallback called by the given retry, nil for the first attempt or without one
+func (p EscalationPolicy) fallback(retry int) AttemptFunc {
+	var fallback AttemptFunc
+	for _, s := range p.Stages {
+		if retry <= 0 {
+			break
+		}
+		if s.Fallback != nil {
+			fallback = s.Fallback
+		}
+		retry -= s.Retries
+	}
+	return fallback
+}
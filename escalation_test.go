@@ -0,0 +1,64 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEscalation(t *testing.T) {
+	errDown := errors.New("down")
+	var primary, fallback int
+	var delays []time.Duration
+	err := Do(context.Background(), func(ctx context.Context) error {
+		primary++
+		return errDown
+	}, WithEscalation(EscalationPolicy{
+		Stages: []EscalationStage{
+			{Retries: 2},
+			{Retries: 3, DelayDuration: time.Millisecond, Backoff: ExponentialBackoff},
+			{Retries: 2, DelayDuration: time.Millisecond * 5, Fallback: func(ctx context.Context) error {
+				fallback++
+				if fallback == 2 {
+					return nil
+				}
+				return errDown
+			}},
+		},
+	}), WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		delays = append(delays, nextDelay)
+	}))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 6, primary)
+	assert.Equal(t, 2, fallback)
+	assert.Equal(t, []time.Duration{0, 0, time.Millisecond, time.Millisecond * 2, time.Millisecond * 4, time.Millisecond * 5, time.Millisecond * 5}, delays)
+}
+
+func TestWithEscalationExhausted(t *testing.T) {
+	errDown := errors.New("down")
+	var calls int
+	r := NewRetryer(WithEscalation(EscalationPolicy{
+		Name:    "escalation",
+		RetryIf: func(err error) bool { return errors.Is(err, errDown) },
+		Stages:  []EscalationStage{{Retries: 1}, {Retries: 1, DelayDuration: time.Millisecond}},
+	}))
+	err := r.Run(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errDown
+	})
+	assert.Equal(t, true, errors.Is(err, errDown))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, int64(1), r.Stats()["escalation"].Exhaustions)
+
+	// the errors RetryIf rejects aren't retried
+	calls = 0
+	err = r.Run(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("other")
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+}
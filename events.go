@@ -0,0 +1,144 @@
+package retry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of an Event
+type EventType int
+
+const (
+	// EventAttemptStarted is emitted before every attempt
+	EventAttemptStarted EventType = iota
+	// EventAttemptFailed is emitted after every failed attempt, with its error
+	EventAttemptFailed
+	// EventSleeping is emitted before waiting for the next attempt, with the delay
+	EventSleeping
+	// EventSucceeded is emitted when the operation succeeds
+	EventSucceeded
+	// EventExhausted is emitted when the executor gives up, with the returned error
+	EventExhausted
+	// EventBreakerOpened is emitted when the breaker of a host opens, with the host and the cooldown
+	// as the Delay, see WithBreakerEvents
+	EventBreakerOpened
+	// EventBreakerClosed is emitted when the breaker of a host closes again, with the host
+	EventBreakerClosed
+)
+
+var eventTypeNames = map[EventType]string{
+	EventAttemptStarted: "attempt-started",
+	EventAttemptFailed:  "attempt-failed",
+	EventSleeping:       "sleeping",
+	EventSucceeded:      "succeeded",
+	EventExhausted:      "exhausted",
+	EventBreakerOpened:  "breaker-opened",
+	EventBreakerClosed:  "breaker-closed",
+}
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event is the activity of a retry sequence, see Retryer.Subscribe
+type Event struct {
+	Type EventType
+	// Attempt is the number of the attempt starting at 1
+	Attempt int
+	// Err is the error of the failed attempt, or the error returned by the executor
+	Err error
+	// Reason classifies Err, see ReasonOf, it's empty if Err is nil
+	Reason Reason
+	// Delay is the delay before the next attempt
+	Delay time.Duration
+	// Host is the host of a breaker event, the Attempt of these events is zero
+	Host string
+	// Time is when the event happened
+	Time time.Time
+}
+
+// eventBufferSize is the capacity of the channels returned by Subscribe
+const eventBufferSize = 64
+
+// eventHub fans the events out to the subscribers
+type eventHub struct {
+	mu          sync.RWMutex
+	subscribers map[<-chan Event]chan Event
+	// closed is set by close, the later subscribers get a closed channel
+	closed bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: map[<-chan Event]chan Event{}}
+}
+
+func (h *eventHub) subscribe() <-chan Event {
+	ch := make(chan Event, eventBufferSize)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	h.subscribers[ch] = ch
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch <-chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(c)
+	}
+}
+
+// close unsubscribes every subscriber, the events emitted afterwards are dropped
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch, c := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(c)
+	}
+}
+
+// emit sends e to every subscriber without blocking, a subscriber whose buffer is full misses it
+func (h *eventHub) emit(e Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the events of every operation run by the Retryer, so
+// the retry activity can be consumed without a callback at each call site. The events are
+// dropped rather than slowing the operations down when the channel's buffer is full, so it
+// should be drained promptly. See Unsubscribe to stop receiving them
+func (r *Retryer) Subscribe() <-chan Event {
+	return r.opts.events.subscribe()
+}
+
+// Unsubscribe stops sending events to ch, a channel returned by Subscribe, and closes it
+func (r *Retryer) Unsubscribe(ch <-chan Event) {
+	r.opts.events.unsubscribe(ch)
+}
+
+// WithBreakerEvents makes a Retryer emit the EventBreakerOpened and EventBreakerClosed events of the
+// hosts of b to its subscribers, i.e: the breakers of a Transport whose requests the Retryer's
+// subscribers watch too. The executors without a Retryer ignore it
+func WithBreakerEvents(b *HostBreakers) Option {
+	return func(o *options) {
+		o.breakerEvents = append(o.breakerEvents, b)
+	}
+}
@@ -0,0 +1,64 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryerSubscribe(t *testing.T) {
+	r := NewRetryer(WithAttempts(2), WithDelay(time.Millisecond))
+	events := r.Subscribe()
+	var calls int
+	err := r.Run(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTestSentinel
+		}
+		return nil
+	})
+	assert.Equal(t, true, err == nil)
+	r.Unsubscribe(events)
+
+	var types []EventType
+	for e := range events {
+		types = append(types, e.Type)
+		if e.Type == EventAttemptFailed {
+			assert.Equal(t, errTestSentinel, e.Err)
+		}
+		if e.Type == EventSleeping {
+			assert.Equal(t, time.Millisecond, e.Delay)
+		}
+	}
+	assert.Equal(t, []EventType{EventAttemptStarted, EventAttemptFailed, EventSleeping, EventAttemptStarted, EventSucceeded}, types)
+}
+
+func TestRetryerSubscribeExhausted(t *testing.T) {
+	r := NewRetryer(WithAttempts(1))
+	events := r.Subscribe()
+	err := r.Run(context.Background(), func(ctx context.Context) error {
+		return errTestSentinel
+	})
+	r.Unsubscribe(events)
+	var last Event
+	for e := range events {
+		last = e
+	}
+	assert.Equal(t, EventExhausted, last.Type)
+	assert.Equal(t, err, last.Err)
+	assert.Equal(t, "exhausted", last.Type.String())
+	// unsubscribing twice is a no-op
+	r.Unsubscribe(events)
+}
+
+func TestRetryerSubscribeSlowSubscriber(t *testing.T) {
+	r := NewRetryer(WithAttempts(1))
+	events := r.Subscribe()
+	// nobody reads the events, the operations aren't blocked
+	for i := 0; i < eventBufferSize*2; i++ {
+		_ = r.Run(context.Background(), func(ctx context.Context) error { return nil })
+	}
+	assert.Equal(t, eventBufferSize, len(events))
+}
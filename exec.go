@@ -0,0 +1,101 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+)
+
+// WithExitCodes sets the exit codes of the command runs ExecCommand retries, the policies still decide how.
+// A run exiting with another code fails right away. Without it, any failed run is evaluated against the policies
+func WithExitCodes(codes ...int) Option {
+	return func(o *options) {
+		o.exitCodes = append(o.exitCodes, codes...)
+	}
+}
+
+// ExecCommand runs cmd, and runs it again as configured by opts, see Do, for as long as it fails, i.e: a
+// flaky CLI in a CI job. Since an exec.Cmd runs once, every attempt runs a copy of cmd with its Path, Args,
+// Env, Dir, Stdin, Stdout, Stderr, ExtraFiles, SysProcAttr and WaitDelay, bound to the context of the
+// attempt so it's killed when the attempt times out. A Stdin implementing io.Seeker is rewound before every
+// retry, and the output of every attempt is written to Stdout and Stderr. A command that can't be found
+// or run isn't retried. See WithExitCodes, ExitCode and ExitSignal to match the runs that failed
+func ExecCommand(ctx context.Context, cmd *exec.Cmd, opts ...Option) error {
+	o := newOptions(opts)
+	return Do(ctx, func(ctx context.Context) error {
+		if cmd.Err != nil {
+			return Unrecoverable(cmd.Err)
+		}
+		if attempt := AttemptFromContext(ctx); attempt > 1 {
+			if s, ok := cmd.Stdin.(io.Seeker); ok {
+				if _, err := s.Seek(0, io.SeekStart); err != nil {
+					return Unrecoverable(err)
+				}
+			}
+		}
+		err := commandCopy(ctx, cmd).Run()
+		if err == nil {
+			return nil
+		}
+		code, exited := ExitCode(err)
+		if !exited {
+			if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+				return Unrecoverable(err)
+			}
+			return err
+		}
+		if len(o.exitCodes) > 0 && !containsCode(o.exitCodes, code) {
+			return Unrecoverable(err)
+		}
+		return err
+	}, opts...)
+}
+
+// commandCopy returns a copy of cmd to be run with ctx
+func commandCopy(ctx context.Context, cmd *exec.Cmd) *exec.Cmd {
+	c := exec.CommandContext(ctx, cmd.Path)
+	c.Args = cmd.Args
+	c.Env = cmd.Env
+	c.Dir = cmd.Dir
+	c.Stdin = cmd.Stdin
+	c.Stdout = cmd.Stdout
+	c.Stderr = cmd.Stderr
+	c.ExtraFiles = cmd.ExtraFiles
+	c.SysProcAttr = cmd.SysProcAttr
+	c.WaitDelay = cmd.WaitDelay
+	return c
+}
+
+// ExitCode returns the exit code of the command whose run failed with err, -1 if it was killed by a
+// signal, and false if err isn't an *exec.ExitError, i.e: the command couldn't be started
+func ExitCode(err error) (int, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+	return exitErr.ExitCode(), true
+}
+
+// ExitSignal returns the signal that killed the command whose run failed with err, i.e: a SIGKILL
+// of the OOM killer, and false if it wasn't killed by a signal. The signal is a syscall.Signal on
+// the unix systems, it's always false on the others
+func ExitSignal(err error) (os.Signal, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return nil, false
+	}
+	return exitSignal(exitErr)
+}
+
+// containsCode reports whether codes contains code
+func containsCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
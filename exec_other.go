@@ -0,0 +1,13 @@
+//go:build !unix
+
+package retry
+
+import (
+	"os"
+	"os/exec"
+)
+
+// exitSignal returns false, the commands aren't killed by unix signals
+func exitSignal(exitErr *exec.ExitError) (os.Signal, bool) {
+	return nil, false
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyCommand returns a sh command exiting with the given codes on its successive runs, then 0
+func flakyCommand(t *testing.T, codes ...string) *exec.Cmd {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	counter := filepath.Join(t.TempDir(), "runs")
+	script := `n=$(cat "$0" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$0"; echo "run $n"; ` +
+		`set -- ` + strings.Join(codes, " ") + ` 0; shift $((n-1)) 2>/dev/null || exit 0; exit ${1:-0}`
+	return exec.Command("sh", "-c", script, counter)
+}
+
+func TestExecCommand(t *testing.T) {
+	cmd := flakyCommand(t, "3", "3")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := ExecCommand(context.Background(), cmd, WithAttempts(3), WithDelay(time.Millisecond))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "run 1\nrun 2\nrun 3\n", out.String())
+
+	// the other exit codes aren't retried
+	cmd = flakyCommand(t, "3", "4")
+	out.Reset()
+	cmd.Stdout = &out
+	err = ExecCommand(context.Background(), cmd, WithAttempts(5), WithDelay(time.Millisecond), WithExitCodes(3))
+	code, ok := ExitCode(err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 4, code)
+	assert.Equal(t, "run 1\nrun 2\n", out.String())
+}
+
+func TestExecCommandNotFound(t *testing.T) {
+	var calls int
+	err := ExecCommand(context.Background(), exec.Command("retry-test-no-such-command"), WithAttempts(3),
+		WithOnRetry(func(attempt int, err error, nextDelay time.Duration) { calls++ }))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 0, calls)
+	_, ok := ExitCode(err)
+	assert.Equal(t, false, ok)
+}
+
+func TestExitSignal(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	err := exec.Command("sh", "-c", "kill -9 $$").Run()
+	sig, ok := ExitSignal(err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, syscall.SIGKILL, sig)
+	code, _ := ExitCode(err)
+	assert.Equal(t, -1, code)
+
+	_, ok = ExitSignal(exec.Command("sh", "-c", "exit 1").Run())
+	assert.Equal(t, false, ok)
+}
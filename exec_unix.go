@@ -0,0 +1,18 @@
+//go:build unix
+
+package retry
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// exitSignal returns the signal that killed the command of exitErr
+func exitSignal(exitErr *exec.ExitError) (os.Signal, bool) {
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return nil, false
+	}
+	return status.Signal(), true
+}
@@ -1,8 +1,13 @@
 package retry
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -13,77 +18,588 @@ type Func func() error
 // FuncHTTP is a function with return httpResponse and error(i.e: http status code). The httpResponse status code will be executed and evaluated by Executor
 type FuncHTTP func() (*http.Response, error)
 
-// Executor executes a closure, inspect the error, and do retry if necessary
+// FuncContext is a context-aware Func. The context passed in is the one given to the executor
+// so the operation can observe cancellation
+type FuncContext func(ctx context.Context) error
+
+// FuncHTTPContext is a context-aware FuncHTTP. The context passed in is the one given to the executor
+// so the request can be bound to it
+type FuncHTTPContext func(ctx context.Context) (*http.Response, error)
+
+// Executor executes a closure, inspect the error, and do retry if necessary.
+// The policies are StandardPolicy unless changed with SetDefaultPolicyType or SetDefaultPolicies
 func Executor(fn Func) error {
-	return ExecutorWithPolicyType(StandardPolicy, fn)
+	return executeFunc(context.Background(), compiledDefaults().options(), fn)
 }
 
 // ExecutorWithPolicyType executes a func, inspect the error and evaluate based on retryPolicies, and do retry if necessary
 func ExecutorWithPolicyType(policyType PolicyType, fn Func) error {
-	retryPolicies := GetRetryPolicies(policyType)
-	return ExecutorWithPolicies(retryPolicies, fn)
+	return executeFunc(context.Background(), compiledPolicyType(policyType).options(), fn)
 }
 
 // ExecutorWithPolicies executes a func, inspect the error and evaluate based retryPolicies, and do retry if necessary
 func ExecutorWithPolicies(retryPolicies []Policy, fn Func) error {
-	err := fn()
-	if err != nil {
-		var attempt = 1
-		for {
-			delay, limit, ok := shouldRetry(retryPolicies, 0, err.Error())
-			if ok && attempt <= limit {
-				time.Sleep(delay)
-				err = fn()
-				if err == nil {
-					return nil
-				}
-				attempt++
-			} else {
-				return err
-			}
-		}
-	}
-	return err
+	return executeFunc(context.Background(), newOptions(withPolicies(retryPolicies, nil)), fn)
+}
+
+// ExecutorWithContext executes a closure, inspect the error, and do retry if necessary.
+// The retry stops as soon as ctx is cancelled or its deadline passes
+func ExecutorWithContext(ctx context.Context, fn FuncContext, opts ...Option) error {
+	return executeVoid(ctx, compiledDefaults().optionsWith(opts), fn)
+}
+
+// ExecutorWithPolicyTypeContext is the context-aware version of ExecutorWithPolicyType
+func ExecutorWithPolicyTypeContext(ctx context.Context, policyType PolicyType, fn FuncContext, opts ...Option) error {
+	return executeVoid(ctx, compiledPolicyType(policyType).optionsWith(opts), fn)
+}
+
+// ExecutorWithPoliciesContext is the context-aware version of ExecutorWithPolicies.
+// If ctx is done while waiting for the next attempt, ctx.Err() is returned
+func ExecutorWithPoliciesContext(ctx context.Context, retryPolicies []Policy, fn FuncContext, opts ...Option) error {
+	return executeVoid(ctx, newOptions(withPolicies(retryPolicies, opts)), fn)
 }
 
 // ExecutorHTTP executes a closure, inspect the error, and do retry if necessary
 func ExecutorHTTP(fn FuncHTTP) error {
-	return ExecutorHTTPWithPolicyType(StandardPolicy, fn)
+	return ExecutorHTTPWithContext(context.Background(), func(context.Context) (*http.Response, error) {
+		return fn()
+	})
 }
 
 // ExecutorHTTPWithPolicyType executes a func, inspect the error and evaluate based on retryPolicies, and do retry if necessary
 func ExecutorHTTPWithPolicyType(policyType PolicyType, fn FuncHTTP) error {
-	retryPolicies := GetRetryPolicies(policyType)
-	return ExecutorHTTPWithPolicies(retryPolicies, fn)
+	return ExecutorHTTPWithPolicyTypeContext(context.Background(), policyType, func(context.Context) (*http.Response, error) {
+		return fn()
+	})
 }
 
 // ExecutorHTTPWithPolicies executes a func, inspect the error and evaluate based retryPolicies, and do retry if necessary
 func ExecutorHTTPWithPolicies(retryPolicies []Policy, fn FuncHTTP) error {
-	resp, err := fn()
+	return ExecutorHTTPWithPoliciesContext(context.Background(), retryPolicies, func(context.Context) (*http.Response, error) {
+		return fn()
+	})
+}
+
+// ExecutorHTTPWithContext executes a closure, inspect the http response, and do retry if necessary.
+// The retry stops as soon as ctx is cancelled or its deadline passes
+func ExecutorHTTPWithContext(ctx context.Context, fn FuncHTTPContext, opts ...Option) error {
+	_, err := executeHTTP(ctx, false, compiledDefaults().optionsWith(opts), fn)
+	return err
+}
+
+// ExecutorHTTPWithPolicyTypeContext is the context-aware version of ExecutorHTTPWithPolicyType
+func ExecutorHTTPWithPolicyTypeContext(ctx context.Context, policyType PolicyType, fn FuncHTTPContext, opts ...Option) error {
+	_, err := executeHTTP(ctx, false, compiledPolicyType(policyType).optionsWith(opts), fn)
+	return err
+}
+
+// ExecutorHTTPWithPoliciesContext is the context-aware version of ExecutorHTTPWithPolicies.
+// If ctx is done while waiting for the next attempt, ctx.Err() is returned
+func ExecutorHTTPWithPoliciesContext(ctx context.Context, retryPolicies []Policy, fn FuncHTTPContext, opts ...Option) error {
+	_, err := executeHTTP(ctx, false, newOptions(withPolicies(retryPolicies, opts)), fn)
+	return err
+}
+
+// execute runs fn, and retries it for as long as the returned error matches one of
+// the policies in o, the RetryLimit of the matched policy isn't reached yet, the
+// MaxElapsedTime budget isn't spent, and it's not Unrecoverable. The result and error of
+// the last attempt are returned
+func execute[T any](ctx context.Context, o *options, fn func(context.Context) (T, error)) (T, error) {
+	return executeResult(ctx, o, fn, nil)
+}
+
+// executeVoid is execute for an operation without result, fn is called as is
+func executeVoid(ctx context.Context, o *options, fn FuncContext) error {
+	_, err := executeOperation(ctx, o, operation[struct{}]{void: fn}, nil)
+	return err
+}
+
+// executeFunc is executeVoid for an operation without context
+func executeFunc(ctx context.Context, o *options, fn Func) error {
+	_, err := executeOperation(ctx, o, operation[struct{}]{plain: fn}, nil)
+	return err
+}
+
+// executeResult is execute that also reports how the retry went in res, unless it's nil
+func executeResult[T any](ctx context.Context, o *options, fn func(context.Context) (T, error), res *Result) (T, error) {
+	return executeOperation(ctx, o, operation[T]{fn: fn}, res)
+}
+
+// operation is the function an execution retries: fn, or one of the forms the executors are given,
+// which is called as is so that the first attempt doesn't allocate a closure adapting it
+type operation[T any] struct {
+	fn     func(context.Context) (T, error)
+	void   FuncContext
+	plain  Func
+	plainT FuncT[T]
+	// bind ties release to the lifetime of a result that outlives the attempt, such as the body of
+	// a response, and reports whether it did. The execution doesn't cancel the contexts of such a result
+	bind func(result T, release func()) bool
+}
+
+// call calls the function of op
+func (op operation[T]) call(ctx context.Context) (T, error) {
+	switch {
+	case op.fn != nil:
+		return op.fn(ctx)
+	case op.void != nil:
+		return zeroOf[T](), op.void(ctx)
+	case op.plain != nil:
+		return zeroOf[T](), op.plain()
+	}
+	return op.plainT()
+}
+
+// function returns the function of op, adapted to the signature of fn if needed
+func (op operation[T]) function() func(context.Context) (T, error) {
+	if op.fn != nil {
+		return op.fn
+	}
+	return op.call
+}
+
+// executeOperation runs the retry loop of op, see executeResult
+func executeOperation[T any](ctx context.Context, o *options, op operation[T], res *Result) (T, error) {
+	var e execution[T]
+	if !e.init(ctx, o, op, res) {
+		return e.result, e.err
+	}
+	defer e.end()
+	if o.initialDelay > 0 {
+		if err := o.sleep(e.ctx, o.initialDelay); err != nil {
+			e.abort(err)
+			return e.result, e.err
+		}
+	}
+	e.begin()
+	for e.call(); e.err != nil; e.call() {
+		if !e.decide() {
+			return e.result, e.err
+		}
+		if err := o.sleep(e.ctx, e.decision.Delay); err != nil {
+			e.interrupted(err)
+			return e.result, e.err
+		}
+		if !e.resume() {
+			return e.result, e.err
+		}
+	}
+	e.succeed()
+	return e.result, nil
+}
+
+// execution is the state of the retry loop of fn, it's driven by executeResult, which sleeps between
+// the attempts, and by Retryer.Go, which parks the operation in a Scheduler instead.
+// Once the execution is over, result and err are what the executor returns
+type execution[T any] struct {
+	// parent is the context given to the executor, ctx is the one of the attempts
+	parent, ctx context.Context
+	cancel      context.CancelFunc
+	o           *options
+	op          operation[T]
+	// bind is the bind of op, see operation
+	bind func(result T, release func()) bool
+	res  *Result
+	// retryIfResult is set by WithRetryIfResult
+	retryIfResult func(T) bool
+	start         time.Time
+	endOperation  func(error)
+	// begun reports whether the first attempt is made, or about to be
+	begun bool
+	// slot reports whether a slot of WithMaxConcurrentRetries is held
+	slot       bool
+	attempt    int
+	info       AttemptInfo
+	attempts   []AttemptError
+	totalDelay time.Duration
+	decision   Decision
+	// evaluator is the retry accounting of every policy, see Policy.RetryLimit
+	evaluator PolicyEvaluator
+	result    T
+	err       error
+}
+
+// init prepares the execution of op as configured by o and res, see executeResult.
+// It reports false if the execution is over before the first attempt
+func (e *execution[T]) init(ctx context.Context, o *options, op operation[T], res *Result) bool {
+	*e = execution[T]{parent: ctx, ctx: ctx, o: o, op: op, bind: op.bind, res: res, start: o.clock.Now(), attempt: 1, evaluator: PolicyEvaluator{o: o}}
+	e.info = firstAttempt
+	if e.res == nil && o.onExhausted != nil {
+		e.res = &Result{}
+	}
+	if e.res != nil {
+		e.res.StartedAt = e.start
+	}
+	var ok bool
+	if e.retryIfResult, ok = retryIfResultOf[T](o); !ok {
+		return e.early(fmt.Errorf("%w: WithRetryIfResult of %T given to an executor of %T", ErrInvalidOption, o.retryIfResult, e.result))
+	}
+	if err := ctx.Err(); err != nil {
+		return e.early(err)
+	}
+	if o.stopped() {
+		return e.early(ErrStopped)
+	}
+	if o.tracer != nil {
+		e.ctx, e.endOperation = o.tracer.StartOperation(e.ctx)
+	}
+	if len(o.middlewares) > 0 {
+		e.op = operation[T]{fn: withMiddlewares(o.middlewares, e.op.function())}
+	}
+	if o.recoverPanics {
+		e.op = operation[T]{fn: recoverPanics(e.op.function())}
+	}
+	if o.maxElapsedTime > 0 {
+		e.ctx, e.cancel = context.WithTimeoutCause(e.ctx, o.maxElapsedTime, ErrMaxElapsedTime)
+	}
+	return true
+}
+
+// early ends the execution with err before it starts, and returns false for init
+func (e *execution[T]) early(err error) bool {
+	if e.res != nil {
+		e.res.EndedAt = e.start
+	}
+	e.err = err
+	return false
+}
+
+// end releases what the execution holds once it's over, the context of WithMaxElapsedTime is
+// released with the result instead if it's bound to it
+func (e *execution[T]) end() {
+	if e.cancel != nil && (e.bind == nil || !e.bind(e.result, e.cancel)) {
+		e.cancel()
+	}
+	if e.slot {
+		e.o.retrySlots.release()
+	}
+}
+
+// abort ends the execution with err while it waits for its first attempt
+func (e *execution[T]) abort(err error) {
+	if e.res != nil {
+		e.res.EndedAt = e.o.clock.Now()
+	}
+	if e.endOperation != nil {
+		e.endOperation(err)
+	}
+	e.err = err
+}
+
+// begin is called before the first attempt
+func (e *execution[T]) begin() {
+	e.begun = true
+	if e.o.budget != nil {
+		e.o.budget.deposit()
+	}
+}
+
+func (e *execution[T]) emit(t EventType, err error, delay time.Duration) {
+	if e.o.events != nil {
+		e.o.events.emit(Event{Type: t, Attempt: e.attempt, Err: err, Reason: ReasonOf(err), Delay: delay, Time: e.o.clock.Now()})
+	}
+}
+
+// call makes the current attempt
+func (e *execution[T]) call() {
+	o := e.o
+	e.emit(EventAttemptStarted, nil, 0)
+	start := o.clock.Now()
+	var attemptCtx context.Context
+	if e.attempt == 1 {
+		attemptCtx = withFirstAttempt(e.ctx)
+	} else {
+		attemptCtx = withAttemptInfo(e.ctx, e.info)
+	}
+	var endAttempt func(error)
+	if o.tracer != nil {
+		attemptCtx, endAttempt = o.tracer.StartAttempt(attemptCtx, e.attempt)
+	}
+	var result T
+	var err error
+	if timeout := o.timeoutOf(e.ctx, e.info); timeout > 0 {
+		result, err = callWithTimeout(attemptCtx, timeout, e.op.function(), e.bind)
+	} else {
+		result, err = e.op.call(attemptCtx)
+	}
+	if err != nil && o.successIf != nil && o.successIf(unwrapStop(err)) {
+		err = nil
+	}
+	if err == nil && e.retryIfResult != nil && e.retryIfResult(result) {
+		err = ErrRetryableResult
+	}
+	if endAttempt != nil {
+		endAttempt(err)
+	}
+	if o.metrics != nil {
+		o.metrics.RecordAttempt(e.attempt, err)
+	}
+	if o.adaptive != nil {
+		o.adaptive.observe(err != nil, o.clock.Now().Sub(start))
+	}
 	if err != nil {
-		return err
-	}
-	if resp.StatusCode >= 300 {
-		var attempt = 1
-		for {
-			delay, limit, ok := shouldRetry(retryPolicies, int(resp.StatusCode), resp.Status)
-			if ok && attempt <= limit {
-				time.Sleep(delay)
-				resp, err = fn()
-				if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-					return nil
-				}
-				attempt++
-			} else {
-				return fmt.Errorf("ERROR: httpStatusCode: %d, httpStatus: %s", resp.StatusCode, resp.Status)
+		e.emit(EventAttemptFailed, unwrapStop(err), 0)
+	}
+	if err != nil && (o.collectErrors || e.res != nil) {
+		e.attempts = append(e.attempts, AttemptError{Attempt: e.attempt, Err: unwrapStop(err), Start: start, Duration: o.clock.Now().Sub(start)})
+	}
+	e.result, e.err = result, err
+}
+
+// decide evaluates the failed attempt, and reports whether it's retried after decision.Delay.
+// Otherwise the execution is over
+func (e *execution[T]) decide() bool {
+	o := e.o
+	var ferr error
+	e.decision, ferr = e.evaluator.evaluate(e.err, e.attempt, o.clock.Now())
+	if o.stats != nil && e.decision.Matched {
+		o.stats.update(e.decision.Policy.Name, func(ps *PolicyStats) { ps.Matches++ })
+	}
+	if !e.decision.Retry {
+		e.fail(e.result, ferr)
+		return false
+	}
+	delay := e.decision.Delay
+	if o.maxElapsedTime > 0 && o.clock.Now().Sub(e.start)+delay >= o.maxElapsedTime {
+		// the next attempt would start after the budget is spent
+		e.stop(ReasonMaxElapsedTime, e.result, e.err)
+		return false
+	}
+	if deadline, ok := e.parent.Deadline(); ok && o.deadlineCheck && deadline.Sub(o.clock.Now()) < delay+o.minAttempt {
+		e.stop(ReasonDeadlineWouldExceed, e.result, fmt.Errorf("%w: %w", ErrDeadlineWouldExceed, e.err))
+		return false
+	}
+	if o.budget != nil && !o.budget.withdraw() {
+		e.stop(ReasonBudgetExhausted, e.result, budgetExhaustedError(e.err))
+		return false
+	}
+	if o.retrySlots != nil && !e.slot {
+		if !o.retrySlots.acquire(e.ctx) {
+			if perr := e.parent.Err(); perr != nil {
+				e.stop(ReasonCanceled, zeroOf[T](), perr)
+				return false
 			}
+			e.stop(ReasonMaxConcurrentRetries, e.result, fmt.Errorf("%w: %w", ErrMaxConcurrentRetries, e.err))
+			return false
 		}
+		e.slot = true
 	}
-	return err
+	if o.stopped() {
+		e.stop(ReasonStopped, e.result, fmt.Errorf("%w: %w", ErrStopped, e.err))
+		return false
+	}
+	if o.onRetry != nil {
+		o.onRetry(e.attempt, e.err, delay)
+	}
+	if o.metrics != nil {
+		o.metrics.ObserveDelay(delay)
+	}
+	e.emit(EventSleeping, nil, delay)
+	if o.tracer != nil {
+		o.tracer.Delay(e.ctx, e.attempt, delay)
+	}
+	return true
+}
+
+// interrupted ends the execution when the wait for the next attempt is cut short with err
+func (e *execution[T]) interrupted(err error) {
+	if perr := e.parent.Err(); perr != nil {
+		e.stop(ReasonCanceled, zeroOf[T](), perr)
+		return
+	}
+	if err == ErrRetryerClosed || err == ErrStopped {
+		e.stop(ReasonOf(err), e.result, fmt.Errorf("%w: %w", err, e.err))
+		return
+	}
+	e.stop(causeReason(e.ctx, err), e.result, e.err)
+}
+
+// resume is called once the delay before the next attempt has passed, and reports whether it's made.
+// Otherwise the execution is over
+func (e *execution[T]) resume() bool {
+	o := e.o
+	delay := e.decision.Delay
+	e.totalDelay += delay
+	e.evaluator.record(e.decision)
+	if o.stats != nil {
+		o.stats.update(e.decision.Policy.Name, func(ps *PolicyStats) { ps.TotalDelay += delay })
+	}
+	if o.limiter != nil {
+		if lerr := o.limiter.Wait(e.ctx); lerr != nil {
+			if perr := e.parent.Err(); perr != nil {
+				e.stop(ReasonCanceled, zeroOf[T](), perr)
+				return false
+			}
+			e.stop(causeReason(e.ctx, lerr), e.result, e.err)
+			return false
+		}
+	}
+	if o.stopped() {
+		e.stop(ReasonStopped, e.result, fmt.Errorf("%w: %w", ErrStopped, e.err))
+		return false
+	}
+	e.attempt++
+	e.info = AttemptInfo{Attempt: e.attempt, Policy: e.decision.Policy, PolicyIndex: e.decision.PolicyIndex, RemainingRetries: e.decision.RemainingRetries}
+	return true
+}
+
+// report fills res with how the retry went
+func (e *execution[T]) report() {
+	if res := e.res; res != nil {
+		res.Attempts = e.attempt
+		res.TotalDelay = e.totalDelay
+		res.PerAttemptErrors = e.attempts
+		res.LastDecision = e.decision
+		if len(e.attempts) > 0 && e.attempts[len(e.attempts)-1].Attempt == e.attempt {
+			res.LastError = e.attempts[len(e.attempts)-1].Err
+		}
+		res.EndedAt = e.o.clock.Now()
+	}
+}
+
+// fail ends the execution with result and err once it's given up on
+func (e *execution[T]) fail(result T, err error) {
+	o := e.o
+	e.report()
+	if o.collectErrors {
+		err = &AttemptsError{Err: err, Attempts: e.attempts}
+	}
+	if o.metrics != nil {
+		o.metrics.RecordExhausted(e.attempt, err)
+	}
+	e.emit(EventExhausted, err, 0)
+	if o.stats != nil && e.decision.Matched {
+		o.stats.update(e.decision.Policy.Name, func(ps *PolicyStats) { ps.Exhaustions++ })
+	}
+	if o.burn != nil {
+		o.burn.record(o.clock.Now(), e.attempt > 1, e.decision.Matched)
+	}
+	if o.onExhausted != nil && e.parent.Err() == nil {
+		o.onExhausted(err, *e.res)
+	}
+	if e.endOperation != nil {
+		e.endOperation(err)
+	}
+	e.result, e.err = result, err
+}
+
+// stop fails with err after a retry decided by a policy is prevented for reason
+func (e *execution[T]) stop(reason Reason, result T, err error) {
+	e.decision.Retry = false
+	e.decision.Reason = reason
+	e.fail(result, err)
 }
 
-// GetRetryPolicies returns list of retry policies
+// succeed ends the execution after a successful attempt
+func (e *execution[T]) succeed() {
+	o := e.o
+	e.report()
+	if o.metrics != nil {
+		o.metrics.RecordSuccess(e.attempt)
+	}
+	e.emit(EventSucceeded, nil, 0)
+	if o.stats != nil && e.decision.Matched {
+		o.stats.update(e.decision.Policy.Name, func(ps *PolicyStats) { ps.RetriedSuccesses++ })
+	}
+	if o.burn != nil {
+		o.burn.record(o.clock.Now(), e.attempt > 1, false)
+	}
+	if e.endOperation != nil {
+		e.endOperation(nil)
+	}
+}
+
+// callWithTimeout runs fn with a context that expires after timeout. If fn doesn't return
+// by then, it's abandoned in its goroutine and ErrAttemptTimeout is returned.
+// The context is released once fn returns, or with the result if bind, unless it's nil, binds it
+func callWithTimeout[T any](ctx context.Context, timeout time.Duration, fn func(context.Context) (T, error), bind func(T, func()) bool) (T, error) {
+	attemptCtx, cancel := context.WithTimeoutCause(ctx, timeout, ErrAttemptTimeout)
+	type outcome struct {
+		result T
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := fn(attemptCtx)
+		done <- outcome{result: result, err: err}
+	}()
+	select {
+	case out := <-done:
+		if out.err != nil && ctx.Err() == nil && errors.Is(out.err, context.DeadlineExceeded) {
+			cancel()
+			return out.result, ErrAttemptTimeout
+		}
+		if bind == nil || !bind(out.result, cancel) {
+			cancel()
+		}
+		return out.result, out.err
+	case <-attemptCtx.Done():
+		cancel()
+		var zero T
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, ErrAttemptTimeout
+	}
+}
+
+// timeoutOf returns the timeout of the attempt of info, see WithAttemptTimeout and WithDeadlinePropagation,
+// zero if there's none
+func (o *options) timeoutOf(ctx context.Context, info AttemptInfo) time.Duration {
+	timeout := o.attemptTimeout
+	if !o.propagateDeadline {
+		return timeout
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout
+	}
+	share := deadline.Sub(o.clock.Now())
+	if share <= 0 {
+		// the context is done already, the attempt sees it canceled
+		return timeout
+	}
+	if n := o.attemptsLeft(info); n > 1 {
+		if o.deadlineFraction > 0 && o.deadlineFraction < 1 {
+			share = time.Duration(float64(share) * o.deadlineFraction)
+		} else {
+			share /= time.Duration(n)
+		}
+	}
+	if timeout > 0 && timeout < share {
+		return timeout
+	}
+	return share
+}
+
+// attemptsLeft returns the number of attempts left, including the attempt of info
+func (o *options) attemptsLeft(info AttemptInfo) int {
+	n := info.RemainingRetries + 1
+	if info.Attempt <= 1 {
+		for _, p := range o.policies {
+			n = max(n, p.RetryLimit+1)
+		}
+		if o.retryIfResult != nil {
+			n = max(n, o.policy.RetryLimit+1)
+		}
+	}
+	if o.maxTotalAttempts > 0 {
+		n = min(n, o.maxTotalAttempts-info.Attempt+1)
+	}
+	return max(n, 1)
+}
+
+// GetRetryPolicies returns list of retry policies.
+// The policies of a type registered with RegisterPolicyType take precedence over the built-in ones
 func GetRetryPolicies(policyType PolicyType) []Policy {
+	if policies, ok := registeredPolicies(policyType); ok {
+		return policies
+	}
+	return builtinPolicies(policyType)
+}
+
+// builtinPolicies returns the built-in policies of policyType, nil if it's not a built-in type
+func builtinPolicies(policyType PolicyType) []Policy {
 	var policies []Policy
 	switch policyType {
 	case HTTPPolicy:
@@ -100,7 +616,45 @@ func GetRetryPolicies(policyType PolicyType) []Policy {
 				DelayDuration:   time.Second * 2,
 				RetryLimit:      3,
 			},
+			tooManyRequestsPolicy(),
+			serverErrorPolicy(http.StatusInternalServerError),
+			serverErrorPolicy(http.StatusBadGateway),
+			serverErrorPolicy(http.StatusGatewayTimeout),
 		}
+	case StrictHTTPPolicy:
+		// only the status codes telling the request wasn't processed, so even
+		// a non idempotent request can be sent again
+		policies = []Policy{
+			{
+				ErrorCodeNumber: http.StatusServiceUnavailable,
+				ErrorCodeString: http.StatusText(http.StatusServiceUnavailable),
+				DelayDuration:   time.Second * 2,
+				RetryLimit:      3,
+			},
+			{
+				ErrorCodeNumber: http.StatusRequestTimeout,
+				ErrorCodeString: http.StatusText(http.StatusRequestTimeout),
+				DelayDuration:   time.Second * 2,
+				RetryLimit:      3,
+			},
+			tooManyRequestsPolicy(),
+		}
+	case NetworkPolicy:
+		policies = networkPolicies()
+	case DatabasePolicy:
+		policies = databasePolicies()
+	case CloudThrottlePolicy:
+		policies = []Policy{
+			cloudThrottlePolicy(0, "ThrottlingException"),
+			cloudThrottlePolicy(0, "RequestLimitExceeded"),
+			cloudThrottlePolicy(0, "SlowDown"),
+			cloudThrottlePolicy(http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests)),
+			cloudThrottlePolicy(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+		}
+	case ReconnectPolicy:
+		policies = []Policy{NewReconnectPolicy()}
+	case IOPolicy:
+		policies = ioPolicies()
 	case StandardPolicy:
 		policies = []Policy{
 			{
@@ -118,28 +672,233 @@ func GetRetryPolicies(policyType PolicyType) []Policy {
 	return policies
 }
 
-func shouldRetry(criteria []Policy, errCodeNumber int, errCodeString string) (time.Duration, int, bool) {
-	if criteria == nil {
-		return time.Duration(0), 0, false
+// tooManyRequestsPolicy backs off exponentially, it's usually overridden by the Retry-After header
+func tooManyRequestsPolicy() Policy {
+	return Policy{
+		ErrorCodeNumber: http.StatusTooManyRequests,
+		ErrorCodeString: http.StatusText(http.StatusTooManyRequests),
+		DelayDuration:   time.Second * 2,
+		RetryLimit:      4,
+		Backoff:         ExponentialBackoff,
+		MaxDelay:        time.Second * 30,
+		Jitter:          FullJitter,
+	}
+}
+
+// cloudThrottlePolicy backs off like the cloud SDKs do when they're throttled, with more
+// attempts than the other policies as the throttling can last a while
+func cloudThrottlePolicy(errCodeNumber int, errCodeString string) Policy {
+	return Policy{
+		ErrorCodeNumber: errCodeNumber,
+		ErrorCodeString: errCodeString,
+		DelayDuration:   time.Millisecond * 500,
+		RetryLimit:      8,
+		Backoff:         ExponentialBackoff,
+		MaxDelay:        time.Second * 20,
+		Jitter:          FullJitter,
+	}
+}
+
+// NewReconnectPolicy returns a policy for the reconnect loops of long-lived connections,
+// i.e: a websocket or a message broker client. It retries any error but the unrecoverable ones
+// until the context is done, waiting from 100ms up to 30s with decorrelated jitter
+func NewReconnectPolicy() Policy {
+	return Policy{
+		RetryIf:       func(error) bool { return true },
+		DelayDuration: time.Millisecond * 100,
+		RetryLimit:    math.MaxInt,
+		MaxDelay:      time.Second * 30,
+		Jitter:        DecorrelatedJitter,
+	}
+}
+
+func serverErrorPolicy(statusCode int) Policy {
+	return Policy{
+		ErrorCodeNumber: statusCode,
+		ErrorCodeString: http.StatusText(statusCode),
+		DelayDuration:   time.Second,
+		RetryLimit:      3,
+		Backoff:         ExponentialBackoff,
+		MaxDelay:        time.Second * 10,
+		Jitter:          EqualJitter,
+	}
+}
+
+// matchPolicy returns the first policy that matches err
+func matchPolicy(criteria []Policy, err error) (Policy, bool) {
+	if i, ok := matchPolicyIndex(criteria, err); ok {
+		return criteria[i], true
+	}
+	return Policy{}, false
+}
+
+// matchPolicyIndex returns the index of the first policy that matches err
+func matchPolicyIndex(criteria []Policy, err error) (int, bool) {
+	for i, c := range criteria {
+		if c.matches(err) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// policyState is the retry accounting of a policy during one execution
+type policyState struct {
+	// retries is the number of retries the policy allowed so far
+	retries int
+	// delay is the delay waited before the last of them
+	delay time.Duration
+}
+
+// matches reports whether err can be retried according to the policy.
+// When any of ErrorPattern, StatusCodes, StatusRanges, MatchError, MatchErrorType, RetryIf, RetryIfResponse,
+// BodyContains or RetryIfBody is set,
+// err is matched only with them and the policy matches if one of them does.
+// Otherwise an HTTP status failure is matched on its status code and status text,
+// and any other error on its message
+func (p Policy) matches(err error) bool {
+	var se *statusError
+	isStatus := errors.As(err, &se)
+	if p.hasMatchers() {
+		return p.ErrorPattern != nil && p.matchesPattern(err, se) ||
+			isStatus && p.matchesStatus(se.resp.StatusCode) ||
+			p.MatchError != nil && errors.Is(err, p.MatchError) ||
+			p.MatchErrorType != nil && p.MatchErrorType(err) ||
+			p.RetryIf != nil && p.RetryIf(err) ||
+			p.RetryIfResponse != nil && isStatus && p.RetryIfResponse(se.resp) ||
+			isStatus && p.matchesBody(se.body)
+	}
+	if isStatus {
+		return p.matchesCode(se.resp.StatusCode, se.resp.Status)
+	}
+	return p.matchesCode(0, err.Error())
+}
+
+// matchesOnBody reports whether the policy matches on the body of the responses
+func (p Policy) matchesOnBody() bool {
+	return p.BodyContains != "" || p.RetryIfBody != nil
+}
+
+// matchesBody reports whether the start of a response body matches BodyContains or RetryIfBody
+func (p Policy) matchesBody(body []byte) bool {
+	if body == nil {
+		return false
+	}
+	return p.BodyContains != "" && bytes.Contains(body, []byte(p.BodyContains)) ||
+		p.RetryIfBody != nil && p.RetryIfBody(body)
+}
+
+// matchesPattern matches ErrorPattern with the status of an HTTP status failure such as
+// "503 Service Unavailable", or with the message of any other error
+func (p Policy) matchesPattern(err error, se *statusError) bool {
+	if se != nil {
+		return p.ErrorPattern.MatchString(se.resp.Status)
+	}
+	return p.ErrorPattern.MatchString(err.Error())
+}
+
+// matchesStatus reports whether code is one of StatusCodes or in one of StatusRanges
+func (p Policy) matchesStatus(code int) bool {
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
 	}
-	for _, c := range criteria {
+	for _, r := range p.StatusRanges {
+		if r.Contains(code) {
+			return true
+		}
+	}
+	return false
+}
 
-		if c.ErrorCodeNumber == errCodeNumber &&
-			c.ErrorCodeString == errCodeString ||
-			strings.Contains(strings.ToLower(errCodeString), strings.ToLower(c.ErrorCodeString)) {
-			return c.DelayDuration, c.RetryLimit, true
+// matchesCode matches the status code and the status of a failed response, or 0 and the
+// message of an error, according to MatchMode
+func (p Policy) matchesCode(errCodeNumber int, errCodeString string) bool {
+	switch p.MatchMode {
+	case MatchExact:
+		if errCodeNumber != 0 && p.ErrorCodeNumber != 0 {
+			return errCodeNumber == p.ErrorCodeNumber
+		}
+		return errCodeString == p.ErrorCodeString
+	case MatchPrefix:
+		if errCodeNumber != 0 && p.ErrorCodeNumber != 0 {
+			return errCodeNumber == p.ErrorCodeNumber
 		}
+		return strings.HasPrefix(errCodeString, p.ErrorCodeString)
+	case MatchRegex:
+		re, _ := compileCodePattern(p.ErrorCodeString)
+		return re != nil && re.MatchString(errCodeString)
 	}
-	return time.Duration(0), 0, false
+	return p.ErrorCodeNumber == errCodeNumber &&
+		p.ErrorCodeString == errCodeString ||
+		strings.Contains(strings.ToLower(errCodeString), strings.ToLower(p.ErrorCodeString))
 }
 
 // Policy will be evaluated by Executor to determine if a certain error that's
 // returned by certain operation can be retried
 type Policy struct {
-	ErrorCodeNumber int
-	ErrorCodeString string
-	DelayDuration   time.Duration
-	RetryLimit      int
+	// Name identifies the policy in Retryer.Stats, i.e: "throttled"
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	ErrorCodeNumber int           `json:"errorCodeNumber,omitempty" yaml:"errorCodeNumber,omitempty"`
+	ErrorCodeString string        `json:"errorCodeString,omitempty" yaml:"errorCodeString,omitempty"`
+	DelayDuration   time.Duration `json:"delayDuration,omitempty" yaml:"delayDuration,omitempty"`
+
+	// MatchMode controls how ErrorCodeString is matched, default is MatchSubstring
+	MatchMode MatchMode `json:"matchMode,omitempty" yaml:"matchMode,omitempty"`
+
+	// RetryLimit is the number of retries the policy allows. Every policy counts only the failures
+	// it matched, so when the errors change from one kind to another during a retry, the policy
+	// of the new kind carries on from its own count and its backoff starts from its own
+	// DelayDuration, and the operation runs at most once plus the sum of the limits of the
+	// policies it went through, unless WithMaxTotalAttempts caps it
+	RetryLimit int `json:"retryLimit,omitempty" yaml:"retryLimit,omitempty"`
+
+	// Backoff controls how DelayDuration grows on every retry attempt, default is ConstantBackoff
+	Backoff BackoffType `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	// Multiplier is the factor by which ExponentialBackoff grows the delay on every retry,
+	// zero means 2
+	Multiplier float64 `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	// MaxDelay caps the computed delay, after the jitter, zero means no cap
+	MaxDelay time.Duration `json:"maxDelay,omitempty" yaml:"maxDelay,omitempty"`
+	// MinDelay is the floor of the computed delay, after the jitter, so the delays stay between MinDelay
+	// and MaxDelay whatever the backoff math. Zero means no floor
+	MinDelay time.Duration `json:"minDelay,omitempty" yaml:"minDelay,omitempty"`
+	// Jitter adds randomness to the computed delay, default is NoJitter
+	Jitter JitterType `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+
+	// ErrorPattern matches the policy when the error message, or the status of a non 2xx
+	// response such as "503 Service Unavailable", matches the expression. Unlike
+	// ErrorCodeString, it can be anchored and is case-sensitive unless it starts with (?i)
+	ErrorPattern *regexp.Regexp `json:"errorPattern,omitempty" yaml:"errorPattern,omitempty"`
+	// StatusCodes matches the policy when a failed response of an HTTP executor has one of the status codes
+	StatusCodes []int `json:"statusCodes,omitempty" yaml:"statusCodes,omitempty"`
+	// StatusRanges matches the policy when the status code of a failed response of an HTTP executor
+	// is in one of the ranges, i.e: []StatusRange{{500, 599}} for any 5xx
+	StatusRanges []StatusRange `json:"statusRanges,omitempty" yaml:"statusRanges,omitempty"`
+	// MatchError matches the policy when errors.Is(err, MatchError)
+	MatchError error `json:"-" yaml:"-"`
+	// MatchErrorType matches the policy when it returns true, see ErrorType for errors.As matching
+	MatchErrorType func(error) bool `json:"-" yaml:"-"`
+	// RetryIf matches the policy when it returns true for the error returned by the operation
+	RetryIf func(error) bool `json:"-" yaml:"-"`
+	// RetryIfResponse matches the policy when it returns true for a non 2xx response of an HTTP executor
+	RetryIfResponse func(*http.Response) bool `json:"-" yaml:"-"`
+	// BodyContains matches the policy when the start of the body of a response of an HTTP executor contains it,
+	// whatever its status, i.e: "ThrottlingException". The body is only peeked at with WithBodyPeek
+	BodyContains string `json:"bodyContains,omitempty" yaml:"bodyContains,omitempty"`
+	// RetryIfBody is like BodyContains but matches the policy when it returns true for the start of the body
+	RetryIfBody func(body []byte) bool `json:"-" yaml:"-"`
+}
+
+// ErrorType returns a MatchErrorType func that reports whether an error in err's chain is of type E.
+// i.e: ErrorType[*net.OpError]()
+func ErrorType[E error]() func(error) bool {
+	return func(err error) bool {
+		var target E
+		return errors.As(err, &target)
+	}
 }
 
 // PolicyType is an enum for list of retryable criteria
@@ -147,9 +906,33 @@ type Policy struct {
 type PolicyType int
 
 const (
-	// HTTPPolicy criteria
+	// HTTPPolicy criteria: 408, 429, 500, 502, 503 and 504
 	HTTPPolicy PolicyType = iota
 
 	// StandardPolicy function call
 	StandardPolicy
+
+	// StrictHTTPPolicy criteria, only the status codes that are safe to retry for
+	// non idempotent requests: 408, 429 and 503
+	StrictHTTPPolicy
+
+	// NetworkPolicy criteria: timeouts, refused and reset connections, and temporary DNS
+	// failures, see IsTemporaryNetErr. Appended to HTTPPolicy, it retries the transport
+	// errors of the HTTP executors too, see TransportError
+	NetworkPolicy
+
+	// DatabasePolicy criteria: bad connections, serialization failures and deadlocks,
+	// see IsBadConn, IsSerializationFailure, IsDeadlock and ExecutorTx
+	DatabasePolicy
+
+	// CloudThrottlePolicy criteria: the throttling errors of cloud SDKs, "ThrottlingException",
+	// "RequestLimitExceeded" and "SlowDown", and 429 and 503
+	CloudThrottlePolicy
+
+	// ReconnectPolicy criteria: any error, retried until the context is done, see NewReconnectPolicy
+	ReconnectPolicy
+
+	// IOPolicy criteria: interrupted system calls, resources temporarily unavailable or busy,
+	// stale file handles and short writes, see IsTransientIOErr
+	IOPolicy
 )
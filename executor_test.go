@@ -1,8 +1,13 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"net"
 	"net/http"
+	"regexp"
 	"testing"
 	"time"
 
@@ -127,6 +132,273 @@ func TestExecutorWithPolicyForHTTPNotRecovered(t *testing.T) {
 	assert.Equal(t, true, err != nil)
 }
 
+func TestExecutorWithContextRecovered(t *testing.T) {
+	// will throw exception 1 time and it will be retried and it could be recovered
+	// so err should be nil
+	indexTestTimedout = 1
+	err := ExecutorWithContext(context.Background(), func(ctx context.Context) error {
+		return testTimedout(1)
+	})
+	assert.Equal(t, true, err == nil)
+}
+
+func TestExecutorWithPoliciesContextCancelled(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Hour,
+			RetryLimit:      3,
+		},
+	}
+	// the delay is much longer than the context deadline, so the executor
+	// should give up as soon as the context is done instead of sleeping
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	indexTestTimedout = 1
+	start := time.Now()
+	err := ExecutorWithPoliciesContext(ctx, policies, func(ctx context.Context) error {
+		return testTimedout(5)
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, true, time.Since(start) < time.Second)
+}
+
+func TestExecutorWithPoliciesContextAlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var called bool
+	err := ExecutorWithPoliciesContext(ctx, nil, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, false, called)
+}
+
+func TestExecutorHTTPWithPoliciesContextCancelled(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeNumber: http.StatusRequestTimeout,
+			ErrorCodeString: http.StatusText(http.StatusRequestTimeout),
+			DelayDuration:   time.Hour,
+			RetryLimit:      3,
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	indexTestTimedout = 1
+	err := ExecutorHTTPWithPoliciesContext(ctx, policies, func(ctx context.Context) (*http.Response, error) {
+		return testHTTPRetryable(4)
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+var errTestSentinel = errors.New("sentinel")
+
+type testTypedError struct {
+	code int
+}
+
+func (e *testTypedError) Error() string {
+	return "typed error"
+}
+
+func TestExecutorWithPoliciesMatchError(t *testing.T) {
+	policies := []Policy{
+		{
+			MatchError:    errTestSentinel,
+			DelayDuration: time.Millisecond * 10,
+			RetryLimit:    3,
+		},
+	}
+	// the sentinel is wrapped twice, it should still be matched by errors.Is
+	var calls int
+	err := ExecutorWithPolicies(policies, func() error {
+		calls++
+		if calls <= 2 {
+			return fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", errTestSentinel))
+		}
+		return nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, calls)
+}
+
+func TestExecutorWithPoliciesMatchErrorType(t *testing.T) {
+	policies := []Policy{
+		{
+			MatchErrorType: ErrorType[*testTypedError](),
+			DelayDuration:  time.Millisecond * 10,
+			RetryLimit:     3,
+		},
+	}
+	var calls int
+	err := ExecutorWithPolicies(policies, func() error {
+		calls++
+		return fmt.Errorf("wrapped: %w", &testTypedError{code: calls})
+	})
+	var typed *testTypedError
+	assert.Equal(t, true, errors.As(err, &typed))
+	assert.Equal(t, 4, typed.code)
+}
+
+func TestExecutorWithPoliciesMatchErrorIgnoresString(t *testing.T) {
+	policies := []Policy{
+		{
+			MatchError:      errTestSentinel,
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      3,
+		},
+	}
+	// the message matches ErrorCodeString, but MatchError takes precedence
+	var calls int
+	err := ExecutorWithPolicies(policies, func() error {
+		calls++
+		return errors.New("timed out")
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+}
+
+type testTimeoutError struct{}
+
+func (e testTimeoutError) Error() string   { return "i/o deadline reached" }
+func (e testTimeoutError) Timeout() bool   { return true }
+func (e testTimeoutError) Temporary() bool { return true }
+
+func TestExecutorWithPoliciesRetryIf(t *testing.T) {
+	policies := []Policy{
+		{
+			RetryIf: func(err error) bool {
+				var netErr net.Error
+				return errors.As(err, &netErr) && netErr.Timeout()
+			},
+			DelayDuration: time.Millisecond * 10,
+			RetryLimit:    3,
+		},
+	}
+	var calls int
+	err := ExecutorWithPolicies(policies, func() error {
+		calls++
+		if calls <= 2 {
+			return testTimeoutError{}
+		}
+		return nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, calls)
+
+	// errors that aren't timeouts are not retried
+	calls = 0
+	err = ExecutorWithPolicies(policies, func() error {
+		calls++
+		return errors.New("timed out")
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+}
+
+func TestExecutorHTTPWithPoliciesRetryIfResponse(t *testing.T) {
+	policies := []Policy{
+		{
+			RetryIfResponse: func(resp *http.Response) bool {
+				return resp.StatusCode >= 500
+			},
+			DelayDuration: time.Millisecond * 10,
+			RetryLimit:    3,
+		},
+	}
+	var calls int
+	err := ExecutorHTTPWithPolicies(policies, func() (*http.Response, error) {
+		calls++
+		if calls <= 2 {
+			return &http.Response{StatusCode: http.StatusBadGateway, Status: http.StatusText(http.StatusBadGateway)}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Status: http.StatusText(http.StatusOK)}, nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = ExecutorHTTPWithPolicies(policies, func() (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusNotFound, Status: http.StatusText(http.StatusNotFound)}, nil
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+}
+
+func TestGetRetryPoliciesHTTP(t *testing.T) {
+	for _, code := range []int{408, 429, 500, 502, 503, 504} {
+		resp := &http.Response{StatusCode: code, Status: fmt.Sprintf("%d %s", code, http.StatusText(code))}
+		_, ok := matchPolicy(GetRetryPolicies(HTTPPolicy), &statusError{resp: resp})
+		assert.Equal(t, true, ok, code)
+	}
+	resp := &http.Response{StatusCode: 404, Status: "404 Not Found"}
+	_, ok := matchPolicy(GetRetryPolicies(HTTPPolicy), &statusError{resp: resp})
+	assert.Equal(t, false, ok)
+}
+
+func TestGetRetryPoliciesStrictHTTP(t *testing.T) {
+	for code, retryable := range map[int]bool{408: true, 429: true, 503: true, 500: false, 502: false, 504: false} {
+		resp := &http.Response{StatusCode: code, Status: fmt.Sprintf("%d %s", code, http.StatusText(code))}
+		_, ok := matchPolicy(GetRetryPolicies(StrictHTTPPolicy), &statusError{resp: resp})
+		assert.Equal(t, retryable, ok, code)
+	}
+	assert.Equal(t, "strict-http", StrictHTTPPolicy.String())
+}
+
+func TestGetRetryPoliciesCloudThrottle(t *testing.T) {
+	policies := GetRetryPolicies(CloudThrottlePolicy)
+	for _, msg := range []string{
+		"ThrottlingException: Rate exceeded",
+		"RequestLimitExceeded: Request limit exceeded.",
+		"SlowDown: Please reduce your request rate.",
+	} {
+		policy, ok := matchPolicy(policies, errors.New(msg))
+		assert.Equal(t, true, ok, msg)
+		assert.Equal(t, ExponentialBackoff, policy.Backoff)
+		assert.Equal(t, FullJitter, policy.Jitter)
+	}
+	for code, retryable := range map[int]bool{429: true, 503: true, 500: false} {
+		resp := &http.Response{StatusCode: code, Status: fmt.Sprintf("%d %s", code, http.StatusText(code))}
+		_, ok := matchPolicy(policies, &statusError{resp: resp})
+		assert.Equal(t, retryable, ok, code)
+	}
+	_, ok := matchPolicy(policies, errors.New("AccessDeniedException"))
+	assert.Equal(t, false, ok)
+	assert.Equal(t, "cloud", CloudThrottlePolicy.String())
+}
+
+func TestGetRetryPoliciesReconnect(t *testing.T) {
+	policies := GetRetryPolicies(ReconnectPolicy)
+	assert.Equal(t, 1, len(policies))
+	_, ok := matchPolicy(policies, errors.New("connection reset by peer"))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "reconnect", ReconnectPolicy.String())
+
+	// the reconnect loop goes on until the context is done, the delays grow up to 30s
+	ctx, cancel := context.WithCancel(context.Background())
+	var delays []time.Duration
+	var calls int
+	err := ExecutorWithPolicyTypeContext(ctx, ReconnectPolicy, func(ctx context.Context) error {
+		calls++
+		if calls == 50 {
+			cancel()
+		}
+		return errTestSentinel
+	}, WithClock(&testClock{}), WithRandSource(rand.NewSource(1)), WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		delays = append(delays, nextDelay)
+	}))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 50, calls)
+	for _, delay := range delays {
+		assert.Equal(t, true, delay >= time.Millisecond*100 && delay <= time.Second*30, delay)
+	}
+	assert.Equal(t, true, delays[len(delays)-1] > time.Second)
+}
+
 func testOne() (string, error) {
 	return "test", nil
 }
@@ -164,3 +436,124 @@ func testHTTPRetryable(n int) (*http.Response, error) {
 	}
 	return &resp, nil
 }
+
+func TestExecutorWithPoliciesErrorPattern(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorPattern:  regexp.MustCompile(`^dial tcp: .*: connection refused$`),
+			DelayDuration: time.Millisecond * 10,
+			RetryLimit:    3,
+		},
+	}
+	var calls int
+	err := ExecutorWithPolicies(policies, func() error {
+		calls++
+		return errors.New("dial tcp: 10.0.0.1:80: connection refused")
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 4, calls)
+
+	// anchored and case-sensitive, so no substring or case-insensitive match like ErrorCodeString
+	calls = 0
+	err = ExecutorWithPolicies(policies, func() error {
+		calls++
+		return errors.New("auth failed: Dial tcp: 10.0.0.1:80: connection refused")
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+}
+
+func TestExecutorHTTPWithPoliciesErrorPattern(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorPattern:  regexp.MustCompile(`^50[23] `),
+			DelayDuration: time.Millisecond * 10,
+			RetryLimit:    1,
+		},
+	}
+	var calls int
+	err := ExecutorHTTPWithPolicies(policies, func() (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway", Body: http.NoBody}, nil
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 2, calls)
+}
+
+func TestExecutorUnrecoverable(t *testing.T) {
+	var calls int
+	// the message matches StandardPolicy, but the operation knows it can't succeed
+	cause := errors.New("authentication failed: token refresh timed out")
+	err := Executor(func() error {
+		calls++
+		return Unrecoverable(cause)
+	})
+	assert.Equal(t, cause, err)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = Executor(func() error {
+		calls++
+		return fmt.Errorf("login: %w", Unrecoverable(cause))
+	})
+	assert.Equal(t, "login: authentication failed: token refresh timed out", err.Error())
+	assert.Equal(t, true, IsUnrecoverable(err))
+	assert.Equal(t, true, errors.Is(err, cause))
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, nil, Unrecoverable(nil))
+	assert.Equal(t, false, IsUnrecoverable(cause))
+}
+
+func TestExecutorHTTPUnrecoverable(t *testing.T) {
+	var calls int
+	cause := errors.New("request timed out")
+	err := ExecutorHTTPWithPolicies(nil, func() (*http.Response, error) {
+		calls++
+		return nil, Unrecoverable(cause)
+	})
+	assert.Equal(t, cause, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestExecutorWithPoliciesPerPolicyRetryLimit(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond,
+			RetryLimit:      1,
+		},
+		{
+			ErrorCodeString: "unavailable",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      2,
+			Backoff:         ExponentialBackoff,
+		},
+	}
+	errs := []string{"unavailable", "timed out", "unavailable", "unavailable"}
+	var calls int
+	var delays []time.Duration
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New(errs[(calls-1)%len(errs)])
+	}, WithPolicies(policies), WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		delays = append(delays, nextDelay)
+	}))
+	// "unavailable" is retried twice and "timed out" once, the third "unavailable" gives up
+	assert.Equal(t, "unavailable", err.Error())
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, []time.Duration{time.Millisecond * 10, time.Millisecond, time.Millisecond * 20}, delays)
+}
+
+func TestPolicyStatusRanges(t *testing.T) {
+	policies := []Policy{{StatusCodes: []int{http.StatusTooManyRequests}, StatusRanges: []StatusRange{{500, 599}}, RetryLimit: 1}}
+	for code, retryable := range map[int]bool{429: true, 500: true, 503: true, 599: true, 404: false, 600: false} {
+		resp := &http.Response{StatusCode: code, Status: fmt.Sprintf("%d %s", code, http.StatusText(code))}
+		_, ok := matchPolicy(policies, &statusError{resp: resp})
+		assert.Equal(t, retryable, ok, code)
+	}
+	// the status criteria don't match the other errors
+	_, ok := matchPolicy(policies, errors.New("500 Internal Server Error"))
+	assert.Equal(t, false, ok)
+	assert.Equal(t, "retry: invalid policy: empty StatusRange 599-500", Policy{StatusRanges: []StatusRange{{599, 500}}, RetryLimit: 1}.Validate().Error())
+}
@@ -0,0 +1,176 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Decision is how the executors handle a failed attempt, see PolicyEvaluator
+type Decision struct {
+	// Retry reports whether the operation is retried
+	Retry bool
+	// Reason tells why the operation isn't retried, empty when it is
+	Reason Reason
+	// Policy is the policy matching the error, valid when Matched is true
+	Policy Policy
+	// PolicyIndex is the index of Policy in the evaluated policies, -1 if none matches
+	PolicyIndex int
+	// Matched reports whether a policy matches the error
+	Matched bool
+	// Delay is the delay before the next attempt, including the jitter and the Retry-After header
+	Delay time.Duration
+	// RemainingRetries is the number of retries the policy allows after this one
+	RemainingRetries int
+
+	// byResult reports whether Policy is the default policy retrying a result, see WithRetryIfResult
+	byResult bool
+}
+
+// The reasons of a Decision not to retry, the ones after ReasonMaxTotalAttempts are only reported
+// by the executors, in Result.LastDecision
+const (
+	ReasonUnrecoverable        Reason = "unrecoverable error"
+	ReasonNoPolicy             Reason = "no matching policy"
+	ReasonNotIdempotent        Reason = "request is not idempotent"
+	ReasonLimitReached         Reason = "retry limit reached"
+	ReasonAdaptive             Reason = "failure rate too high"
+	ReasonAborted              Reason = "abort condition matched"
+	ReasonMaxTotalAttempts     Reason = "max total attempts reached"
+	ReasonBudgetExhausted      Reason = "retry budget exhausted"
+	ReasonMaxElapsedTime       Reason = "max elapsed time exceeded"
+	ReasonDeadlineWouldExceed  Reason = "next attempt would exceed the deadline"
+	ReasonMaxConcurrentRetries Reason = "too many concurrent retries"
+	ReasonCanceled             Reason = "context done"
+	ReasonStopped              Reason = "stopped"
+	ReasonRetryerClosed        Reason = "retryer closed"
+)
+
+// PolicyEvaluator reports how the executors configured by the same options would handle a
+// sequence of failed attempts, without running anything, i.e: to validate the policies
+// loaded from a configuration file. Timing options such as WithMaxElapsedTime and the
+// retry budget are not evaluated. It's not safe for concurrent use
+type PolicyEvaluator struct {
+	o      *options
+	states []policyState
+	// result is the state of the default policy retrying the results, see WithRetryIfResult
+	result  policyState
+	attempt int
+}
+
+// NewPolicyEvaluator returns a PolicyEvaluator of the policies configured by opts, see Do
+func NewPolicyEvaluator(opts ...Option) *PolicyEvaluator {
+	return &PolicyEvaluator{o: newOptions(opts), attempt: 1}
+}
+
+// Evaluate returns the decision for err as the error of the next attempt. A retry is counted
+// against its policy, so evaluating the same error again eventually reaches its RetryLimit
+func (e *PolicyEvaluator) Evaluate(err error) Decision {
+	d, _ := e.evaluate(err, e.attempt, e.o.clock.Now())
+	if d.Retry {
+		e.record(d)
+		e.attempt++
+	}
+	return d
+}
+
+// EvaluateResponse is Evaluate for a response of an HTTP executor, a successful response or a redirect
+// isn't retried, see StatusClass
+func (e *PolicyEvaluator) EvaluateResponse(resp *http.Response) Decision {
+	switch e.o.statusClass(resp) {
+	case StatusSuccess, StatusRedirect:
+		return Decision{PolicyIndex: -1}
+	case StatusFatal:
+		return e.Evaluate(&stopError{err: &statusError{resp: resp}})
+	}
+	return e.Evaluate(&statusError{resp: resp})
+}
+
+// Reset starts the evaluation of a new sequence of attempts
+func (e *PolicyEvaluator) Reset() {
+	e.states = nil
+	e.result = policyState{}
+	e.attempt = 1
+}
+
+// Explain returns the decision of the executors configured by opts for err as the error of the first attempt
+func Explain(err error, opts ...Option) Decision {
+	return NewPolicyEvaluator(opts...).Evaluate(err)
+}
+
+// evaluate returns the decision for err, the error of the given attempt. When the operation isn't
+// retried, the error the executors return is returned too
+func (e *PolicyEvaluator) evaluate(err error, attempt int, now time.Time) (Decision, error) {
+	d := Decision{PolicyIndex: -1}
+	if IsUnrecoverable(err) {
+		d.Reason = ReasonUnrecoverable
+		return d, unwrapStop(err)
+	}
+	if e.o.aborts(err) {
+		d.Reason = ReasonAborted
+		return d, err
+	}
+	i, ok := e.o.matchPolicy(err)
+	byResult := !ok && e.o.retryIfResult != nil && errors.Is(err, ErrRetryableResult)
+	if !ok && !byResult {
+		d.Reason = ReasonNoPolicy
+		return d, err
+	}
+	if e.states == nil {
+		e.states = make([]policyState, len(e.o.policies))
+	}
+	var policy Policy
+	var state policyState
+	if byResult {
+		// no policy matches the result, it's retried by the default policy
+		policy, state, i = e.o.policy, e.result, -1
+	} else {
+		policy, state = e.o.policies[i], e.states[i]
+	}
+	d.Policy, d.PolicyIndex, d.Matched, d.byResult = policy, i, true, byResult
+	if e.o.idempotentOnly && !idempotentAllowed(err) {
+		d.Reason = ReasonNotIdempotent
+		return d, err
+	}
+	if state.retries >= policy.RetryLimit {
+		d.Reason = ReasonLimitReached
+		return d, exhaustedError(err, attempt)
+	}
+	if e.o.maxTotalAttempts > 0 && attempt >= e.o.maxTotalAttempts {
+		d.Reason = ReasonMaxTotalAttempts
+		return d, exhaustedError(err, attempt)
+	}
+	delay := policy.nextDelayRand(state.retries+1, state.delay, e.o.int63n)
+	if e.o.adaptive != nil {
+		var ok bool
+		if delay, ok = e.o.adaptive.adapt(delay); !ok {
+			d.Reason = ReasonAdaptive
+			return d, err
+		}
+	}
+	if e.o.immediateFirstRetry && attempt == 1 {
+		delay = 0
+	}
+	d.Retry = true
+	d.RemainingRetries = policy.RetryLimit - state.retries - 1
+	d.Delay = delay
+	if delay, ok := retryAfterDelay(err, e.o.maxRetryAfter, now); ok {
+		d.Delay = delay
+	}
+	if e.o.delayFunc != nil {
+		if delay := e.o.delayFunc(attempt, unwrapStop(err)); delay >= 0 {
+			d.Delay = delay
+		}
+	}
+	return d, nil
+}
+
+// record counts the retry of d against its policy
+func (e *PolicyEvaluator) record(d Decision) {
+	state := &e.result
+	if !d.byResult {
+		state = &e.states[d.PolicyIndex]
+	}
+	state.retries++
+	state.delay = d.Delay
+}
@@ -0,0 +1,82 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyEvaluator(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Second,
+			RetryLimit:      2,
+			Backoff:         ExponentialBackoff,
+		},
+	}
+	e := NewPolicyEvaluator(WithPolicies(policies))
+	d := e.Evaluate(errors.New("timed out"))
+	assert.Equal(t, true, d.Retry)
+	assert.Equal(t, 0, d.PolicyIndex)
+	assert.Equal(t, time.Second, d.Delay)
+	assert.Equal(t, 1, d.RemainingRetries)
+
+	d = e.Evaluate(errors.New("timed out"))
+	assert.Equal(t, true, d.Retry)
+	assert.Equal(t, time.Second*2, d.Delay)
+	assert.Equal(t, 0, d.RemainingRetries)
+
+	d = e.Evaluate(errors.New("timed out"))
+	assert.Equal(t, false, d.Retry)
+	assert.Equal(t, true, d.Matched)
+	assert.Equal(t, ReasonLimitReached, d.Reason)
+
+	e.Reset()
+	assert.Equal(t, true, e.Evaluate(errors.New("timed out")).Retry)
+
+	d = e.Evaluate(errors.New("permission denied"))
+	assert.Equal(t, false, d.Matched)
+	assert.Equal(t, -1, d.PolicyIndex)
+	assert.Equal(t, ReasonNoPolicy, d.Reason)
+}
+
+func TestPolicyEvaluatorResetResult(t *testing.T) {
+	e := NewPolicyEvaluator(WithPolicies([]Policy{{ErrorCodeString: "busy", RetryLimit: 5}}),
+		WithRetryIfResult(func(n int) bool { return n == 0 }), WithAttempts(2))
+	assert.Equal(t, true, e.Evaluate(ErrRetryableResult).Retry)
+	assert.Equal(t, ReasonLimitReached, e.Evaluate(ErrRetryableResult).Reason)
+
+	// the retries of the results are counted from scratch too
+	e.Reset()
+	d := e.Evaluate(ErrRetryableResult)
+	assert.Equal(t, true, d.Retry)
+	assert.Equal(t, Reason(""), d.Reason)
+}
+
+func TestPolicyEvaluatorResponse(t *testing.T) {
+	e := NewPolicyEvaluator(WithPolicyType(HTTPPolicy))
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests", Header: http.Header{"Retry-After": {"7"}}}
+	d := e.EvaluateResponse(resp)
+	assert.Equal(t, true, d.Retry)
+	assert.Equal(t, http.StatusTooManyRequests, d.Policy.ErrorCodeNumber)
+	assert.Equal(t, time.Second*7, d.Delay)
+
+	d = e.EvaluateResponse(&http.Response{StatusCode: http.StatusOK})
+	assert.Equal(t, false, d.Retry)
+	assert.Equal(t, Reason(""), d.Reason)
+}
+
+func TestExplain(t *testing.T) {
+	d := Explain(Unrecoverable(errors.New("timed out")))
+	assert.Equal(t, false, d.Retry)
+	assert.Equal(t, ReasonUnrecoverable, d.Reason)
+
+	d = Explain(errors.New("anything"), WithDelay(time.Millisecond*10))
+	assert.Equal(t, true, d.Retry)
+	assert.Equal(t, time.Millisecond*10, d.Delay)
+	assert.Equal(t, DefaultAttempts-2, d.RemainingRetries)
+}
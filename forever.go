@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultHealthyPeriod is how long an operation run by RunForever must run before its backoff is reset
+const DefaultHealthyPeriod = time.Minute
+
+// WithHealthyPeriod sets how long an operation run by RunForever must run before the failure that
+// ends it is considered a new one, and retried after DelayDuration instead of a longer delay
+func WithHealthyPeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.healthyPeriod = d
+	}
+}
+
+// RunForever runs fn, and runs it again whenever it returns, for long-running operations such
+// as a consumer, a websocket client or a watch loop. An error matching the policies in opts
+// restarts fn after the backoff delay of the matched policy, see Do, whose RetryLimit is ignored.
+// A nil error restarts it after the delay of the default policy, see WithDelay. The delays grow while fn keeps failing and start over once it ran for
+// the healthy period, see WithHealthyPeriod. It returns the error of fn when it matches no
+// policy or is Unrecoverable, and ctx.Err() once ctx is done
+func RunForever(ctx context.Context, fn FuncContext, opts ...Option) error {
+	o := newOptions(opts)
+	backoffs := make([]*Backoff, len(o.policies))
+	// fn returning nil is restarted with the delays of the default policy
+	ended := &Backoff{policy: o.policy, int63n: o.int63n}
+	var attempt int
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := o.clock.Now()
+		attempt++
+		err := fn(ctx)
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		if IsUnrecoverable(err) {
+			return unwrapStop(err)
+		}
+		backoff := ended
+		if err != nil {
+			i, ok := o.matchPolicy(err)
+			if !ok {
+				return err
+			}
+			if backoffs[i] == nil {
+				backoffs[i] = &Backoff{policy: o.policies[i], int63n: o.int63n}
+			}
+			backoff = backoffs[i]
+		}
+		if o.clock.Now().Sub(start) >= o.healthyPeriod {
+			ended.Reset()
+			for _, b := range backoffs {
+				if b != nil {
+					b.Reset()
+				}
+			}
+		}
+		delay := backoff.Next()
+		if o.onRetry != nil {
+			o.onRetry(attempt, err, delay)
+		}
+		if err := o.wait(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
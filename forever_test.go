@@ -0,0 +1,90 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunForever(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var delays []time.Duration
+	var calls int
+	err := RunForever(ctx, func(ctx context.Context) error {
+		calls++
+		switch calls {
+		case 3:
+			// a healthy run resets the backoff
+			clock.now = clock.now.Add(time.Minute)
+		case 5:
+			return nil
+		case 6:
+			cancel()
+		}
+		return errTestSentinel
+	},
+		WithDelay(time.Second),
+		WithBackoff(ExponentialBackoff),
+		WithClock(clock),
+		WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+			delays = append(delays, nextDelay)
+		}),
+	)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 6, calls)
+	assert.Equal(t, []time.Duration{time.Second, time.Second * 2, time.Second, time.Second * 2, time.Second}, delays)
+}
+
+func TestRunForeverRandSource(t *testing.T) {
+	run := func() []time.Duration {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var delays []time.Duration
+		_ = RunForever(ctx, func(ctx context.Context) error {
+			if len(delays) == 4 {
+				cancel()
+			}
+			return errTestSentinel
+		},
+			WithDelay(time.Second),
+			WithBackoff(ExponentialBackoff),
+			WithJitter(FullJitter),
+			WithRandSource(rand.NewSource(1)),
+			WithClock(&testClock{now: time.Now()}),
+			WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+				delays = append(delays, nextDelay)
+			}),
+		)
+		return delays
+	}
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff, Jitter: FullJitter}
+	assert.Equal(t, p.JitteredSchedule(4, rand.NewSource(1)), run())
+}
+
+func TestRunForeverUnrecoverable(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	var calls int
+	err := RunForever(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 3 {
+			return Unrecoverable(errTestSentinel)
+		}
+		return errors.New("disconnected")
+	}, WithClock(clock))
+	assert.Equal(t, errTestSentinel, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRunForeverNotRetryable(t *testing.T) {
+	policies := []Policy{{ErrorCodeString: "disconnected", DelayDuration: time.Second}}
+	err := RunForever(context.Background(), func(ctx context.Context) error {
+		return errTestSentinel
+	}, WithPolicies(policies), WithClock(&testClock{}), WithHealthyPeriod(time.Second))
+	assert.Equal(t, errTestSentinel, err)
+}
@@ -0,0 +1,64 @@
+package retry
+
+import (
+	"context"
+)
+
+// FuncT is a function returning a value of type T and an error that will be executed and evaluated by ExecutorT
+type FuncT[T any] func() (T, error)
+
+// FuncTContext is a context-aware FuncT
+type FuncTContext[T any] func(ctx context.Context) (T, error)
+
+// ExecutorT executes a closure, inspect the error, and do retry if necessary.
+// The value returned by the last attempt is returned
+func ExecutorT[T any](fn FuncT[T]) (T, error) {
+	return executeOperation(context.Background(), compiledDefaults().options(), operation[T]{plainT: fn}, nil)
+}
+
+// ExecutorTWithPolicyType is the generic version of ExecutorWithPolicyType
+func ExecutorTWithPolicyType[T any](policyType PolicyType, fn FuncT[T]) (T, error) {
+	return executeOperation(context.Background(), compiledPolicyType(policyType).options(), operation[T]{plainT: fn}, nil)
+}
+
+// ExecutorTWithPolicies is the generic version of ExecutorWithPolicies
+func ExecutorTWithPolicies[T any](retryPolicies []Policy, fn FuncT[T]) (T, error) {
+	return executeOperation(context.Background(), newOptions(withPolicies(retryPolicies, nil)), operation[T]{plainT: fn}, nil)
+}
+
+// ExecutorTWithContext is the generic version of ExecutorWithContext
+func ExecutorTWithContext[T any](ctx context.Context, fn FuncTContext[T], opts ...Option) (T, error) {
+	return execute(ctx, compiledDefaults().optionsWith(opts), fn)
+}
+
+// ExecutorTWithPolicyTypeContext is the generic version of ExecutorWithPolicyTypeContext
+func ExecutorTWithPolicyTypeContext[T any](ctx context.Context, policyType PolicyType, fn FuncTContext[T], opts ...Option) (T, error) {
+	return execute(ctx, compiledPolicyType(policyType).optionsWith(opts), fn)
+}
+
+// ExecutorTWithPoliciesContext is the generic version of ExecutorWithPoliciesContext
+func ExecutorTWithPoliciesContext[T any](ctx context.Context, retryPolicies []Policy, fn FuncTContext[T], opts ...Option) (T, error) {
+	return execute(ctx, newOptions(withPolicies(retryPolicies, opts)), fn)
+}
+
+// WithRetryIfResult makes the generic executors of T treat an attempt succeeding with a result for which fn
+// returns true as failed with ErrRetryableResult, i.e: a call whose payload reports a pending operation.
+// It's retried by the first policy matching ErrRetryableResult if any, and by the default policy otherwise,
+// whatever the other policies, see WithAttempts and WithDelay. When the retries are exhausted, the last
+// result is returned with the error. An executor of another type than T fails with ErrInvalidOption
+// before running anything, rather than ignoring fn
+func WithRetryIfResult[T any](fn func(result T) bool) Option {
+	return func(o *options) {
+		o.retryIfResult = fn
+	}
+}
+
+// retryIfResultOf returns the predicate set by WithRetryIfResult for the results of type T, nil if there's
+// none, and false if it's set for another type
+func retryIfResultOf[T any](o *options) (func(T) bool, bool) {
+	if o.retryIfResult == nil {
+		return nil, true
+	}
+	fn, ok := o.retryIfResult.(func(T) bool)
+	return fn, ok
+}
@@ -0,0 +1,141 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorT(t *testing.T) {
+	v, err := ExecutorT(testOne)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, "test", v)
+}
+
+func TestExecutorTWithPoliciesRecovered(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      3,
+		},
+	}
+	// will throw exception 2 times and it will be retried and it could be recovered
+	// so the value of the last attempt should be returned
+	indexTestTimedout = 1
+	var calls int
+	v, err := ExecutorTWithPolicies(policies, func() (int, error) {
+		calls++
+		return calls, testTimedout(2)
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, v)
+}
+
+func TestExecutorTWithPoliciesNotRecovered(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      3,
+		},
+	}
+	indexTestTimedout = 1
+	_, err := ExecutorTWithPolicies(policies, func() (string, error) {
+		return "", testTimedout(5)
+	})
+	assert.Equal(t, true, err != nil)
+}
+
+func TestExecutorTWithPoliciesContextCancelled(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Hour,
+			RetryLimit:      3,
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	indexTestTimedout = 1
+	v, err := ExecutorTWithPoliciesContext(ctx, policies, func(ctx context.Context) (string, error) {
+		return "partial", testTimedout(5)
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, "", v)
+}
+
+func TestWithRetryIfResult(t *testing.T) {
+	type job struct{ Status string }
+	pending := WithRetryIfResult(func(j job) bool { return j.Status == "PENDING" })
+	policies := []Policy{{MatchError: ErrRetryableResult, DelayDuration: time.Millisecond, RetryLimit: 2}}
+
+	var calls int
+	v, err := ExecutorTWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (job, error) {
+		calls++
+		if calls < 3 {
+			return job{Status: "PENDING"}, nil
+		}
+		return job{Status: "DONE"}, nil
+	}, pending)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "DONE", v.Status)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	v, err = ExecutorTWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (job, error) {
+		calls++
+		return job{Status: "PENDING"}, nil
+	}, pending)
+	assert.Equal(t, true, errors.Is(err, ErrRetryableResult))
+	assert.Equal(t, "PENDING", v.Status)
+	assert.Equal(t, 3, calls)
+
+	// the predicate of another type fails the executor rather than being ignored
+	calls = 0
+	_, err = ExecutorTWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (string, error) {
+		calls++
+		return "PENDING", nil
+	}, pending)
+	assert.Equal(t, true, errors.Is(err, ErrInvalidOption))
+	assert.Equal(t, 0, calls)
+}
+
+func TestWithRetryIfResultDefaultPolicies(t *testing.T) {
+	type job struct{ Status string }
+	var calls int
+	fn := func(ctx context.Context) (job, error) {
+		calls++
+		if calls < 3 {
+			return job{Status: "PENDING"}, nil
+		}
+		return job{Status: "DONE"}, nil
+	}
+	// no policy matches ErrRetryableResult, the default policy retries the results
+	v, err := ExecutorTWithContext(context.Background(), fn, WithRetryIfResult(func(j job) bool { return j.Status == "PENDING" }), WithDelay(time.Millisecond))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "DONE", v.Status)
+	assert.Equal(t, 3, calls)
+
+	// within the limit of the default policy
+	calls = 0
+	v, err = ExecutorTWithContext(context.Background(), func(ctx context.Context) (job, error) {
+		calls++
+		return job{Status: "PENDING"}, nil
+	}, WithRetryIfResult(func(j job) bool { return j.Status == "PENDING" }), WithDelay(time.Millisecond), WithAttempts(2))
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: ErrRetryableResult}, err)
+	assert.Equal(t, "PENDING", v.Status)
+	assert.Equal(t, 2, calls)
+
+	// the errors are still evaluated against the policies only
+	calls = 0
+	_, err = ExecutorTWithContext(context.Background(), func(ctx context.Context) (job, error) {
+		calls++
+		return job{}, errTestSentinel
+	}, WithRetryIfResult(func(j job) bool { return false }), WithDelay(time.Millisecond))
+	assert.Equal(t, errTestSentinel, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs operations in goroutines like errgroup.Group, and retries each of them as
+// configured by the options it's created with, see Do. When an operation fails for good,
+// the context of the group is cancelled so the others stop
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	opts   *options
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	err     error
+	results []Result
+}
+
+// NewGroup returns a Group and the context passed to its operations, derived from ctx
+func NewGroup(ctx context.Context, opts ...Option) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{ctx: ctx, cancel: cancel, opts: newOptions(opts)}, ctx
+}
+
+// Go runs fn in a new goroutine, and retries it until it succeeds or fails for good.
+// The first operation failing for good cancels the context of the group, with its error as the
+// context.Cause, and its error is returned by Wait
+func (g *Group) Go(fn FuncContext) {
+	g.mu.Lock()
+	i := len(g.results)
+	g.results = append(g.results, Result{})
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		var res Result
+		_, err := executeResult(g.ctx, g.opts, func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, fn(ctx)
+		}, &res)
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.results[i] = res
+		if err != nil && g.err == nil {
+			g.err = err
+			g.cancel(err)
+		}
+	}()
+}
+
+// Wait waits for every operation to return, and returns the first error of an operation that
+// failed for good, along with how the retries of every operation went, in the order of Go
+func (g *Group) Wait() ([]Result, error) {
+	g.wg.Wait()
+	g.cancel(nil)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Result(nil), g.results...), g.err
+}
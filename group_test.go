@@ -0,0 +1,47 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	g, _ := NewGroup(context.Background(), WithDelay(time.Millisecond))
+	var calls int
+	g.Go(func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTestSentinel
+		}
+		return nil
+	})
+	g.Go(func(ctx context.Context) error {
+		return nil
+	})
+	results, err := g.Wait()
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, 3, results[0].Attempts)
+	assert.Equal(t, 1, results[1].Attempts)
+}
+
+func TestGroupCancel(t *testing.T) {
+	g, ctx := NewGroup(context.Background(), WithDelay(time.Millisecond))
+	g.Go(func(ctx context.Context) error {
+		return Unrecoverable(errTestSentinel)
+	})
+	g.Go(func(ctx context.Context) error {
+		// waits for the other operation to fail
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	results, err := g.Wait()
+	assert.Equal(t, errTestSentinel, err)
+	assert.Equal(t, true, errors.Is(ctx.Err(), context.Canceled))
+	assert.Equal(t, errTestSentinel, context.Cause(ctx))
+	assert.Equal(t, 1, results[0].Attempts)
+}
@@ -0,0 +1,108 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Hedge runs fn, and instead of waiting for it to fail, starts another concurrent attempt every
+// delay until one succeeds, up to attempts attempts in total. A failed attempt starts the next one
+// right away. The result of the first successful attempt is returned and the context of the other
+// attempts is cancelled with the ErrHedgeWon cause. If every attempt fails, the error of the last one to finish is returned.
+// Hedging is meant for read-only operations, since several attempts may be processed
+func Hedge[T any](ctx context.Context, delay time.Duration, attempts int, fn FuncTContext[T]) (T, error) {
+	return hedge(ctx, delay, attempts, fn, nil)
+}
+
+// HedgeHTTP is the HTTP version of Hedge, a response that's neither successful nor a redirect by
+// default is a failed attempt, see StatusClass.
+// The bodies of the failed responses, and of the responses that lost the race, are drained and closed
+func HedgeHTTP(ctx context.Context, delay time.Duration, attempts int, fn FuncHTTPContext) (*http.Response, error) {
+	return hedge(ctx, delay, attempts, func(ctx context.Context) (*http.Response, error) {
+		resp, err := fn(ctx)
+		if err != nil {
+			if resp != nil {
+				drainBody(resp, DefaultDrainLimit)
+			}
+			return nil, err
+		}
+		if sharedOptions(nil).failed(resp) {
+			drainBody(resp, DefaultDrainLimit)
+			return nil, &statusError{resp: resp}
+		}
+		return resp, nil
+	}, func(resp *http.Response) {
+		drainBody(resp, DefaultDrainLimit)
+	})
+}
+
+// hedge implements Hedge, discard is called with the successful results that lost the race
+func hedge[T any](ctx context.Context, delay time.Duration, attempts int, fn FuncTContext[T], discard func(T)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	hedgeCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	type outcome struct {
+		result T
+		err    error
+	}
+	results := make(chan outcome, attempts)
+	var launched, finished int
+	launch := func() {
+		launched++
+		go func() {
+			result, err := fn(hedgeCtx)
+			results <- outcome{result: result, err: err}
+		}()
+	}
+	// drain waits for the attempts still running, so their results can be discarded
+	drain := func() {
+		pending := launched - finished
+		if discard == nil || pending == 0 {
+			return
+		}
+		go func() {
+			for i := 0; i < pending; i++ {
+				if out := <-results; out.err == nil {
+					discard(out.result)
+				}
+			}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case out := <-results:
+			finished++
+			if out.err == nil {
+				cancel(ErrHedgeWon)
+				drain()
+				return out.result, nil
+			}
+			if launched < attempts {
+				launch()
+				timer.Reset(delay)
+			} else if finished == launched {
+				return zero, out.err
+			}
+		case <-timer.C:
+			if launched < attempts {
+				launch()
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			drain()
+			return zero, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedgeFirstSlow(t *testing.T) {
+	// the first attempt hangs, the hedged one answers quickly
+	var calls int32
+	var cancelled int32
+	var cause atomic.Value
+	start := time.Now()
+	v, err := Hedge(context.Background(), time.Millisecond*20, 3, func(ctx context.Context) (int, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			<-ctx.Done()
+			cause.Store(context.Cause(ctx))
+			atomic.AddInt32(&cancelled, 1)
+			return 0, ctx.Err()
+		}
+		return int(n), nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, true, time.Since(start) < time.Second)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&cancelled) == 1 }, time.Second, time.Millisecond*5)
+	assert.Equal(t, ErrHedgeWon, cause.Load())
+}
+
+func TestHedgeFailureStartsNextAttempt(t *testing.T) {
+	// a failed attempt doesn't wait for the hedge delay
+	var calls int32
+	start := time.Now()
+	v, err := Hedge(context.Background(), time.Hour, 3, func(ctx context.Context) (int, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n < 3 {
+			return 0, errors.New("down")
+		}
+		return int(n), nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, v)
+	assert.Equal(t, true, time.Since(start) < time.Second)
+}
+
+func TestHedgeAllFailed(t *testing.T) {
+	var calls int32
+	_, err := Hedge(context.Background(), time.Millisecond, 3, func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, errors.New("down")
+	})
+	assert.Equal(t, "down", err.Error())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestHedgeContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err := Hedge(ctx, time.Millisecond*10, 2, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestHedgeHTTP(t *testing.T) {
+	var calls int32
+	slow := &testBody{Reader: strings.NewReader("slow")}
+	release := make(chan struct{})
+	resp, err := HedgeHTTP(context.Background(), time.Millisecond*20, 2, func(ctx context.Context) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			return &http.Response{StatusCode: http.StatusOK, Body: slow}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: &testBody{Reader: strings.NewReader("fast")}}, nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, false, resp.Body.(*testBody).closed.Load())
+	// the response that lost the race is closed once it arrives
+	close(release)
+	assert.Eventually(t, func() bool { return slow.closed.Load() }, time.Second, time.Millisecond*5)
+}
+
+func TestHedgeHTTPRedirect(t *testing.T) {
+	var calls int32
+	resp, err := HedgeHTTP(context.Background(), time.Millisecond*20, 2, func(ctx context.Context) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{StatusCode: http.StatusNotModified, Body: http.NoBody}, nil
+	})
+	// a redirect is returned as is, it's not hedged
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,324 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultDrainLimit is how many bytes of a failed response body are read before closing it,
+// so the underlying keep-alive connection can be reused, see WithDrainLimit
+const DefaultDrainLimit = 4 << 10
+
+// WithDrainLimit sets how many bytes of the body of a failed response the HTTP executors and
+// Transport read before closing it. A connection is only reused once its response body is read
+// to the end, so a larger limit reuses more connections at the cost of reading larger error
+// bodies. Zero or less closes the bodies without reading them
+func WithDrainLimit(n int64) Option {
+	return func(o *options) {
+		o.drainLimit = n
+	}
+}
+
+// WithBodyPeek makes the HTTP executors and Transport read up to n bytes of the body of every response,
+// and match them against the BodyContains and RetryIfBody of the policies. A response whose body matches
+// is a failure retried according to the policies whatever its status, i.e: a 200 or a 400 carrying a
+// "ThrottlingException". The bytes read are put back in front of the body, so the caller reads it unchanged.
+// Zero or less, the default, doesn't peek
+func WithBodyPeek(n int64) Option {
+	return func(o *options) {
+		o.bodyPeek = n
+	}
+}
+
+// IsSuccessStatus reports whether resp has a status below 300, it's the default of WithHTTPSuccess
+func IsSuccessStatus(resp *http.Response) bool {
+	return resp.StatusCode < 300
+}
+
+// WithHTTPSuccess sets which responses the HTTP executors and Transport consider successful,
+// IsSuccessStatus by default. Any other response is a failure matched against the policies,
+// i.e: a 207 Multi-Status carrying failed parts, or a 200 whose body holds a business error.
+// A predicate reading the body must restore it for the caller and the policies
+func WithHTTPSuccess(fn func(resp *http.Response) bool) Option {
+	return func(o *options) {
+		o.httpSuccess = fn
+	}
+}
+
+// StatusClass is how the HTTP executors and Transport handle a response, depending on its status
+type StatusClass int
+
+const (
+	// StatusRetryable is a failure retried according to the policies, the default of the
+	// responses WithHTTPSuccess doesn't consider successful, except the redirects
+	StatusRetryable StatusClass = iota
+	// StatusSuccess is a successful response returned to the caller
+	StatusSuccess
+	// StatusRedirect is a redirect the client didn't follow, i.e: a 304 Not Modified, or a 302
+	// of a client whose CheckRedirect returns http.ErrUseLastResponse. It is neither retried
+	// nor reported as an error, the response is returned to the caller. It's the default of the 3xx statuses
+	StatusRedirect
+	// StatusFatal is a failure that's not retried, the error is returned right away
+	StatusFatal
+)
+
+// WithStatusClass sets the class of the responses with the given status codes, overriding
+// WithHTTPSuccess and the redirect default, i.e: WithStatusClass(StatusFatal, 400, 401, 403)
+func WithStatusClass(class StatusClass, codes ...int) Option {
+	return func(o *options) {
+		if o.statusClasses == nil {
+			o.statusClasses = make(map[int]StatusClass)
+		}
+		for _, code := range codes {
+			o.statusClasses[code] = class
+		}
+	}
+}
+
+// statusClass returns the class of resp, see StatusClass
+func (o *options) statusClass(resp *http.Response) StatusClass {
+	if class, ok := o.statusClasses[resp.StatusCode]; ok {
+		return class
+	}
+	if o.httpSuccess(resp) {
+		return StatusSuccess
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return StatusRedirect
+	}
+	return StatusRetryable
+}
+
+// failed reports whether resp is a failure, retryable or fatal, rather than a success or a redirect
+func (o *options) failed(resp *http.Response) bool {
+	switch o.statusClass(resp) {
+	case StatusSuccess, StatusRedirect:
+		return false
+	}
+	return true
+}
+
+// ExecutorHTTPResponse executes a closure, inspect the http response, and do retry if necessary.
+// Unlike ExecutorHTTP, the successful response is returned and the caller must close its body.
+// The bodies of the failed responses are drained and closed, and nil is returned with the error
+func ExecutorHTTPResponse(fn FuncHTTP) (*http.Response, error) {
+	return ExecutorHTTPResponseWithContext(context.Background(), func(context.Context) (*http.Response, error) {
+		return fn()
+	})
+}
+
+// ExecutorHTTPResponseWithPolicyType is the ExecutorHTTPResponse version of ExecutorHTTPWithPolicyType
+func ExecutorHTTPResponseWithPolicyType(policyType PolicyType, fn FuncHTTP) (*http.Response, error) {
+	return ExecutorHTTPResponseWithPolicyTypeContext(context.Background(), policyType, func(context.Context) (*http.Response, error) {
+		return fn()
+	})
+}
+
+// ExecutorHTTPResponseWithPolicies is the ExecutorHTTPResponse version of ExecutorHTTPWithPolicies
+func ExecutorHTTPResponseWithPolicies(retryPolicies []Policy, fn FuncHTTP) (*http.Response, error) {
+	return ExecutorHTTPResponseWithPoliciesContext(context.Background(), retryPolicies, func(context.Context) (*http.Response, error) {
+		return fn()
+	})
+}
+
+// ExecutorHTTPResponseWithContext is the ExecutorHTTPResponse version of ExecutorHTTPWithContext
+func ExecutorHTTPResponseWithContext(ctx context.Context, fn FuncHTTPContext, opts ...Option) (*http.Response, error) {
+	return executeHTTP(ctx, true, compiledDefaults().optionsWith(opts), fn)
+}
+
+// ExecutorHTTPResponseWithPolicyTypeContext is the ExecutorHTTPResponse version of ExecutorHTTPWithPolicyTypeContext
+func ExecutorHTTPResponseWithPolicyTypeContext(ctx context.Context, policyType PolicyType, fn FuncHTTPContext, opts ...Option) (*http.Response, error) {
+	return executeHTTP(ctx, true, compiledPolicyType(policyType).optionsWith(opts), fn)
+}
+
+// ExecutorHTTPResponseWithPoliciesContext is the ExecutorHTTPResponse version of ExecutorHTTPWithPoliciesContext
+func ExecutorHTTPResponseWithPoliciesContext(ctx context.Context, retryPolicies []Policy, fn FuncHTTPContext, opts ...Option) (*http.Response, error) {
+	return executeHTTP(ctx, true, newOptions(withPolicies(retryPolicies, opts)), fn)
+}
+
+// executeHTTP retries fn until it returns a successful response, which is returned.
+// On failure the body of the last response is drained and closed.
+// If keep, the response is read by the caller so the contexts it's made with are released when
+// its body is closed, rather than when executeHTTP returns
+func executeHTTP(ctx context.Context, keep bool, o *options, fn FuncHTTPContext) (*http.Response, error) {
+	call := &httpCall{fn: fn, drainLimit: o.drainLimit, classify: o.statusClass}
+	call.peekBodies(o)
+	op := operation[*http.Response]{fn: call.attempt}
+	if keep {
+		op.bind = bindBody
+	}
+	resp, err := executeOperation(ctx, o, op, nil)
+	if err != nil {
+		call.close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// httpCall adapts fn to the retry loop, a failed response is reported as a *statusError,
+// wrapped in a *stopError when it's fatal, and a transport error as a *TransportError.
+// An attempt abandoned by WithAttemptTimeout keeps running in its goroutine, so the state
+// shared by the attempts is guarded by mu, and such an attempt drains its own response
+type httpCall struct {
+	fn FuncHTTPContext
+	// drainLimit is how many bytes of the failed responses are drained, see WithDrainLimit
+	drainLimit int64
+	// classify returns the class of a response, see StatusClass
+	classify func(resp *http.Response) StatusClass
+	// peek is how many bytes of the bodies are matched with bodyPolicies, see WithBodyPeek
+	peek         int64
+	bodyPolicies []Policy
+
+	mu sync.Mutex
+	// last is the failed response of the previous attempt, prev is the one before it, whose
+	// body is closed
+	last, prev *http.Response
+}
+
+// peekBodies makes c peek at the bodies of the responses if o asks for it and a policy matches on them
+func (c *httpCall) peekBodies(o *options) {
+	if o.bodyPeek <= 0 {
+		return
+	}
+	for _, p := range o.policies {
+		if p.matchesOnBody() {
+			c.bodyPolicies = append(c.bodyPolicies, p)
+		}
+	}
+	if len(c.bodyPolicies) > 0 {
+		c.peek = o.bodyPeek
+	}
+}
+
+func (c *httpCall) attempt(ctx context.Context) (*http.Response, error) {
+	c.mu.Lock()
+	if c.last != nil {
+		drainBody(c.last, c.drainLimit)
+	}
+	c.prev, c.last = c.last, nil
+	c.mu.Unlock()
+	resp, err := c.fn(ctx)
+	if err != nil {
+		if resp != nil {
+			drainBody(resp, c.drainLimit)
+		}
+		if IsUnrecoverable(err) {
+			return nil, err
+		}
+		return nil, &TransportError{Err: err}
+	}
+	class := c.classify(resp)
+	var body []byte
+	if c.peek > 0 {
+		body = peekBody(resp, c.peek)
+		for _, p := range c.bodyPolicies {
+			if p.matchesBody(body) {
+				class = StatusRetryable
+				break
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ctx.Err() != nil {
+		// the attempt is over, it may be abandoned so nothing else would close the response
+		drainBody(resp, c.drainLimit)
+		return nil, context.Cause(ctx)
+	}
+	switch class {
+	case StatusRetryable:
+		c.last = resp
+		return resp, &statusError{resp: resp, body: body}
+	case StatusFatal:
+		c.last = resp
+		return resp, &stopError{err: &statusError{resp: resp, body: body}}
+	}
+	return resp, nil
+}
+
+// previous returns the failed response of the previous attempt, whose body is closed, nil if
+// it's the first attempt or the previous one didn't get a response
+func (c *httpCall) previous() *http.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prev
+}
+
+// isLast reports whether resp is the failed response of the last attempt
+func (c *httpCall) isLast(resp *http.Response) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return resp == c.last
+}
+
+// bindBody ties release to the body of resp, which calls it once it's closed. It reports false
+// if resp has no body to tie it to
+func bindBody(resp *http.Response, release func()) bool {
+	if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return false
+	}
+	b := &boundBody{ReadCloser: resp.Body, release: release}
+	if w, ok := resp.Body.(io.Writer); ok {
+		// the body of a 101 Switching Protocols response is the upgraded connection
+		resp.Body = &boundConn{boundBody: b, Writer: w}
+	} else {
+		resp.Body = b
+	}
+	return true
+}
+
+// boundBody is a response body that releases the contexts it's read with once it's closed
+type boundBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *boundBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// boundConn is the boundBody of an upgraded connection
+type boundConn struct {
+	*boundBody
+	io.Writer
+}
+
+// peekBody reads up to n bytes of the body of resp, and puts them back in front of the body
+func peekBody(resp *http.Response, n int64) []byte {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	buf, _ := io.ReadAll(io.LimitReader(resp.Body, n))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), resp.Body), resp.Body}
+	return buf
+}
+
+// close drains and closes the failed response of the last attempt, if any
+func (c *httpCall) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil {
+		drainBody(c.last, c.drainLimit)
+		c.last = nil
+	}
+}
+
+// drainBody reads up to limit bytes of the response body and closes it
+func drainBody(resp *http.Response, limit int64) {
+	if resp.Body == nil {
+		return
+	}
+	if limit > 0 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, limit))
+	}
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,330 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *testBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestExecutorHTTPResponseWithPolicies(t *testing.T) {
+	var bodies []*testBody
+	resp, err := ExecutorHTTPResponseWithPolicies(testTransportPolicies, func() (*http.Response, error) {
+		body := &testBody{Reader: strings.NewReader("body")}
+		bodies = append(bodies, body)
+		if len(bodies) <= 2 {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: http.StatusText(http.StatusServiceUnavailable), Body: body}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Status: http.StatusText(http.StatusOK), Body: body}, nil
+	})
+	assert.Equal(t, true, err == nil)
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "body", string(b))
+	// the failed responses are closed, not the returned one
+	assert.Equal(t, 3, len(bodies))
+	assert.Equal(t, true, bodies[0].closed.Load())
+	assert.Equal(t, true, bodies[1].closed.Load())
+	assert.Equal(t, false, bodies[2].closed.Load())
+}
+
+func TestExecutorHTTPAbandonedAttempt(t *testing.T) {
+	release := make(chan struct{})
+	late := &testBody{Reader: strings.NewReader("late")}
+	ok := &testBody{Reader: strings.NewReader("ok")}
+	var calls atomic.Int32
+	policies := []Policy{{MatchError: ErrAttemptTimeout, DelayDuration: time.Millisecond, RetryLimit: 1}}
+	resp, err := ExecutorHTTPResponseWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			// the attempt times out, and its response comes once the retry succeeded
+			<-release
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: http.StatusText(http.StatusServiceUnavailable), Body: late}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Status: http.StatusText(http.StatusOK), Body: ok}, nil
+	}, WithAttemptTimeout(time.Millisecond*20))
+	assert.Equal(t, true, err == nil)
+	close(release)
+	// the abandoned attempt closes its own response, the returned one is left open
+	assert.Eventually(t, func() bool { return late.closed.Load() }, time.Second, time.Millisecond*5)
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(b))
+	assert.Equal(t, false, ok.closed.Load())
+}
+
+func TestExecutorHTTPResponseWithPoliciesNotRecovered(t *testing.T) {
+	var bodies []*testBody
+	resp, err := ExecutorHTTPResponseWithPoliciesContext(context.Background(), testTransportPolicies, func(ctx context.Context) (*http.Response, error) {
+		body := &testBody{Reader: strings.NewReader("body")}
+		bodies = append(bodies, body)
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: http.StatusText(http.StatusServiceUnavailable), Body: body}, nil
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, true, resp == nil)
+	assert.Equal(t, 4, len(bodies))
+	for _, body := range bodies {
+		assert.Equal(t, true, body.closed.Load())
+	}
+}
+
+func TestRetryerRunHTTPResponse(t *testing.T) {
+	r := NewRetryer(WithPolicies(testTransportPolicies))
+	var calls int
+	resp, err := r.RunHTTPResponse(context.Background(), func(ctx context.Context) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: http.StatusText(http.StatusServiceUnavailable)}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Status: http.StatusText(http.StatusOK)}, nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestExecutorHTTPWithPoliciesExhaustedError(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeNumber: http.StatusServiceUnavailable,
+			ErrorCodeString: http.StatusText(http.StatusServiceUnavailable),
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      2,
+		},
+	}
+	err := ExecutorHTTPWithPolicies(policies, func() (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Body: http.NoBody}, nil
+	})
+	var exhausted *ExhaustedError
+	assert.Equal(t, true, errors.As(err, &exhausted))
+	assert.Equal(t, http.StatusServiceUnavailable, exhausted.StatusCode)
+	assert.Equal(t, 3, exhausted.Attempts)
+	assert.Equal(t, "ERROR: httpStatusCode: 503, httpStatus: 503 Service Unavailable", err.Error())
+
+	var httpErr HTTPError
+	assert.Equal(t, true, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode())
+
+	// an error that's not retried at all isn't an ExhaustedError, but its status code is still there
+	err = ExecutorHTTPWithPolicies(policies, func() (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: http.NoBody}, nil
+	})
+	assert.Equal(t, false, errors.As(err, &exhausted))
+	assert.Equal(t, true, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode())
+	assert.Equal(t, "404 Not Found", httpErr.Response().Status)
+	code, ok := StatusCodeOf(err)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, true, ok)
+	_, ok = StatusCodeOf(errTestSentinel)
+	assert.Equal(t, false, ok)
+}
+
+func TestWithDrainLimit(t *testing.T) {
+	for limit, remaining := range map[int64]int{DefaultDrainLimit: 8<<10 - DefaultDrainLimit, 8 << 10: 0, 0: 8 << 10} {
+		var bodies []*strings.Reader
+		_, err := ExecutorHTTPResponseWithPoliciesContext(context.Background(), testTransportPolicies, func(ctx context.Context) (*http.Response, error) {
+			reader := strings.NewReader(strings.Repeat("x", 8<<10))
+			bodies = append(bodies, reader)
+			if len(bodies) == 1 {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: http.StatusText(http.StatusServiceUnavailable), Body: &testBody{Reader: reader}}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}, WithDrainLimit(limit))
+		assert.Equal(t, true, err == nil)
+		assert.Equal(t, remaining, bodies[0].Len(), limit)
+	}
+}
+
+func TestWithHTTPSuccess(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeNumber: http.StatusMultiStatus,
+			DelayDuration:   time.Millisecond,
+			RetryLimit:      3,
+		},
+	}
+	// a 207 is retried until every part succeeds
+	var calls int
+	resp, err := ExecutorHTTPResponseWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		calls++
+		if calls <= 2 {
+			return &http.Response{StatusCode: http.StatusMultiStatus, Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}, WithHTTPSuccess(func(resp *http.Response) bool {
+		return resp.StatusCode != http.StatusMultiStatus && IsSuccessStatus(resp)
+	}))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, calls)
+
+	evaluator := NewPolicyEvaluator(WithPolicies(policies), WithHTTPSuccess(func(resp *http.Response) bool { return false }))
+	assert.Equal(t, true, evaluator.EvaluateResponse(&http.Response{StatusCode: http.StatusMultiStatus}).Retry)
+}
+
+func TestStatusClassRedirect(t *testing.T) {
+	// the redirects aren't retried nor reported as errors, even by a catch-all policy
+	var calls int
+	resp, err := ExecutorHTTPResponseWithContext(context.Background(), func(ctx context.Context) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusNotModified, Body: http.NoBody}, nil
+	}, WithPolicies([]Policy{{DelayDuration: time.Millisecond, RetryLimit: 3}}))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithStatusClass(t *testing.T) {
+	policies := []Policy{{DelayDuration: time.Millisecond, RetryLimit: 3}}
+	var calls int
+	err := ExecutorHTTPWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return &http.Response{StatusCode: http.StatusFound, Status: "302 Found", Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusForbidden, Status: "403 Forbidden", Body: http.NoBody}, nil
+	}, WithStatusClass(StatusRetryable, http.StatusFound), WithStatusClass(StatusFatal, http.StatusForbidden))
+	// the 302 is retried, the 403 stops the retry
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "ERROR: httpStatusCode: 403, httpStatus: 403 Forbidden", err.Error())
+	var exhausted *ExhaustedError
+	assert.Equal(t, false, errors.As(err, &exhausted))
+	code, _ := StatusCodeOf(err)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	evaluator := NewPolicyEvaluator(WithPolicies(policies), WithStatusClass(StatusFatal, http.StatusForbidden))
+	assert.Equal(t, ReasonUnrecoverable, evaluator.EvaluateResponse(&http.Response{StatusCode: http.StatusForbidden}).Reason)
+	assert.Equal(t, false, evaluator.EvaluateResponse(&http.Response{StatusCode: http.StatusMovedPermanently}).Retry)
+}
+
+func TestWithBodyPeek(t *testing.T) {
+	policies := []Policy{
+		{StatusCodes: []int{http.StatusServiceUnavailable}, DelayDuration: time.Millisecond, RetryLimit: 3},
+		{BodyContains: "ThrottlingException", DelayDuration: time.Millisecond, RetryLimit: 3},
+	}
+	bodies := []string{
+		`{"code": "ThrottlingException"}`,
+		`{"code": "ThrottlingException", "message": "slow down"}`,
+		`{"code": "OK"}`,
+	}
+	statuses := []int{http.StatusOK, http.StatusBadRequest, http.StatusOK}
+	var calls int
+	resp, err := ExecutorHTTPResponseWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: statuses[calls-1], Body: io.NopCloser(strings.NewReader(bodies[calls-1]))}, nil
+	}, WithBodyPeek(32), WithStatusClass(StatusFatal, http.StatusBadRequest))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, calls)
+	// the peeked bytes are put back
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, bodies[2], string(b))
+
+	// without WithBodyPeek the body isn't read
+	calls = 0
+	resp, err = ExecutorHTTPResponseWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(bodies[0]))}, nil
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, calls)
+	b, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, bodies[0], string(b))
+
+	// RetryIfBody is only given the peeked bytes
+	p := Policy{RetryIfBody: func(body []byte) bool { return len(body) == 4 }}
+	assert.Equal(t, true, p.matches(&statusError{resp: &http.Response{StatusCode: http.StatusOK}, body: []byte("body")}))
+	assert.Equal(t, false, p.matches(&statusError{resp: &http.Response{StatusCode: http.StatusOK}}))
+}
+
+func TestExecutorHTTPTransportError(t *testing.T) {
+	policies := append(GetRetryPolicies(HTTPPolicy), GetRetryPolicies(NetworkPolicy)...)
+	for i := range policies {
+		policies[i].DelayDuration = time.Millisecond
+	}
+	var calls int
+	resp, err := ExecutorHTTPResponseWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+
+	// a transport error matching no policy isn't retried
+	errBroken := errors.New("broken")
+	calls = 0
+	_, err = ExecutorHTTPResponseWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		calls++
+		return nil, errBroken
+	})
+	assert.Equal(t, true, errors.Is(err, errBroken))
+	assert.Equal(t, true, IsTransportError(err))
+	assert.Equal(t, 1, calls)
+
+	// the transport errors can be matched as such
+	calls = 0
+	policies = []Policy{{MatchErrorType: IsTransportError, DelayDuration: time.Millisecond, RetryLimit: 2}}
+	_, err = ExecutorHTTPResponseWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		calls++
+		if calls == 2 {
+			return nil, Unrecoverable(errBroken)
+		}
+		return nil, errBroken
+	})
+	assert.Equal(t, errBroken, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestExecutorHTTPResponseStreamedBody(t *testing.T) {
+	release := make(chan struct{})
+	server := testStreamServer(t, release)
+	resp, err := ExecutorHTTPResponseWithContext(context.Background(), func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		return http.DefaultClient.Do(req)
+	}, WithAttemptTimeout(time.Second))
+	assert.Equal(t, true, err == nil)
+	close(release)
+	b, err := io.ReadAll(resp.Body)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, "head-tail", string(b))
+	_ = resp.Body.Close()
+}
+
+func TestExecutorHTTPResponseMaxElapsedTimeStreamedBody(t *testing.T) {
+	release := make(chan struct{})
+	server := testStreamServer(t, release)
+	resp, err := ExecutorHTTPResponseWithContext(context.Background(), func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		return http.DefaultClient.Do(req)
+	}, WithMaxElapsedTime(time.Second), WithAttemptTimeout(time.Second))
+	assert.Equal(t, true, err == nil)
+	close(release)
+	b, err := io.ReadAll(resp.Body)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, "head-tail", string(b))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type retryableKey struct{}
+
+// MarkRetryable returns a copy of ctx marking the requests made with it as safe to retry,
+// even if their method isn't idempotent. See WithIdempotentOnly
+func MarkRetryable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryableKey{}, true)
+}
+
+// IsRetryableRequest reports whether req can be sent more than once without duplicating side effects:
+// its method is idempotent (GET, HEAD, PUT, DELETE, OPTIONS, TRACE), it carries an Idempotency-Key
+// or X-Idempotency-Key header, or its context is marked with MarkRetryable
+func IsRetryableRequest(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	if req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != "" {
+		return true
+	}
+	marked, _ := req.Context().Value(retryableKey{}).(bool)
+	return marked
+}
+
+// WithIdempotentOnly makes the HTTP executors and Transport retry a request only when IsRetryableRequest
+// reports it's safe. The HTTP executors find the request in the Request field of the response, which
+// http.Client sets; a response without it is not retried
+func WithIdempotentOnly() Option {
+	return func(o *options) {
+		o.idempotentOnly = true
+	}
+}
+
+// idempotentAllowed reports whether err may be retried under WithIdempotentOnly
+func idempotentAllowed(err error) bool {
+	var se *statusError
+	if !errors.As(err, &se) {
+		return true
+	}
+	return se.resp.Request != nil && IsRetryableRequest(se.resp.Request)
+}
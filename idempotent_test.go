@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryableRequest(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	assert.Equal(t, true, IsRetryableRequest(get))
+
+	post, _ := http.NewRequest(http.MethodPost, "http://localhost", nil)
+	assert.Equal(t, false, IsRetryableRequest(post))
+
+	post.Header.Set("Idempotency-Key", "abc")
+	assert.Equal(t, true, IsRetryableRequest(post))
+
+	patch, _ := http.NewRequestWithContext(MarkRetryable(context.Background()), http.MethodPatch, "http://localhost", nil)
+	assert.Equal(t, true, IsRetryableRequest(patch))
+}
+
+func TestExecutorHTTPWithIdempotentOnly(t *testing.T) {
+	post, _ := http.NewRequest(http.MethodPost, "http://localhost", nil)
+	get, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	for _, tc := range []struct {
+		req   *http.Request
+		calls int
+	}{
+		{req: post, calls: 1},
+		{req: get, calls: 4},
+		{req: nil, calls: 1},
+	} {
+		var calls int
+		err := ExecutorHTTPWithPoliciesContext(context.Background(), testTransportPolicies, func(ctx context.Context) (*http.Response, error) {
+			calls++
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: http.StatusText(http.StatusServiceUnavailable), Request: tc.req}, nil
+		}, WithIdempotentOnly())
+		assert.Equal(t, true, err != nil)
+		assert.Equal(t, tc.calls, calls)
+	}
+}
+
+func TestTransportWithIdempotentOnly(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Policies: testTransportPolicies, Options: []Option{WithIdempotentOnly()}}}
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+	req.Header.Set("Idempotency-Key", "abc")
+	resp, err = client.Do(req)
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}
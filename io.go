@@ -0,0 +1,70 @@
+package retry
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ioPolicies are the policies of IOPolicy
+func ioPolicies() []Policy {
+	return []Policy{
+		{
+			MatchErrorType: IsTransientIOErr,
+			DelayDuration:  time.Millisecond * 50,
+			RetryLimit:     5,
+			Backoff:        ExponentialBackoff,
+			MaxDelay:       time.Second * 2,
+			Jitter:         FullJitter,
+		},
+	}
+}
+
+// IsTransientIOErr reports whether err is a file or I/O failure that's likely to go away on its own:
+// an interrupted system call, a resource temporarily unavailable or busy, a stale handle of a network
+// file system, or a short write. It inspects the syscall errnos in err's chain, such as the ones
+// wrapped by *fs.PathError and *os.SyscallError, so it doesn't depend on the error messages
+func IsTransientIOErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	return IsInterrupted(err) || IsTryAgain(err) || IsBusy(err) || IsStaleHandle(err) || IsShortWrite(err)
+}
+
+// IsInterrupted reports whether err is a system call interrupted by a signal before it completed
+func IsInterrupted(err error) bool {
+	return isAny(err, interruptedErrnos)
+}
+
+// IsTryAgain reports whether err is a resource temporarily unavailable, i.e: a locked file or
+// a non-blocking descriptor that isn't ready
+func IsTryAgain(err error) bool {
+	return isAny(err, tryAgainErrnos)
+}
+
+// IsBusy reports whether err is a device or resource busy, i.e: a file held by another process
+func IsBusy(err error) bool {
+	return isAny(err, busyErrnos)
+}
+
+// IsStaleHandle reports whether err is a stale file handle of a network file system such as NFS,
+// the file is usually found again once it's reopened
+func IsStaleHandle(err error) bool {
+	return isAny(err, staleErrnos)
+}
+
+// IsShortWrite reports whether err is a write that accepted fewer bytes than requested, see io.ErrShortWrite.
+// The bytes that were written must be accounted for before retrying
+func IsShortWrite(err error) bool {
+	return errors.Is(err, io.ErrShortWrite)
+}
+
+// isAny reports whether err's chain matches one of targets
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
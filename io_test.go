@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIOErrorHelpers(t *testing.T) {
+	pathErr := func(errno syscall.Errno) error {
+		return &fs.PathError{Op: "open", Path: "/mnt/nfs/data", Err: errno}
+	}
+	assert.Equal(t, true, IsInterrupted(pathErr(syscall.EINTR)))
+	assert.Equal(t, true, IsTryAgain(pathErr(syscall.EAGAIN)))
+	assert.Equal(t, true, IsBusy(pathErr(syscall.EBUSY)))
+	assert.Equal(t, true, IsStaleHandle(pathErr(syscall.ESTALE)))
+	assert.Equal(t, true, IsShortWrite(fmt.Errorf("copy: %w", io.ErrShortWrite)))
+	assert.Equal(t, false, IsBusy(pathErr(syscall.EINTR)))
+
+	assert.Equal(t, true, IsTransientIOErr(pathErr(syscall.ESTALE)))
+	assert.Equal(t, false, IsTransientIOErr(pathErr(syscall.ENOENT)))
+	assert.Equal(t, false, IsTransientIOErr(fs.ErrPermission))
+	// the message would match a string criteria, but it's not the errno
+	assert.Equal(t, false, IsTransientIOErr(errors.New("resource temporarily unavailable")))
+	assert.Equal(t, false, IsTransientIOErr(nil))
+}
+
+func TestIOPolicy(t *testing.T) {
+	assert.Equal(t, "io", IOPolicy.String())
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &fs.PathError{Op: "write", Path: "out", Err: syscall.EINTR}
+		}
+		return nil
+	}, WithPolicyType(IOPolicy), WithClock(&testClock{now: time.Now()}))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return &fs.PathError{Op: "open", Path: "missing", Err: syscall.ENOENT}
+	}, WithPolicyType(IOPolicy), WithClock(&testClock{now: time.Now()}))
+	assert.Equal(t, true, errors.Is(err, fs.ErrNotExist))
+	assert.Equal(t, 1, calls)
+}
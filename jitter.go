@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// JitterType is an enum for how randomness is added to the computed delay, so
+// that many clients retrying the same failed dependency don't retry in lockstep
+type JitterType int
+
+const (
+	// NoJitter uses the computed backoff delay as is
+	NoJitter JitterType = iota
+
+	// FullJitter waits a random duration between zero and the computed delay
+	FullJitter
+
+	// EqualJitter keeps half of the computed delay and randomizes the other half
+	EqualJitter
+
+	// DecorrelatedJitter waits a random duration between DelayDuration and three times
+	// the previous delay, the computed backoff delay is not used
+	DecorrelatedJitter
+)
+
+// nextDelay returns the delay before the given retry attempt, applying the policy's
+// Backoff and Jitter. prev is the delay used before the previous attempt, zero if none
+func (p Policy) nextDelay(attempt int, prev time.Duration) time.Duration {
+	return p.nextDelayRand(attempt, prev, rand.Int63n)
+}
+
+// nextDelayRand is nextDelay drawing the jitter from int63n, see rand.Int63n
+func (p Policy) nextDelayRand(attempt int, prev time.Duration, int63n func(int64) int64) time.Duration {
+	var delay time.Duration
+	switch p.Jitter {
+	case FullJitter:
+		delay = randomDuration(p.backoffDelay(attempt), int63n)
+	case EqualJitter:
+		half := p.backoffDelay(attempt) / 2
+		delay = half + randomDuration(half, int63n)
+	case DecorrelatedJitter:
+		if prev < p.DelayDuration {
+			prev = p.DelayDuration
+		}
+		delay = p.DelayDuration + randomDuration(scaleDuration(prev, 3)-p.DelayDuration, int63n)
+	default:
+		return p.backoffDelay(attempt)
+	}
+	return p.clamp(delay)
+}
+
+// randomDuration returns a random duration in [0, d] drawn from int63n
+func randomDuration(d time.Duration, int63n func(int64) int64) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	if d == maxDuration {
+		return time.Duration(int63n(int64(d)))
+	}
+	return time.Duration(int63n(int64(d) + 1))
+}
+
+// WithRandSource draws the jitter of the delays from src instead of the global source of
+// math/rand, i.e: rand.NewSource(1) for reproducible delays in tests. The global source is
+// safe for concurrent use without locking, while src is guarded by a mutex shared by every
+// operation using the options, such as the ones of a Retryer, a RunForever or a Queue
+func WithRandSource(src rand.Source) Option {
+	r := rand.New(src)
+	var mu sync.Mutex
+	return func(o *options) {
+		o.int63n = func(n int64) int64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return r.Int63n(n)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDelayNoJitter(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff}
+	assert.Equal(t, time.Second*4, p.nextDelay(3, 0))
+}
+
+func TestNextDelayFullJitter(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff, Jitter: FullJitter}
+	for i := 0; i < 100; i++ {
+		d := p.nextDelay(3, 0)
+		assert.Equal(t, true, d >= 0 && d <= time.Second*4)
+	}
+}
+
+func TestNextDelayEqualJitter(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Backoff: ExponentialBackoff, Jitter: EqualJitter}
+	for i := 0; i < 100; i++ {
+		d := p.nextDelay(3, 0)
+		assert.Equal(t, true, d >= time.Second*2 && d <= time.Second*4)
+	}
+}
+
+func TestNextDelayDecorrelatedJitter(t *testing.T) {
+	p := Policy{DelayDuration: time.Second, Jitter: DecorrelatedJitter, MaxDelay: time.Second * 10}
+	var prev time.Duration
+	for i := 1; i <= 100; i++ {
+		d := p.nextDelay(i, prev)
+		upper := prev * 3
+		if upper < time.Second*3 {
+			upper = time.Second * 3
+		}
+		assert.Equal(t, true, d >= time.Second && d <= upper)
+		assert.Equal(t, true, d <= time.Second*10)
+		prev = d
+	}
+}
+
+func TestWithRandSource(t *testing.T) {
+	policies := []Policy{{ErrorCodeString: "timed out", DelayDuration: time.Second, RetryLimit: 5, Backoff: ExponentialBackoff, Jitter: FullJitter}}
+	delays := func() []time.Duration {
+		var delays []time.Duration
+		_ = Do(context.Background(), func(ctx context.Context) error {
+			return errors.New("timed out")
+		}, WithPolicies(policies), WithClock(&testClock{}), WithRandSource(rand.NewSource(42)),
+			WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+				delays = append(delays, nextDelay)
+			}))
+		return delays
+	}
+	first := delays()
+	assert.Equal(t, 5, len(first))
+	assert.Equal(t, first, delays())
+}
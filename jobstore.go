@@ -0,0 +1,170 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a JobStore keeping the jobs in memory, they don't survive the process.
+// It's safe for concurrent use
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: map[string]Job{}}
+}
+
+// Save implements JobStore
+func (s *MemoryStore) Save(ctx context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+// Due implements JobStore
+func (s *MemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return dueJobs(s.jobs, now, limit), nil
+}
+
+// Delete implements JobStore
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+// Jobs returns the jobs of the store, the earliest first
+func (s *MemoryStore) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return dueJobs(s.jobs, time.Time{}, 0)
+}
+
+// FileStore is a JobStore keeping the jobs in a JSON file, which is rewritten on every change,
+// so it suits the small queues of a single process. It's safe for concurrent use
+type FileStore struct {
+	path string
+
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+// NewFileStore returns a FileStore of the file at path, loading its jobs if it exists
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, jobs: map[string]Job{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		s.jobs[job.ID] = job
+	}
+	return s, nil
+}
+
+// Save implements JobStore
+func (s *FileStore) Save(ctx context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.jobs[job.ID]
+	s.jobs[job.ID] = job
+	if err := s.write(); err != nil {
+		if ok {
+			s.jobs[job.ID] = prev
+		} else {
+			delete(s.jobs, job.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Due implements JobStore
+func (s *FileStore) Due(ctx context.Context, now time.Time, limit int) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return dueJobs(s.jobs, now, limit), nil
+}
+
+// Delete implements JobStore
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil
+	}
+	delete(s.jobs, id)
+	if err := s.write(); err != nil {
+		s.jobs[id] = job
+		return err
+	}
+	return nil
+}
+
+// write replaces the file with the jobs, through a temporary file so a crash doesn't corrupt it
+func (s *FileStore) write() error {
+	data, err := json.Marshal(dueJobs(s.jobs, time.Time{}, 0))
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// dueJobs returns up to limit jobs whose NextAttempt isn't after now, the earliest first.
+// A zero now returns every job and a zero limit doesn't limit them
+func dueJobs(jobs map[string]Job, now time.Time, limit int) []Job {
+	due := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		if now.IsZero() || !job.NextAttempt.After(now) {
+			due = append(due, job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].NextAttempt.Equal(due[j].NextAttempt) {
+			return due[i].ID < due[j].ID
+		}
+		return due[i].NextAttempt.Before(due[j].NextAttempt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
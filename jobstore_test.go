@@ -0,0 +1,35 @@
+package retry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	store, err := NewFileStore(path)
+	assert.Equal(t, true, err == nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	assert.Equal(t, true, store.Save(ctx, Job{ID: "b", Payload: []byte("b"), NextAttempt: now.Add(time.Second)}) == nil)
+	assert.Equal(t, true, store.Save(ctx, Job{ID: "a", Payload: []byte("a"), NextAttempt: now}) == nil)
+	assert.Equal(t, true, store.Save(ctx, Job{ID: "c", NextAttempt: now.Add(time.Hour)}) == nil)
+	assert.Equal(t, true, store.Delete(ctx, "c") == nil)
+
+	// the jobs survive a restart
+	store, err = NewFileStore(path)
+	assert.Equal(t, true, err == nil)
+	due, err := store.Due(ctx, now.Add(time.Second), 10)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 2, len(due))
+	assert.Equal(t, "a", due[0].ID)
+	assert.Equal(t, []byte("b"), due[1].Payload)
+	due, _ = store.Due(ctx, now, 10)
+	assert.Equal(t, 1, len(due))
+	due, _ = store.Due(ctx, now.Add(time.Second), 1)
+	assert.Equal(t, 1, len(due))
+}
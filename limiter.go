@@ -0,0 +1,22 @@
+package retry
+
+import (
+	"context"
+)
+
+// Limiter paces the retries of every operation sharing it. *rate.Limiter
+// from golang.org/x/time/rate implements it
+type Limiter interface {
+	// Wait blocks until a retry is allowed, or returns an error if ctx is done
+	// or the wait would exceed its deadline
+	Wait(ctx context.Context) error
+}
+
+// WithRateLimiter makes every retry wait for l after its delay, so the retries
+// of concurrent operations are paced globally rather than each goroutine only
+// sleeping on its own. First attempts are not limited
+func WithRateLimiter(l Limiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
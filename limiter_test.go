@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLimiter struct {
+	waits int32
+	err   error
+}
+
+func (l *testLimiter) Wait(ctx context.Context) error {
+	atomic.AddInt32(&l.waits, 1)
+	return l.err
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	limiter := &testLimiter{}
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errTestSentinel
+	}, WithAttempts(3), WithDelay(time.Millisecond), WithRateLimiter(limiter))
+	assert.Equal(t, &ExhaustedError{Attempts: 3, LastErr: errTestSentinel}, err)
+	assert.Equal(t, 3, calls)
+	// only the retries wait for the limiter
+	assert.Equal(t, int32(2), atomic.LoadInt32(&limiter.waits))
+}
+
+func TestWithRateLimiterError(t *testing.T) {
+	limiter := &testLimiter{err: errors.New("rate: Wait would exceed context deadline")}
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errTestSentinel
+	}, WithAttempts(3), WithDelay(time.Millisecond), WithRateLimiter(limiter))
+	// the error of the last attempt is returned
+	assert.Equal(t, errTestSentinel, err)
+	assert.Equal(t, 1, calls)
+}
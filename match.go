@@ -0,0 +1,51 @@
+package retry
+
+import (
+	"regexp"
+	"sync"
+)
+
+// MatchMode is an enum for how the ErrorCodeString of a policy is matched against the message
+// of an error, or the status of a failed response such as "503 Service Unavailable"
+type MatchMode int
+
+const (
+	// MatchSubstring matches when the message contains ErrorCodeString, ignoring the case.
+	// A response also matches when both its status code and status equal the policy's
+	MatchSubstring MatchMode = iota
+
+	// MatchExact matches when the message equals ErrorCodeString, case-sensitive.
+	// A response matches on its status code alone when ErrorCodeNumber is set,
+	// i.e: a "deadline" policy doesn't match "context deadline exceeded"
+	MatchExact
+
+	// MatchPrefix matches when the message starts with ErrorCodeString, case-sensitive.
+	// A response matches on its status code alone when ErrorCodeNumber is set
+	MatchPrefix
+
+	// MatchRegex matches when the message matches ErrorCodeString as a regular expression,
+	// see Policy.ErrorPattern for a precompiled one. An invalid expression never matches,
+	// Policy.Validate reports it
+	MatchRegex
+)
+
+// codePatterns caches the expressions of the MatchRegex policies, an ErrorCodeString
+// is compiled once for every policy using it
+var codePatterns sync.Map
+
+// codePattern is a compiled ErrorCodeString
+type codePattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// compileCodePattern returns expr compiled
+func compileCodePattern(expr string) (*regexp.Regexp, error) {
+	if cached, ok := codePatterns.Load(expr); ok {
+		p := cached.(codePattern)
+		return p.re, p.err
+	}
+	re, err := regexp.Compile(expr)
+	codePatterns.Store(expr, codePattern{re: re, err: err})
+	return re, err
+}
@@ -0,0 +1,44 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchMode(t *testing.T) {
+	deadline := errors.New("context deadline exceeded")
+	for mode, matches := range map[MatchMode]map[string]bool{
+		MatchSubstring: {"deadline": true, "Context Deadline": true, "context deadline exceeded": true},
+		MatchExact:     {"deadline": false, "Context Deadline": false, "context deadline exceeded": true},
+		MatchPrefix:    {"deadline": false, "context deadline": true, "Context Deadline": false},
+		MatchRegex:     {"deadline": true, "^deadline": false, "^context .* exceeded$": true, "(": false},
+	} {
+		for s, ok := range matches {
+			p := Policy{ErrorCodeString: s, MatchMode: mode}
+			assert.Equal(t, ok, p.matches(deadline), mode.String()+" "+s)
+		}
+	}
+
+	// the status code is enough for a response
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}
+	p := Policy{ErrorCodeNumber: http.StatusServiceUnavailable, ErrorCodeString: "Service Unavailable", MatchMode: MatchExact}
+	assert.Equal(t, true, p.matches(&statusError{resp: resp}))
+	p = Policy{ErrorCodeString: `^50[34] `, MatchMode: MatchRegex}
+	assert.Equal(t, true, p.matches(&statusError{resp: resp}))
+}
+
+func TestMatchModeConfig(t *testing.T) {
+	policies, err := LoadPolicies(strings.NewReader(`[{"errorCodeString": "deadline", "matchMode": "exact", "retryLimit": 1}]`))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, MatchExact, policies[0].MatchMode)
+	_, err = LoadPolicies(strings.NewReader(`[{"matchMode": "fuzzy"}]`))
+	assert.Equal(t, true, err != nil)
+
+	err = Policy{ErrorCodeString: "(", MatchMode: MatchRegex, RetryLimit: 1}.Validate()
+	assert.Equal(t, true, errors.Is(err, ErrInvalidPolicy))
+	assert.Equal(t, true, strings.Contains(err.Error(), "invalid ErrorCodeString: error parsing regexp"))
+}
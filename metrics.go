@@ -0,0 +1,28 @@
+package retry
+
+import (
+	"time"
+)
+
+// MetricsCollector receives the outcome of every retry sequence, so retry rates and
+// exhaustion can be monitored. Its methods are called from the goroutine running the
+// operation and must be safe for concurrent use. See the retryprom package for a
+// Prometheus implementation
+type MetricsCollector interface {
+	// RecordAttempt is called after every attempt, attempt starts at 1 and err is nil on success
+	RecordAttempt(attempt int, err error)
+	// RecordSuccess is called when the operation succeeds after the given number of attempts
+	RecordSuccess(attempts int)
+	// RecordExhausted is called when the executor gives up after the given number of attempts,
+	// because the retries are exhausted or the error can't be retried. err is the returned error
+	RecordExhausted(attempts int, err error)
+	// ObserveDelay is called with the delay before every retry
+	ObserveDelay(delay time.Duration)
+}
+
+// WithMetrics reports the attempts, outcome and delays of every retry sequence to c
+func WithMetrics(c MetricsCollector) Option {
+	return func(o *options) {
+		o.metrics = c
+	}
+}
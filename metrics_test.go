@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMetrics struct {
+	sync.Mutex
+	attempts  []int
+	failures  int
+	successes []int
+	exhausted []int
+	delays    []time.Duration
+}
+
+func (m *testMetrics) RecordAttempt(attempt int, err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.attempts = append(m.attempts, attempt)
+	if err != nil {
+		m.failures++
+	}
+}
+
+func (m *testMetrics) RecordSuccess(attempts int) {
+	m.Lock()
+	defer m.Unlock()
+	m.successes = append(m.successes, attempts)
+}
+
+func (m *testMetrics) RecordExhausted(attempts int, err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.exhausted = append(m.exhausted, attempts)
+}
+
+func (m *testMetrics) ObserveDelay(delay time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.delays = append(m.delays, delay)
+}
+
+func TestWithMetrics(t *testing.T) {
+	metrics := &testMetrics{}
+	r := NewRetryer(WithAttempts(3), WithDelay(time.Millisecond*10), WithMetrics(metrics))
+
+	var calls int
+	err := r.Run(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 2 {
+			return errors.New("something else")
+		}
+		return nil
+	})
+	assert.Equal(t, true, err == nil)
+
+	err = r.Run(context.Background(), func(ctx context.Context) error {
+		return errors.New("something else")
+	})
+	assert.Equal(t, true, err != nil)
+
+	assert.Equal(t, []int{1, 2, 1, 2, 3}, metrics.attempts)
+	assert.Equal(t, 4, metrics.failures)
+	assert.Equal(t, []int{2}, metrics.successes)
+	assert.Equal(t, []int{3}, metrics.exhausted)
+	assert.Equal(t, []time.Duration{time.Millisecond * 10, time.Millisecond * 10, time.Millisecond * 10}, metrics.delays)
+}
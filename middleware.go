@@ -0,0 +1,42 @@
+package retry
+
+import (
+	"context"
+)
+
+// AttemptFunc is a single attempt of an operation, as seen by the AttemptMiddleware
+type AttemptFunc func(ctx context.Context) error
+
+// AttemptMiddleware wraps every attempt of the operations of an executor, so the cross-cutting
+// concerns such as logging, metrics or refreshing a token before a retry are layered onto it
+// without changing the call sites, like an http.Handler middleware. It calls next to make the
+// attempt, and may skip it by returning an error. ctx carries the AttemptInfo of the attempt,
+// see AttemptInfoFromContext. The error returned is evaluated against the policies
+type AttemptMiddleware func(next AttemptFunc) AttemptFunc
+
+// WithAttemptMiddleware wraps every attempt with mws, the first one being the outermost.
+// It adds to the previous middlewares. The middlewares run within the WithAttemptTimeout and
+// their panics are recovered by WithRecoverPanics, as if they were the operation
+func WithAttemptMiddleware(mws ...AttemptMiddleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, mws...)
+	}
+}
+
+// withMiddlewares returns fn wrapped by mws, see WithAttemptMiddleware. The result of fn is the
+// one of the attempt, the zero value if a middleware skips it
+func withMiddlewares[T any](mws []AttemptMiddleware, fn func(context.Context) (T, error)) func(context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		var result T
+		next := AttemptFunc(func(ctx context.Context) error {
+			var err error
+			result, err = fn(ctx)
+			return err
+		})
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		err := next(ctx)
+		return result, err
+	}
+}
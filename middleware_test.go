@@ -0,0 +1,50 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAttemptMiddleware(t *testing.T) {
+	var log []string
+	logging := func(name string) AttemptMiddleware {
+		return func(next AttemptFunc) AttemptFunc {
+			return func(ctx context.Context) error {
+				log = append(log, fmt.Sprintf("%s %d", name, AttemptFromContext(ctx)))
+				return next(ctx)
+			}
+		}
+	}
+	var calls int
+	policies := []Policy{{RetryIf: func(error) bool { return true }, RetryLimit: 2, DelayDuration: time.Millisecond}}
+	v, err := ExecutorTWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (int, error) {
+		calls++
+		if calls < 2 {
+			return 0, errTestSentinel
+		}
+		return calls, nil
+	}, WithAttemptMiddleware(logging("outer"), logging("inner")))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, []string{"outer 1", "inner 1", "outer 2", "inner 2"}, log)
+}
+
+func TestWithAttemptMiddlewareSkip(t *testing.T) {
+	errNoToken := errors.New("no token")
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return nil
+	}, WithAttempts(3), WithDelay(time.Millisecond), WithAttemptMiddleware(func(next AttemptFunc) AttemptFunc {
+		return func(ctx context.Context) error {
+			return errNoToken
+		}
+	}))
+	assert.Equal(t, &ExhaustedError{Attempts: 3, LastErr: errNoToken}, err)
+	assert.Equal(t, 0, calls)
+}
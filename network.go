@@ -0,0 +1,66 @@
+package retry
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// networkPolicies are the policies of NetworkPolicy
+func networkPolicies() []Policy {
+	return []Policy{
+		{
+			MatchErrorType: IsTemporaryNetErr,
+			DelayDuration:  time.Millisecond * 200,
+			RetryLimit:     4,
+			Backoff:        ExponentialBackoff,
+			MaxDelay:       time.Second * 5,
+			Jitter:         FullJitter,
+		},
+	}
+}
+
+// IsTemporaryNetErr reports whether err is a network failure that's likely to go away on its own:
+// a timeout, a refused, reset or aborted connection, or a DNS error that isn't a missing host.
+// It inspects net.Error, *net.DNSError and the syscall errnos in err's chain, so it doesn't depend
+// on the platform or the locale of the error messages
+func IsTemporaryNetErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return IsConnRefused(err) || IsConnReset(err) || isAny(err, connAbortedErrnos)
+}
+
+// IsConnRefused reports whether err is a connection refused by the peer, i.e: nothing listens on the port yet
+func IsConnRefused(err error) bool {
+	return isAny(err, connRefusedErrnos)
+}
+
+// IsConnReset reports whether err is a connection reset by the peer, or written after the peer closed it
+func IsConnReset(err error) bool {
+	return isAny(err, connResetErrnos)
+}
+
+// IsDNSError reports whether err is a failure to resolve a host name, see IsTemporaryNetErr
+// to tell the temporary ones from a missing host
+func IsDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// IsTLSHandshakeTimeout reports whether err is the timeout of a TLS handshake, such as the one
+// reported by http.Transport when its TLSHandshakeTimeout expires. That error type isn't exported,
+// so it's recognized as a net.Error timeout mentioning the handshake
+func IsTLSHandshakeTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() && strings.Contains(netErr.Error(), "TLS handshake")
+}
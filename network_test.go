@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOpError(errno syscall.Errno) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+}
+
+type testTLSTimeoutError struct{}
+
+func (testTLSTimeoutError) Error() string   { return "net/http: TLS handshake timeout" }
+func (testTLSTimeoutError) Timeout() bool   { return true }
+func (testTLSTimeoutError) Temporary() bool { return true }
+
+func TestNetworkErrorHelpers(t *testing.T) {
+	refused := testOpError(syscall.ECONNREFUSED)
+	assert.Equal(t, true, IsConnRefused(refused))
+	assert.Equal(t, true, IsConnRefused(fmt.Errorf("get: %w", refused)))
+	assert.Equal(t, false, IsConnReset(refused))
+	assert.Equal(t, true, IsTemporaryNetErr(refused))
+
+	reset := testOpError(syscall.ECONNRESET)
+	assert.Equal(t, true, IsConnReset(reset))
+	assert.Equal(t, true, IsTemporaryNetErr(reset))
+
+	notFound := &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}
+	assert.Equal(t, true, IsDNSError(notFound))
+	assert.Equal(t, false, IsTemporaryNetErr(notFound))
+	assert.Equal(t, true, IsTemporaryNetErr(&net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}))
+
+	assert.Equal(t, true, IsTLSHandshakeTimeout(testTLSTimeoutError{}))
+	assert.Equal(t, false, IsTLSHandshakeTimeout(testTimeoutError{}))
+	assert.Equal(t, true, IsTemporaryNetErr(testTimeoutError{}))
+
+	// the message would match a string criteria, but it's not a network failure
+	assert.Equal(t, false, IsTemporaryNetErr(errors.New("connection refused")))
+	assert.Equal(t, false, IsTemporaryNetErr(nil))
+}
+
+func TestNetworkPolicy(t *testing.T) {
+	assert.Equal(t, "network", NetworkPolicy.String())
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return testOpError(syscall.ECONNREFUSED)
+		}
+		return nil
+	}, WithPolicyType(NetworkPolicy), WithClock(&testClock{now: time.Now()}))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, calls)
+}
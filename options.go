@@ -0,0 +1,458 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Option configures how Do and the context-aware executors retry an operation
+type Option func(*options)
+
+// The settings of the policy Do uses when it's given neither WithPolicies nor WithPolicyType
+const (
+	// DefaultAttempts is the default maximum number of attempts, including the first one
+	DefaultAttempts = 4
+	// DefaultDelay is the default delay between attempts
+	DefaultDelay = time.Second * 2
+)
+
+// options holds the configuration of a single retry sequence
+type options struct {
+	policies       []Policy
+	customPolicies bool
+	// matcher is set by WithCompiledPolicies and NewRetryer
+	matcher *CompiledPolicies
+	// policy is used when customPolicies is false
+	policy Policy
+
+	onRetry     func(attempt int, err error, nextDelay time.Duration)
+	onExhausted func(err error, stats Result)
+	delayFunc   func(attempt int, err error) time.Duration
+	successIf   func(err error) bool
+	// retryIfResult is the func(T) bool set by WithRetryIfResult
+	retryIfResult any
+	// abortIf is set by WithAbortOn, WithAbortOnStatus and WithAbortIf
+	abortIf []func(err error) bool
+	// exitCodes is set by WithExitCodes
+	exitCodes []int
+	// staleLookups is set by WithStaleLookups
+	staleLookups time.Duration
+	// maxStaleness is set by WithMaxStaleness
+	maxStaleness time.Duration
+	// breakerEvents is set by WithBreakerEvents
+	breakerEvents []*HostBreakers
+	// stopCh and stopIf are set by WithStopChannel and WithStopFunc
+	stopCh <-chan struct{}
+	stopIf func() bool
+	// middlewares is set by WithAttemptMiddleware
+	middlewares []AttemptMiddleware
+
+	collectErrors bool
+	maxRetryAfter time.Duration
+	// maxTotalAttempts is set by WithMaxTotalAttempts
+	maxTotalAttempts int
+
+	maxElapsedTime time.Duration
+	attemptTimeout time.Duration
+	deadlineCheck  bool
+	minAttempt     time.Duration
+	// propagateDeadline and deadlineFraction are set by WithDeadlinePropagation
+	propagateDeadline bool
+	deadlineFraction  float64
+	recoverPanics     bool
+	healthyPeriod     time.Duration
+	// tickOverlap and onTick are set by WithTickOverlap and WithOnTick
+	tickOverlap TickOverlap
+	onTick      func(err error)
+
+	initialDelay        time.Duration
+	immediateFirstRetry bool
+
+	idempotentOnly bool
+	drainLimit     int64
+	bodyPeek       int64
+	httpSuccess    func(resp *http.Response) bool
+	// statusClasses is set by WithStatusClass
+	statusClasses map[int]StatusClass
+
+	metrics MetricsCollector
+	tracer  Tracer
+	clock   Clock
+	// waiter is set by WithWaiter, the clock waits without it
+	waiter Waiter
+	budget *RetryBudget
+	// adaptive is set by WithAdaptiveRetry
+	adaptive *AdaptiveRetry
+	limiter  Limiter
+	// retrySlots is set by WithMaxConcurrentRetries
+	retrySlots *retrySlots
+	int63n     func(int64) int64
+	// events, stats and burn are set by NewRetryer
+	events *eventHub
+	stats  *policyStats
+	burn   *burnTracker
+	// shutdown is done once the Retryer is closed, see Retryer.Close
+	shutdown context.Context
+	// burnAlerts is set by WithBurnAlert
+	burnAlerts []BurnAlert
+	// scheduler parks the operations of Retryer.Go, see WithScheduler
+	scheduler *Scheduler
+}
+
+// newOptions returns the default options with opts applied
+func newOptions(opts []Option) *options {
+	o := &options{
+		policy: Policy{
+			RetryIf:       func(error) bool { return true },
+			DelayDuration: DefaultDelay,
+			RetryLimit:    DefaultAttempts - 1,
+		},
+		maxRetryAfter: DefaultMaxRetryAfter,
+		healthyPeriod: DefaultHealthyPeriod,
+		drainLimit:    DefaultDrainLimit,
+		httpSuccess:   IsSuccessStatus,
+		clock:         realClock{},
+		int63n:        rand.Int63n,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.customPolicies {
+		o.policies = []Policy{o.policy}
+	}
+	return o
+}
+
+// defaultOptions are the options of Do without any, they're built once since nothing changes them
+var defaultOptions = struct {
+	once sync.Once
+	o    *options
+}{}
+
+// sharedOptions returns the options configured by opts, the ones without any are shared by the
+// callers so they mustn't be changed
+func sharedOptions(opts []Option) *options {
+	if len(opts) > 0 {
+		return newOptions(opts)
+	}
+	defaultOptions.once.Do(func() {
+		defaultOptions.o = newOptions(nil)
+	})
+	return defaultOptions.o
+}
+
+// sleep waits for d with the waiter, it's cut short with ErrRetryerClosed when the Retryer is closed,
+// and with ErrStopped when the stop channel is closed
+func (o *options) sleep(ctx context.Context, d time.Duration) error {
+	if o.shutdown == nil && o.stopCh == nil {
+		return o.wait(ctx, d)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if o.shutdown != nil {
+		stop := context.AfterFunc(o.shutdown, func() {
+			cancel(ErrRetryerClosed)
+		})
+		defer stop()
+	}
+	if o.stopCh != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-o.stopCh:
+				cancel(ErrStopped)
+			case <-done:
+			}
+		}()
+	}
+	if err := o.wait(ctx, d); err != nil {
+		if cause := context.Cause(ctx); cause == ErrRetryerClosed || cause == ErrStopped {
+			return cause
+		}
+		return err
+	}
+	return nil
+}
+
+// withPolicies returns opts preceded by WithPolicies(retryPolicies)
+func withPolicies(retryPolicies []Policy, opts []Option) []Option {
+	return append([]Option{WithPolicies(retryPolicies)}, opts...)
+}
+
+// withCompiled is withPolicies for compiled policies
+func withCompiled(c *CompiledPolicies, opts []Option) []Option {
+	return append([]Option{WithCompiledPolicies(c)}, opts...)
+}
+
+// WithPolicies sets the policies evaluated on every failed attempt. A nil or empty
+// slice means nothing is retried
+func WithPolicies(retryPolicies []Policy) Option {
+	return func(o *options) {
+		o.policies = retryPolicies
+		o.customPolicies = true
+		o.matcher = nil
+	}
+}
+
+// WithPolicyType sets the policies evaluated on every failed attempt to GetRetryPolicies(policyType),
+// which are compiled once for every executor given the type, see WithCompiledPolicies
+func WithPolicyType(policyType PolicyType) Option {
+	return WithCompiledPolicies(compiledPolicyType(policyType))
+}
+
+// WithAttempts sets the maximum number of attempts, including the first one, of the default policy.
+// The default policy retries any error, and is only used without WithPolicies or WithPolicyType,
+// or for the results retried by WithRetryIfResult
+func WithAttempts(attempts int) Option {
+	return func(o *options) {
+		if attempts < 1 {
+			attempts = 1
+		}
+		o.policy.RetryLimit = attempts - 1
+	}
+}
+
+// WithDelay sets the DelayDuration of the default policy
+func WithDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.policy.DelayDuration = delay
+	}
+}
+
+// WithMaxDelay sets the MaxDelay of the default policy
+func WithMaxDelay(max time.Duration) Option {
+	return func(o *options) {
+		o.policy.MaxDelay = max
+	}
+}
+
+// WithMinDelay sets the MinDelay of the default policy
+func WithMinDelay(min time.Duration) Option {
+	return func(o *options) {
+		o.policy.MinDelay = min
+	}
+}
+
+// WithBackoff sets the Backoff of the default policy
+func WithBackoff(backoff BackoffType) Option {
+	return func(o *options) {
+		o.policy.Backoff = backoff
+	}
+}
+
+// WithJitter sets the Jitter of the default policy
+func WithJitter(jitter JitterType) Option {
+	return func(o *options) {
+		o.policy.Jitter = jitter
+	}
+}
+
+// WithRetryIf sets the RetryIf predicate of the default policy, so only the errors
+// it returns true for are retried
+func WithRetryIf(fn func(error) bool) Option {
+	return func(o *options) {
+		o.policy.RetryIf = fn
+	}
+}
+
+// WithMaxTotalAttempts caps the number of attempts of an operation, including the first one, whatever
+// the policies matching their errors. Every policy still counts the failures it matched against its
+// own RetryLimit, see Policy.RetryLimit, so a sequence of errors of different kinds is stopped by
+// whichever limit is reached first. The decision is made with ReasonMaxTotalAttempts.
+// Zero or less doesn't cap the attempts
+func WithMaxTotalAttempts(n int) Option {
+	return func(o *options) {
+		o.maxTotalAttempts = n
+	}
+}
+
+// WithOnRetry sets a hook that is called after a failed attempt, right before waiting for the next one.
+// attempt is the number of the failed attempt starting at 1, err is its error, and nextDelay is
+// how long the executor is going to wait before the next attempt
+func WithOnRetry(fn func(attempt int, err error, nextDelay time.Duration)) Option {
+	return func(o *options) {
+		o.onRetry = fn
+	}
+}
+
+// WithOnExhausted sets a hook called when the executor gives up on the operation, because the
+// retries are exhausted or the error can't be retried, i.e: to alert or to fall back.
+// err is the returned error and stats tells how the retry went, see DoResult.
+// It's not called when the operation succeeds or ctx is done
+func WithOnExhausted(fn func(err error, stats Result)) Option {
+	return func(o *options) {
+		o.onExhausted = fn
+	}
+}
+
+// WithDelayFunc computes the delay before every retry with fn instead of the matched policy,
+// i.e: from a backoff hint carried by the error. attempt is the number of the failed attempt
+// starting at 1 and err is its error. A negative delay keeps the one of the policy, including
+// the Retry-After header of the response, see WithMaxRetryAfter
+func WithDelayFunc(fn func(attempt int, err error) time.Duration) Option {
+	return func(o *options) {
+		o.delayFunc = fn
+	}
+}
+
+// WithSuccessIf makes the executor treat an attempt failing with an error for which fn returns true
+// as successful, i.e: a partial success reported as an error. The attempt isn't retried and its
+// result is returned with a nil error
+func WithSuccessIf(fn func(err error) bool) Option {
+	return func(o *options) {
+		o.successIf = fn
+	}
+}
+
+// WithAbortOn makes the executor return right away the errors matching any of errs with errors.Is,
+// even when a policy matches them, i.e: context.Canceled from a nested context.
+// The decision is made with ReasonAborted. It adds to the previous abort conditions
+func WithAbortOn(errs ...error) Option {
+	return func(o *options) {
+		for _, target := range errs {
+			target := target
+			o.abortIf = append(o.abortIf, func(err error) bool {
+				return errors.Is(err, target)
+			})
+		}
+	}
+}
+
+// WithAbortOnStatus is WithAbortOn for the failed responses of the HTTP executors with one of
+// the status codes, i.e: 401 and 403 that no retry fixes
+func WithAbortOnStatus(codes ...int) Option {
+	return func(o *options) {
+		o.abortIf = append(o.abortIf, func(err error) bool {
+			var se *statusError
+			if !errors.As(err, &se) {
+				return false
+			}
+			for _, code := range codes {
+				if se.resp.StatusCode == code {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+// WithAbortIf is WithAbortOn for the errors fn returns true for
+func WithAbortIf(fn func(err error) bool) Option {
+	return func(o *options) {
+		o.abortIf = append(o.abortIf, fn)
+	}
+}
+
+// aborts reports whether err matches one of the abort conditions
+func (o *options) aborts(err error) bool {
+	for _, abort := range o.abortIf {
+		if abort(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithStopChannel stops the retries once ch is closed, for the code that doesn't thread a context, i.e: to
+// abort them from a signal handler or a shutdown hook. It's checked before every attempt and interrupts the
+// delays. The attempt in flight isn't interrupted, the executor returns ErrStopped wrapping its error,
+// or alone if the first attempt wasn't made. Retryer.Go ignores it
+func WithStopChannel(ch <-chan struct{}) Option {
+	return func(o *options) {
+		o.stopCh = ch
+	}
+}
+
+// WithStopFunc is like WithStopChannel but stops the retries once fn returns true. fn is called before every
+// delay and every attempt, it doesn't interrupt the delays
+func WithStopFunc(fn func() bool) Option {
+	return func(o *options) {
+		o.stopIf = fn
+	}
+}
+
+// stopped reports whether the retries are stopped by WithStopChannel or WithStopFunc
+func (o *options) stopped() bool {
+	if o.stopCh != nil {
+		select {
+		case <-o.stopCh:
+			return true
+		default:
+		}
+	}
+	return o.stopIf != nil && o.stopIf()
+}
+
+// WithInitialDelay delays the first attempt by d, i.e: for a reconnect loop after a known outage.
+// The initial delay counts against WithMaxElapsedTime but not in Result.TotalDelay.
+// The executor returns ctx's error if it's done before the first attempt
+func WithInitialDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.initialDelay = d
+	}
+}
+
+// WithImmediateFirstRetry makes the first retry right after the first attempt fails, when latency
+// matters more than the load of the retries. The delays of the next retries are unchanged,
+// and a Retry-After header or WithDelayFunc still delay the first retry
+func WithImmediateFirstRetry() Option {
+	return func(o *options) {
+		o.immediateFirstRetry = true
+	}
+}
+
+// WithCollectErrors makes the executor return an *AttemptsError holding the error and timing
+// of every failed attempt instead of only the last error when the retry gives up
+func WithCollectErrors() Option {
+	return func(o *options) {
+		o.collectErrors = true
+	}
+}
+
+// WithMaxElapsedTime sets a budget for the whole retry sequence, including the delays between attempts.
+// The context passed to the operation expires when the budget is spent, and no attempt is started
+// if it would begin after that; the error of the last attempt is returned. Zero means no budget
+func WithMaxElapsedTime(max time.Duration) Option {
+	return func(o *options) {
+		o.maxElapsedTime = max
+	}
+}
+
+// WithAttemptTimeout runs every attempt with a context that expires after timeout.
+// An attempt that doesn't return in time is abandoned and fails with ErrAttemptTimeout,
+// which is evaluated against the policies like any other error. Zero means no timeout
+func WithAttemptTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.attemptTimeout = timeout
+	}
+}
+
+// WithDeadlinePropagation runs every attempt with a share of the time left before the deadline of the
+// context, or of WithMaxElapsedTime, so the first attempts can't use all of it and starve the next ones.
+// With a fraction in (0, 1) an attempt gets that fraction of the time left, otherwise the time left is
+// divided by the attempts left: the retries the matched policy still allows, or the largest RetryLimit
+// of the policies for the first attempt, capped by WithMaxTotalAttempts. The last attempt gets all the
+// time left. An attempt running out of its share fails with ErrAttemptTimeout, as with WithAttemptTimeout,
+// whose timeout still applies when it's shorter. Without a deadline the attempts aren't limited
+func WithDeadlinePropagation(fraction float64) Option {
+	return func(o *options) {
+		o.propagateDeadline = true
+		o.deadlineFraction = fraction
+	}
+}
+
+// WithDeadlineCheck gives up right away with ErrDeadlineWouldExceed when the deadline of the context
+// passed to the executor would pass before the next attempt could run for minAttempt, instead of
+// waiting for the delay only to fail with context.DeadlineExceeded
+func WithDeadlineCheck(minAttempt time.Duration) Option {
+	return func(o *options) {
+		o.deadlineCheck = true
+		o.minAttempt = minAttempt
+	}
+}
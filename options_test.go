@@ -0,0 +1,557 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithOnRetry(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      3,
+			Backoff:         LinearBackoff,
+		},
+	}
+	var attempts []int
+	var delays []time.Duration
+	indexTestTimedout = 1
+	err := ExecutorWithPoliciesContext(context.Background(), policies, func(ctx context.Context) error {
+		return testTimedout(2)
+	}, WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		assert.Equal(t, "timed out", err.Error())
+		attempts = append(attempts, attempt)
+		delays = append(delays, nextDelay)
+	}))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, []time.Duration{time.Millisecond * 10, time.Millisecond * 20}, delays)
+}
+
+func TestWithOnRetryNotCalledWhenNotRetried(t *testing.T) {
+	var called bool
+	indexTestTimedout = 1
+	err := ExecutorWithPoliciesContext(context.Background(), nil, func(ctx context.Context) error {
+		return testNonRetryableError(1)
+	}, WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		called = true
+	}))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, false, called)
+}
+
+func TestWithCollectErrors(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      2,
+		},
+	}
+	var calls int
+	err := ExecutorWithPoliciesContext(context.Background(), policies, func(ctx context.Context) error {
+		calls++
+		if calls == 2 {
+			return errTestSentinel
+		}
+		return fmt.Errorf("attempt %d timed out", calls)
+	}, WithCollectErrors())
+
+	// the sentinel isn't retryable so only 2 attempts happen
+	var attemptsErr *AttemptsError
+	assert.Equal(t, true, errors.As(err, &attemptsErr))
+	assert.Equal(t, errTestSentinel, attemptsErr.Err)
+	assert.Equal(t, 2, len(attemptsErr.Attempts))
+	assert.Equal(t, 1, attemptsErr.Attempts[0].Attempt)
+	assert.Equal(t, "attempt 1 timed out", attemptsErr.Attempts[0].Err.Error())
+	assert.Equal(t, 2, attemptsErr.Attempts[1].Attempt)
+	assert.Equal(t, true, errors.Is(err, errTestSentinel))
+	assert.Equal(t, "sentinel (2 failed attempts; #1 after "+attemptsErr.Attempts[0].Duration.String()+
+		": attempt 1 timed out; #2 after "+attemptsErr.Attempts[1].Duration.String()+": sentinel)", err.Error())
+}
+
+func TestWithCollectErrorsExhausted(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      2,
+		},
+	}
+	indexTestTimedout = 1
+	err := ExecutorWithPoliciesContext(context.Background(), policies, func(ctx context.Context) error {
+		return testTimedout(5)
+	}, WithCollectErrors())
+	var attemptsErr *AttemptsError
+	assert.Equal(t, true, errors.As(err, &attemptsErr))
+	assert.Equal(t, 3, len(attemptsErr.Attempts))
+}
+
+func TestWithMaxElapsedTime(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond * 40,
+			RetryLimit:      100,
+		},
+	}
+	// the retry limit allows 4s of waiting, but the budget stops it after about 100ms
+	var calls int
+	start := time.Now()
+	err := ExecutorWithPoliciesContext(context.Background(), policies, func(ctx context.Context) error {
+		calls++
+		return errors.New("timed out")
+	}, WithMaxElapsedTime(time.Millisecond*100))
+	assert.Equal(t, "timed out", err.Error())
+	assert.Equal(t, true, time.Since(start) < time.Millisecond*100)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithMaxElapsedTimeAttemptContext(t *testing.T) {
+	// a hanging attempt is cut short by the budget
+	err := ExecutorWithPoliciesContext(context.Background(), nil, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithMaxElapsedTime(time.Millisecond*50))
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestWithAttemptTimeout(t *testing.T) {
+	policies := []Policy{
+		{
+			MatchError:    ErrAttemptTimeout,
+			DelayDuration: time.Millisecond * 10,
+			RetryLimit:    3,
+		},
+	}
+	// the first two attempts hang and ignore their context, the third one is quick
+	var calls int32
+	err := ExecutorWithPoliciesContext(context.Background(), policies, func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			time.Sleep(time.Second)
+		}
+		return nil
+	}, WithAttemptTimeout(time.Millisecond*50))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestWithAttemptTimeoutContextAware(t *testing.T) {
+	// an attempt returning the expired context's error is reported as ErrAttemptTimeout
+	var calls int32
+	err := ExecutorWithPoliciesContext(context.Background(), nil, func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithAttemptTimeout(time.Millisecond*20))
+	assert.Equal(t, ErrAttemptTimeout, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWithAttemptTimeoutStandardPolicy(t *testing.T) {
+	// StandardPolicy retries ErrAttemptTimeout since it's a "timed out" error
+	policy, ok := matchPolicy(GetRetryPolicies(StandardPolicy), ErrAttemptTimeout)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "timed out", policy.ErrorCodeString)
+}
+
+func TestWithDeadlineCheck(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var calls int
+	start := time.Now()
+	err := Do(ctx, func(ctx context.Context) error {
+		calls++
+		return errTestSentinel
+	}, WithDelay(time.Millisecond*800), WithDeadlineCheck(time.Millisecond*500))
+	// the first retry would start 800ms in and couldn't run 500ms before the deadline
+	assert.Equal(t, true, errors.Is(err, ErrDeadlineWouldExceed))
+	assert.Equal(t, true, errors.Is(err, errTestSentinel))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, true, time.Since(start) < time.Millisecond*500)
+}
+
+func TestWithDeadlineCheckEnoughTime(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var calls int
+	err := Do(ctx, func(ctx context.Context) error {
+		calls++
+		return errTestSentinel
+	}, WithAttempts(2), WithDelay(time.Millisecond*10), WithDeadlineCheck(time.Millisecond*100))
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: errTestSentinel}, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWithDeadlineCheckClock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	var calls int
+	err := Do(ctx, func(ctx context.Context) error {
+		calls++
+		return errTestSentinel
+	}, WithAttempts(10), WithDelay(time.Minute*20), WithDeadlineCheck(time.Minute), WithClock(&testClock{now: time.Now()}))
+	// the clock passed 40 minutes when the third retry would start too late
+	assert.Equal(t, true, errors.Is(err, ErrDeadlineWouldExceed))
+	assert.Equal(t, 3, calls)
+
+	// the share of an attempt is taken from the clock too
+	var left time.Duration
+	err = Do(ctx, func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		left = time.Until(deadline)
+		return nil
+	}, WithDeadlinePropagation(0), WithClock(&testClock{now: time.Now().Add(time.Minute * 59)}))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, left <= time.Minute, left)
+}
+
+func TestWithDelayFunc(t *testing.T) {
+	hint := time.Millisecond * 5
+	var delays []time.Duration
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTestSentinel
+		}
+		return errors.New("timed out")
+	}, WithAttempts(3), WithDelay(time.Second), WithDelayFunc(func(attempt int, err error) time.Duration {
+		// only the sentinel carries a hint, the other errors keep the policy delay
+		if errors.Is(err, errTestSentinel) {
+			return hint * time.Duration(attempt)
+		}
+		return -1
+	}), WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		delays = append(delays, nextDelay)
+	}), WithMaxElapsedTime(time.Millisecond*100))
+	assert.Equal(t, "timed out", err.Error())
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []time.Duration{hint}, delays)
+}
+
+func TestWithSuccessIf(t *testing.T) {
+	var calls int
+	result, err := DoResult(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errTestSentinel
+	}, WithAttempts(3), WithDelay(time.Millisecond), WithSuccessIf(func(err error) bool {
+		return errors.Is(err, errTestSentinel)
+	}))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, result.Attempts)
+}
+
+func TestWithOnExhausted(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "timed out",
+			DelayDuration:   time.Millisecond,
+			RetryLimit:      2,
+		},
+	}
+	var stats []Result
+	var errs []error
+	opts := []Option{WithOnExhausted(func(err error, s Result) {
+		errs = append(errs, err)
+		stats = append(stats, s)
+	})}
+	indexTestTimedout = 1
+	err := ExecutorWithPoliciesContext(context.Background(), policies, func(ctx context.Context) error {
+		return testTimedout(5)
+	}, opts...)
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, err, errs[0])
+	assert.Equal(t, 3, stats[0].Attempts)
+	assert.Equal(t, time.Millisecond*2, stats[0].TotalDelay)
+	assert.Equal(t, "timed out", stats[0].LastDecision.Policy.ErrorCodeString)
+	assert.Equal(t, ReasonLimitReached, stats[0].LastDecision.Reason)
+	assert.Equal(t, true, stats[0].Duration() > 0)
+
+	// neither a success nor a cancellation is reported
+	err = ExecutorWithPoliciesContext(context.Background(), policies, func(ctx context.Context) error {
+		return nil
+	}, opts...)
+	assert.Equal(t, true, err == nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = ExecutorWithPoliciesContext(ctx, policies, func(ctx context.Context) error {
+		return errors.New("timed out")
+	}, opts...)
+	assert.Equal(t, 1, len(stats))
+
+	// Retryer.Go reports it too
+	f := NewRetryer(append(opts, WithPolicies(policies))...).Go(context.Background(), func(ctx context.Context) error {
+		return Unrecoverable(errTestSentinel)
+	})
+	assert.Equal(t, errTestSentinel, f.Err())
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, ReasonUnrecoverable, stats[1].LastDecision.Reason)
+	assert.Equal(t, errTestSentinel, stats[1].LastError)
+}
+
+func TestWithInitialDelay(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var startedAt time.Time
+	res, err := DoResult(context.Background(), func(ctx context.Context) error {
+		startedAt = clock.Now()
+		return nil
+	}, WithInitialDelay(time.Second), WithClock(clock))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, res.StartedAt.Add(time.Second), startedAt)
+	assert.Equal(t, time.Duration(0), res.TotalDelay)
+
+	// the scheduled operations wait too
+	start := time.Now()
+	err = NewRetryer(WithInitialDelay(time.Millisecond*20)).Go(context.Background(), func(ctx context.Context) error {
+		return nil
+	}).Err()
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, true, time.Since(start) >= time.Millisecond*20)
+
+	// a context done during the initial delay makes no attempt
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	var calls int
+	err = Do(ctx, func(ctx context.Context) error {
+		calls++
+		return nil
+	}, WithInitialDelay(time.Second))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 0, calls)
+}
+
+func TestWithImmediateFirstRetry(t *testing.T) {
+	var delays []time.Duration
+	err := Do(context.Background(), func(ctx context.Context) error {
+		return errTestSentinel
+	}, WithAttempts(3), WithDelay(time.Millisecond*10), WithBackoff(LinearBackoff), WithImmediateFirstRetry(),
+		WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+			delays = append(delays, nextDelay)
+		}))
+	assert.Equal(t, &ExhaustedError{Attempts: 3, LastErr: errTestSentinel}, err)
+	assert.Equal(t, []time.Duration{0, time.Millisecond * 20}, delays)
+}
+
+func TestAttemptContextCause(t *testing.T) {
+	causes := make(chan error, 3)
+	err := Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	}, WithAttempts(2), WithDelay(time.Millisecond), WithAttemptTimeout(time.Millisecond*10))
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: ErrAttemptTimeout}, err)
+	// the attempts are abandoned when they time out, so they may still be running
+	assert.Equal(t, ErrAttemptTimeout, <-causes)
+	assert.Equal(t, ErrAttemptTimeout, <-causes)
+
+	err = Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	}, WithMaxElapsedTime(time.Millisecond*10))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, ErrMaxElapsedTime, <-causes)
+}
+
+func TestWithAbortOn(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return fmt.Errorf("query: %w", context.Canceled)
+	}, WithAttempts(3), WithDelay(time.Millisecond), WithAbortOn(errTestSentinel, context.Canceled))
+	assert.Equal(t, true, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("token expired")
+	}, WithAttempts(3), WithDelay(time.Millisecond), WithAbortIf(func(err error) bool {
+		return strings.Contains(err.Error(), "expired")
+	}))
+	assert.Equal(t, "token expired", err.Error())
+	assert.Equal(t, 1, calls)
+
+	d := Explain(errTestSentinel, WithAbortOn(errTestSentinel))
+	assert.Equal(t, false, d.Retry)
+	assert.Equal(t, ReasonAborted, d.Reason)
+}
+
+func TestWithAbortOnStatus(t *testing.T) {
+	var calls int
+	err := ExecutorHTTPWithPoliciesContext(context.Background(), []Policy{{StatusRanges: []StatusRange{{From: 400, To: 599}}, RetryLimit: 2, DelayDuration: time.Millisecond}},
+		func(ctx context.Context) (*http.Response, error) {
+			calls++
+			return &http.Response{StatusCode: http.StatusForbidden, Status: http.StatusText(http.StatusForbidden)}, nil
+		}, WithAbortOnStatus(http.StatusUnauthorized, http.StatusForbidden))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = ExecutorHTTPWithPoliciesContext(context.Background(), []Policy{{StatusRanges: []StatusRange{{From: 400, To: 599}}, RetryLimit: 2, DelayDuration: time.Millisecond}},
+		func(ctx context.Context) (*http.Response, error) {
+			calls++
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: http.StatusText(http.StatusServiceUnavailable)}, nil
+		}, WithAbortOnStatus(http.StatusUnauthorized, http.StatusForbidden))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithMaxTotalAttempts(t *testing.T) {
+	policies := []Policy{
+		{ErrorCodeString: "timed out", DelayDuration: time.Millisecond, RetryLimit: 3},
+		{ErrorCodeString: "unavailable", DelayDuration: time.Millisecond, RetryLimit: 3},
+	}
+	var calls int
+	err := ExecutorWithPoliciesContext(context.Background(), policies, func(ctx context.Context) error {
+		calls++
+		if calls%2 == 0 {
+			return errors.New("unavailable")
+		}
+		return errors.New("timed out")
+	}, WithMaxTotalAttempts(4))
+	assert.Equal(t, &ExhaustedError{Attempts: 4, LastErr: errors.New("unavailable")}, err)
+	assert.Equal(t, 4, calls)
+
+	e := NewPolicyEvaluator(WithPolicies(policies), WithMaxTotalAttempts(2))
+	assert.Equal(t, true, e.Evaluate(errors.New("timed out")).Retry)
+	d := e.Evaluate(errors.New("unavailable"))
+	assert.Equal(t, false, d.Retry)
+	assert.Equal(t, ReasonMaxTotalAttempts, d.Reason)
+}
+
+func TestWithStopChannel(t *testing.T) {
+	stop := make(chan struct{})
+	errFlaky := errors.New("flaky")
+	var calls int
+	done := make(chan error, 1)
+	go func() {
+		done <- Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls == 2 {
+				close(stop)
+			}
+			return errFlaky
+		}, WithAttempts(10), WithDelay(time.Millisecond*5), WithStopChannel(stop))
+	}()
+	err := <-done
+	assert.Equal(t, true, errors.Is(err, ErrStopped))
+	assert.Equal(t, true, errors.Is(err, errFlaky))
+	assert.Equal(t, 2, calls)
+
+	// the delay is interrupted
+	stop = make(chan struct{})
+	start := time.Now()
+	time.AfterFunc(time.Millisecond*10, func() { close(stop) })
+	err = Do(context.Background(), func(ctx context.Context) error {
+		return errFlaky
+	}, WithAttempts(2), WithDelay(time.Hour), WithStopChannel(stop))
+	assert.Equal(t, true, errors.Is(err, ErrStopped))
+	assert.Equal(t, true, time.Since(start) < time.Minute)
+
+	// nothing is run once stopped
+	calls = 0
+	err = Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return nil
+	}, WithStopChannel(stop))
+	assert.Equal(t, ErrStopped, err)
+	assert.Equal(t, 0, calls)
+}
+
+func TestWithStopFunc(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("flaky")
+	}, WithAttempts(10), WithDelay(time.Millisecond), WithStopFunc(func() bool { return calls >= 3 }))
+	assert.Equal(t, true, errors.Is(err, ErrStopped))
+	assert.Equal(t, 3, calls)
+}
+
+// deadlineShares records the time an attempt has before its deadline, the abandoned attempts may still be running
+type deadlineShares struct {
+	mu     sync.Mutex
+	shares []time.Duration
+}
+
+func (d *deadlineShares) record(ctx context.Context) {
+	deadline, _ := ctx.Deadline()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.shares = append(d.shares, time.Until(deadline))
+}
+
+func (d *deadlineShares) get() []time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]time.Duration(nil), d.shares...)
+}
+
+func TestWithDeadlinePropagation(t *testing.T) {
+	policies := []Policy{{MatchError: ErrAttemptTimeout, RetryLimit: 3}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*4)
+	defer cancel()
+	d := &deadlineShares{}
+	err := Do(ctx, func(ctx context.Context) error {
+		d.record(ctx)
+		if AttemptFromContext(ctx) < 4 {
+			<-ctx.Done()
+		}
+		return nil
+	}, WithPolicies(policies), WithDeadlinePropagation(0), WithAttemptTimeout(time.Millisecond*20))
+	assert.Equal(t, nil, err)
+	shares := d.get()
+	assert.Equal(t, 4, len(shares))
+	// the attempt timeout is shorter than the share of every attempt
+	for _, share := range shares {
+		assert.Equal(t, true, share <= time.Millisecond*20, share)
+	}
+
+	// the time left is divided by the attempts left, the last one gets all of it
+	d = &deadlineShares{}
+	err = Do(context.Background(), func(ctx context.Context) error {
+		d.record(ctx)
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithPolicies(policies), WithDeadlinePropagation(0), WithMaxElapsedTime(time.Millisecond*400))
+	assert.Equal(t, true, err != nil)
+	shares = d.get()
+	assert.Equal(t, 4, len(shares))
+	assert.Equal(t, true, shares[0] <= time.Millisecond*100 && shares[0] > time.Millisecond*80, shares[0])
+	assert.Equal(t, true, shares[1] <= time.Millisecond*100 && shares[1] > time.Millisecond*80, shares[1])
+	assert.Equal(t, true, shares[3] > time.Millisecond*80, shares[3])
+
+	// a fraction of the time left
+	d = &deadlineShares{}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*400)
+	defer cancel()
+	_ = Do(ctx, func(ctx context.Context) error {
+		d.record(ctx)
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithPolicies(policies), WithDeadlinePropagation(0.5))
+	shares = d.get()
+	assert.Equal(t, true, shares[0] <= time.Millisecond*200 && shares[0] > time.Millisecond*180, shares[0])
+	assert.Equal(t, true, shares[1] <= time.Millisecond*100 && shares[1] > time.Millisecond*80, shares[1])
+
+	// without a deadline the attempts aren't limited
+	err = Do(context.Background(), func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		assert.Equal(t, false, ok)
+		return nil
+	}, WithDeadlinePropagation(0))
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of an attempt that panicked, see WithRecoverPanics
+type PanicError struct {
+	// Value is the value passed to panic
+	Value interface{}
+	// Stack is the stack trace of the goroutine when it panicked
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("retry: panic: %v", e.Value)
+}
+
+// Unwrap returns Value if it's an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithRecoverPanics recovers a panic of the operation and reports it as a *PanicError, which is
+// evaluated against the policies like any other error. So it's retried by the default policy, and
+// by a policy matching it, i.e: MatchErrorType: ErrorType[*PanicError](), while the built-in
+// policies give up and return it
+func WithRecoverPanics() Option {
+	return func(o *options) {
+		o.recoverPanics = true
+	}
+}
+
+// recoverPanics returns fn reporting its panics as a *PanicError
+func recoverPanics[T any](fn func(context.Context) (T, error)) func(context.Context) (T, error) {
+	return func(ctx context.Context) (result T, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				var zero T
+				result, err = zero, &PanicError{Value: v, Stack: debug.Stack()}
+			}
+		}()
+		return fn(ctx)
+	}
+}
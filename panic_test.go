@@ -0,0 +1,49 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRecoverPanics(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			panic("nil map")
+		}
+		return nil
+	}, WithDelay(time.Millisecond), WithRecoverPanics())
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithRecoverPanicsNotRetryable(t *testing.T) {
+	var calls int
+	err := ExecutorWithPolicyTypeContext(context.Background(), StandardPolicy, func(ctx context.Context) error {
+		calls++
+		panic(errTestSentinel)
+	}, WithRecoverPanics())
+	var panicErr *PanicError
+	assert.Equal(t, true, errors.As(err, &panicErr))
+	assert.Equal(t, true, errors.Is(err, errTestSentinel))
+	assert.Equal(t, "retry: panic: sentinel", err.Error())
+	assert.Equal(t, true, strings.Contains(string(panicErr.Stack), "panic_test.go"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithRecoverPanicsPolicy(t *testing.T) {
+	policies := []Policy{{MatchErrorType: ErrorType[*PanicError](), DelayDuration: time.Millisecond, RetryLimit: 2}}
+	var calls int
+	_, err := ExecutorTWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (int, error) {
+		calls++
+		panic("boom")
+	}, WithRecoverPanics(), WithAttemptTimeout(time.Second))
+	assert.Equal(t, "retry: panic: boom", err.Error())
+	assert.Equal(t, 3, calls)
+}
@@ -0,0 +1,119 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TickOverlap is what ScheduleEvery does with a tick while the run of a previous one is still retrying
+type TickOverlap int
+
+const (
+	// SkipOverlappingTicks drops the tick, fn runs again on the first tick after the run is over
+	SkipOverlappingTicks TickOverlap = iota
+	// CoalesceOverlappingTicks runs fn once more right after the run is over, however many ticks overlapped it
+	CoalesceOverlappingTicks
+)
+
+// WithTickOverlap sets what ScheduleEvery does with the ticks overlapping a run, SkipOverlappingTicks by default
+func WithTickOverlap(overlap TickOverlap) Option {
+	return func(o *options) {
+		o.tickOverlap = overlap
+	}
+}
+
+// WithOnTick sets a hook called by ScheduleEvery with the outcome of every run once its retries are over,
+// a run cut short by the cancellation of the context of ScheduleEvery isn't reported
+func WithOnTick(fn func(err error)) Option {
+	return func(o *options) {
+		o.onTick = fn
+	}
+}
+
+// ScheduleEvery runs fn right away and then every interval until ctx is done, for health-check pollers
+// and sync jobs. Every run is retried as configured by opts, see Do, and its outcome is given to the
+// WithOnTick hook. A run is never started while the previous one is still retrying, the overlapping
+// ticks are skipped or coalesced, see WithTickOverlap. It waits for the last run to return, then
+// returns ctx.Err(), or an error if interval isn't positive
+func ScheduleEvery(ctx context.Context, interval time.Duration, fn FuncContext, opts ...Option) error {
+	if interval <= 0 {
+		return fmt.Errorf("retry: non-positive interval %v", interval)
+	}
+	o := newOptions(opts)
+	if o.matcher == nil {
+		o.matcher = CompilePolicies(o.policies)
+	}
+	t := &ticker{o: o, fn: func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}}
+	defer t.wg.Wait()
+	next := o.clock.Now()
+	for {
+		t.tick(ctx)
+		next = next.Add(interval)
+		now := o.clock.Now()
+		if !next.After(now) {
+			// the ticks the clock already passed are missed
+			next = next.Add((now.Sub(next)/interval + 1) * interval)
+		}
+		if err := o.clock.Sleep(ctx, next.Sub(now)); err != nil {
+			return err
+		}
+	}
+}
+
+// ticker runs the ticks of ScheduleEvery one at a time
+type ticker struct {
+	o  *options
+	fn func(ctx context.Context) (struct{}, error)
+	wg sync.WaitGroup
+
+	mu sync.Mutex
+	// busy reports whether a run is in progress, pending whether a tick was coalesced into it
+	busy    bool
+	pending bool
+}
+
+// tick starts a run, unless one is in progress
+func (t *ticker) tick(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.busy {
+		t.pending = t.o.tickOverlap == CoalesceOverlappingTicks
+		return
+	}
+	t.busy = true
+	t.wg.Add(1)
+	go t.run(ctx)
+}
+
+// run runs fn, and again as long as ticks were coalesced into the run
+func (t *ticker) run(ctx context.Context) {
+	defer t.wg.Done()
+	for {
+		_, err := execute(ctx, t.o, t.fn)
+		if ctx.Err() != nil {
+			t.done()
+			return
+		}
+		if t.o.onTick != nil {
+			t.o.onTick(err)
+		}
+		t.mu.Lock()
+		if !t.pending {
+			t.busy = false
+			t.mu.Unlock()
+			return
+		}
+		t.pending = false
+		t.mu.Unlock()
+	}
+}
+
+func (t *ticker) done() {
+	t.mu.Lock()
+	t.busy, t.pending = false, false
+	t.mu.Unlock()
+}
@@ -0,0 +1,66 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	var mu sync.Mutex
+	var outcomes []error
+	errFlaky := errors.New("flaky")
+	err := ScheduleEvery(ctx, time.Millisecond*10, func(ctx context.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			return errFlaky
+		}
+		return nil
+	}, WithAttempts(2), WithDelay(time.Millisecond), WithOnTick(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		outcomes = append(outcomes, err)
+		if len(outcomes) == 3 {
+			cancel()
+		}
+	}))
+	assert.Equal(t, context.Canceled, err)
+	// the first run is retried within its tick
+	assert.Equal(t, []error{nil, nil, nil}, outcomes)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+}
+
+func TestScheduleEveryOverlap(t *testing.T) {
+	for _, tc := range []struct {
+		overlap TickOverlap
+		runs    int32
+	}{
+		{SkipOverlappingTicks, 2},
+		{CoalesceOverlappingTicks, 3},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*150)
+		var runs int32
+		err := ScheduleEvery(ctx, time.Millisecond*40, func(ctx context.Context) error {
+			// the first run overlaps the ticks at 40ms and 80ms
+			if atomic.AddInt32(&runs, 1) == 1 {
+				time.Sleep(time.Millisecond * 100)
+			}
+			return nil
+		}, WithTickOverlap(tc.overlap))
+		cancel()
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, tc.runs, atomic.LoadInt32(&runs))
+	}
+}
+
+func TestScheduleEveryInterval(t *testing.T) {
+	err := ScheduleEvery(context.Background(), 0, func(ctx context.Context) error { return nil })
+	assert.Equal(t, true, err != nil)
+}
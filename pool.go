@@ -0,0 +1,114 @@
+package retry
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool runs the jobs submitted to it on a bounded number of workers, and retries each one as configured
+// by the options it's created with, see Do, so a batch processor gets retries, concurrency limiting and
+// backpressure from one component. Submit blocks while the queue of the pool is full. It's safe for concurrent use
+type Pool struct {
+	// OnDeadLetter is called with the jobs given up on and the error of their last attempt, i.e: to save
+	// them for later. It's called from the worker that ran the job, and must be set before the first Submit
+	OnDeadLetter func(job Func, err error)
+
+	opts    []Option
+	o       *options
+	jobs    chan poolJob
+	workers sync.WaitGroup
+
+	// mu guards closed, Submit holds it for reading while it waits for room in the queue
+	mu     sync.RWMutex
+	closed bool
+}
+
+// poolJob is a job submitted to a Pool, o is nil when it has no options of its own
+type poolJob struct {
+	fn Func
+	o  *options
+}
+
+// NewPool returns a Pool running up to workers jobs at a time, with a queue of up to queueSize jobs
+// waiting for a worker, and starts its workers. A workers of zero or less is one worker. Close stops them
+func NewPool(workers, queueSize int, opts ...Option) *Pool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	o := newOptions(opts)
+	if o.matcher == nil {
+		o.matcher = CompilePolicies(o.policies)
+	}
+	p := &Pool{opts: opts, o: o, jobs: make(chan poolJob, queueSize)}
+	p.workers.Add(workers)
+	for w := 0; w < workers; w++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues job to be run by a worker, and blocks while the queue is full. The options of the job,
+// i.e: WithPolicies, are applied after the options of the pool. It returns ErrPoolClosed after Close
+func (p *Pool) Submit(job Func, opts ...Option) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	p.jobs <- p.job(job, opts)
+	return nil
+}
+
+// TrySubmit is like Submit but doesn't block, it returns false if the queue is full or the pool is closed
+func (p *Pool) TrySubmit(job Func, opts ...Option) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	select {
+	case p.jobs <- p.job(job, opts):
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting jobs, and waits for the queued ones to be run and the workers to return
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+	p.workers.Wait()
+}
+
+func (p *Pool) job(fn Func, opts []Option) poolJob {
+	job := poolJob{fn: fn}
+	if len(opts) > 0 {
+		job.o = newOptions(append(append([]Option(nil), p.opts...), opts...))
+	}
+	return job
+}
+
+// work runs the queued jobs until the pool is closed
+func (p *Pool) work() {
+	defer p.workers.Done()
+	for job := range p.jobs {
+		o := job.o
+		if o == nil {
+			o = p.o
+		}
+		_, err := execute(context.Background(), o, func(context.Context) (struct{}, error) {
+			return struct{}{}, job.fn()
+		})
+		if err != nil && p.OnDeadLetter != nil {
+			p.OnDeadLetter(job.fn, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool(t *testing.T) {
+	p := NewPool(2, 10, WithAttempts(3), WithDelay(time.Millisecond))
+	var mu sync.Mutex
+	var dead []error
+	p.OnDeadLetter = func(job Func, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dead = append(dead, err)
+	}
+
+	var running, peak, calls int32
+	job := func(fail bool) Func {
+		return func() error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+					break
+				}
+			}
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(time.Millisecond)
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		}
+	}
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, nil, p.Submit(job(false)))
+	}
+	assert.Equal(t, nil, p.Submit(job(true)))
+	// the job's own options override the pool's
+	assert.Equal(t, nil, p.Submit(job(true), WithAttempts(1)))
+	p.Close()
+
+	assert.Equal(t, int32(5+3+1), calls)
+	assert.Equal(t, true, peak <= 2)
+	assert.Equal(t, 2, len(dead))
+	assert.Equal(t, ErrPoolClosed, p.Submit(job(false)))
+	assert.Equal(t, false, p.TrySubmit(job(false)))
+	p.Close()
+}
+
+func TestPoolBackpressure(t *testing.T) {
+	p := NewPool(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	assert.Equal(t, nil, p.Submit(func() error {
+		close(started)
+		<-release
+		return nil
+	}))
+	<-started
+	assert.Equal(t, true, p.TrySubmit(func() error { return nil }))
+	// the worker is busy and the queue is full
+	assert.Equal(t, false, p.TrySubmit(func() error { return nil }))
+
+	submitted := make(chan struct{})
+	go func() {
+		_ = p.Submit(func() error { return nil })
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("Submit didn't block")
+	case <-time.After(time.Millisecond * 20):
+	}
+	close(release)
+	<-submitted
+	p.Close()
+}
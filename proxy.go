@@ -0,0 +1,54 @@
+package retry
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// NewReverseProxy returns an httputil.ReverseProxy to target, like httputil.NewSingleHostReverseProxy,
+// whose upstream requests are retried by a Transport with policies and opts, GetRetryPolicies(HTTPPolicy)
+// if policies is nil. When the retries are exhausted the last upstream response is passed on to the
+// client, and a transport error is answered with 502 Bad Gateway by the ReverseProxy.
+// The requests are retried only before the upstream response headers are received, so a streamed
+// response is never retried once it started. A request body is only retried if it's buffered, see BufferBody
+func NewReverseProxy(target *url.URL, policies []Policy, opts ...Option) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = &Transport{Policies: policies, Options: opts}
+	return proxy
+}
+
+// BufferBody is a middleware that reads the request bodies of up to maxBytes in memory, so the
+// requests proxied by NewReverseProxy can be retried. A larger body is streamed to next as is,
+// and its request is sent once. A body that fails to be read is answered with 400 Bad Request
+func BufferBody(next http.Handler, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody || r.ContentLength > maxBytes {
+			next.ServeHTTP(w, r)
+			return
+		}
+		buf, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if int64(len(buf)) > maxBytes {
+			// too large to be buffered, the part already read is sent before the rest
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+			next.ServeHTTP(w, r)
+			return
+		}
+		_ = r.Body.Close()
+		r.ContentLength = int64(len(buf))
+		r.Body = io.NopCloser(bytes.NewReader(buf))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(buf)), nil
+		}
+		next.ServeHTTP(w, r)
+	})
+}
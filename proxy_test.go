@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReverseProxy(t *testing.T) {
+	var calls int32
+	var bodies []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("created"))
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+	proxy := httptest.NewServer(BufferBody(NewReverseProxy(target, testTransportPolicies), 1<<10))
+	defer proxy.Close()
+
+	resp, err := http.Post(proxy.URL, "text/plain", strings.NewReader("payload"))
+	assert.Equal(t, true, err == nil)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "created", string(b))
+	assert.Equal(t, []string{"payload", "payload", "payload"}, bodies)
+}
+
+func TestNewReverseProxyLargeBody(t *testing.T) {
+	var calls int32
+	var body string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+	proxy := httptest.NewServer(BufferBody(NewReverseProxy(target, testTransportPolicies), 4))
+	defer proxy.Close()
+
+	// the body is larger than the buffer so the request is sent once, and its response passed on
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL, io.NopCloser(strings.NewReader("payload")))
+	resp, err := http.DefaultClient.Do(req)
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, "payload", body)
+}
@@ -0,0 +1,239 @@
+package retry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// The settings of a Queue when they're not set
+const (
+	// DefaultPollInterval is how often Queue.Run looks for due jobs
+	DefaultPollInterval = time.Second
+	// DefaultBatchSize is how many due jobs Queue.Run loads from the store at once
+	DefaultBatchSize = 100
+)
+
+// Job is an operation retried by a Queue until it succeeds, it's saved in a JobStore between attempts
+type Job struct {
+	// ID identifies the job in its store, Enqueue generates one if empty
+	ID string
+	// Payload is what the handler needs to run the operation again, i.e: a serialized request
+	Payload []byte
+	// Policy is the name of the policy type retrying the job, see LookupPolicyType.
+	// Empty retries the job with DefaultPolicies
+	Policy string
+	// Attempts is the number of failed attempts of the job
+	Attempts int
+	// NextAttempt is when the job is run again
+	NextAttempt time.Time
+	// Delay is the delay before NextAttempt
+	Delay time.Duration
+	// LastError is the error message of the last failed attempt
+	LastError string
+	// PolicyStates is the retry accounting of each policy of the job's policy type, by index,
+	// so every policy counts its own retries against its RetryLimit and backs off from its own
+	// last delay, as in the executors. Nil before the first retry
+	PolicyStates []JobPolicyState `json:",omitempty"`
+}
+
+// JobPolicyState is the retry accounting of a policy for a Job
+type JobPolicyState struct {
+	// Retries is the number of retries the policy allowed so far
+	Retries int
+	// Delay is the delay waited before the last of them
+	Delay time.Duration
+}
+
+// JobStore saves the jobs of a Queue, so they outlive the process, i.e: in a file, BoltDB or Redis.
+// MemoryStore and FileStore are provided. Its methods may be called from multiple goroutines
+type JobStore interface {
+	// Save inserts job, or replaces the job with the same ID
+	Save(ctx context.Context, job Job) error
+	// Due returns up to limit jobs whose NextAttempt isn't after now, the earliest first
+	Due(ctx context.Context, now time.Time, limit int) ([]Job, error)
+	// Delete removes the job with the given ID, deleting a missing job isn't an error
+	Delete(ctx context.Context, id string) error
+}
+
+// Queue retries failed operations in the background, at least once, across process restarts.
+// The operations are enqueued as jobs into a JobStore, and Run hands the due ones to the handler
+// until they succeed or their policy gives up on them. A job whose handler returns an error is
+// saved again with its next attempt scheduled by the policy matching the error, as the executors do.
+// A Queue is meant to be the only worker of its store
+type Queue struct {
+	// OnGiveUp is called with the jobs that are given up on and the error of their last attempt,
+	// i.e: to alert or to move them to a dead-letter store
+	OnGiveUp func(ctx context.Context, job Job, err error)
+	// OnError is called with the due jobs that can't be run as they are, and the error saying why,
+	// i.e: their policy type isn't registered. They're kept in the store as is and tried again every
+	// PollInterval, so they resume once a process registering their policy type runs them
+	OnError func(ctx context.Context, job Job, err error)
+	// PollInterval is how often Run looks for due jobs, DefaultPollInterval if zero
+	PollInterval time.Duration
+	// BatchSize is how many due jobs are loaded from the store and run by RunDue at once, DefaultBatchSize if zero
+	BatchSize int
+
+	store   JobStore
+	handler func(ctx context.Context, job Job) error
+	clock   Clock
+	int63n  func(int64) int64
+}
+
+// NewQueue returns a Queue retrying the jobs of store with handler. The policies come from the jobs,
+// opts only set how the delays are drawn and timed, see WithRandSource and WithClock
+func NewQueue(store JobStore, handler func(ctx context.Context, job Job) error, opts ...Option) *Queue {
+	o := newOptions(opts)
+	return &Queue{
+		store:   store,
+		handler: handler,
+		clock:   o.clock,
+		int63n:  o.int63n,
+	}
+}
+
+// Enqueue saves job to be run at its NextAttempt, right away if it's zero
+func (q *Queue) Enqueue(ctx context.Context, job Job) (string, error) {
+	if job.ID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return "", err
+		}
+		job.ID = hex.EncodeToString(id)
+	}
+	if job.NextAttempt.IsZero() {
+		job.NextAttempt = q.clock.Now()
+	}
+	if err := q.store.Save(ctx, job); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// Run runs the due jobs every PollInterval until ctx is done, and returns ctx's error,
+// or the error of the store
+func (q *Queue) Run(ctx context.Context) error {
+	for {
+		if _, err := q.RunDue(ctx); err != nil {
+			return err
+		}
+		if err := q.clock.Sleep(ctx, q.pollInterval()); err != nil {
+			return err
+		}
+	}
+}
+
+// pollInterval returns PollInterval, or its default
+func (q *Queue) pollInterval() time.Duration {
+	if q.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return q.PollInterval
+}
+
+// RunDue runs the jobs that are due now once, at most BatchSize of them, and returns how many were run.
+// The jobs still due afterwards, including the ones retried without delay, are left to the next call
+func (q *Queue) RunDue(ctx context.Context) (int, error) {
+	limit := q.BatchSize
+	if limit <= 0 {
+		limit = DefaultBatchSize
+	}
+	jobs, err := q.store.Due(ctx, q.clock.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		if err := q.run(ctx, job); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// run runs job, then deletes it or saves it with its next attempt
+func (q *Queue) run(ctx context.Context, job Job) error {
+	c, err := q.policies(job)
+	if err != nil {
+		// the job can't be retried as it should, so it's parked as is rather than run or given up on
+		job.NextAttempt = q.clock.Now().Add(q.pollInterval())
+		if serr := q.store.Save(ctx, job); serr != nil {
+			return serr
+		}
+		if q.OnError != nil {
+			q.OnError(ctx, job, err)
+		}
+		return nil
+	}
+	err = q.handler(ctx, job)
+	if err == nil {
+		return q.store.Delete(ctx, job.ID)
+	}
+	job.Attempts++
+	job.LastError = unwrapStop(err).Error()
+	policies, i, ok := c.policies, -1, false
+	if !IsUnrecoverable(err) {
+		i, ok = c.match(err)
+	}
+	var states []policyState
+	if ok {
+		states = job.policyStates(len(policies), i)
+		ok = states[i].retries < policies[i].RetryLimit
+	}
+	if !ok {
+		if derr := q.store.Delete(ctx, job.ID); derr != nil {
+			return derr
+		}
+		if q.OnGiveUp != nil {
+			q.OnGiveUp(ctx, job, unwrapStop(err))
+		}
+		return nil
+	}
+	state := &states[i]
+	state.retries++
+	state.delay = policies[i].nextDelayRand(state.retries, state.delay, q.int63n)
+	job.setPolicyStates(states)
+	job.Delay = state.delay
+	job.NextAttempt = q.clock.Now().Add(job.Delay)
+	return q.store.Save(ctx, job)
+}
+
+// policies returns the policies of job, and an error if its policy type isn't registered
+func (q *Queue) policies(job Job) (*CompiledPolicies, error) {
+	if job.Policy == "" {
+		return compiledDefaults(), nil
+	}
+	policyType, ok := LookupPolicyType(job.Policy)
+	if !ok {
+		return nil, fmt.Errorf("retry: unknown policy type %q", job.Policy)
+	}
+	return compiledPolicyType(policyType), nil
+}
+
+// policyStates returns the accounting of the n policies of job. A job saved before the accounting was
+// kept per policy has its earlier retries counted against the policy at index matched, as they were
+func (job Job) policyStates(n, matched int) []policyState {
+	states := make([]policyState, n)
+	if job.PolicyStates == nil && job.Attempts > 1 {
+		states[matched] = policyState{retries: job.Attempts - 1, delay: job.Delay}
+		return states
+	}
+	for i := 0; i < n && i < len(job.PolicyStates); i++ {
+		states[i] = policyState{retries: job.PolicyStates[i].Retries, delay: job.PolicyStates[i].Delay}
+	}
+	return states
+}
+
+// setPolicyStates saves states as the accounting of job
+func (job *Job) setPolicyStates(states []policyState) {
+	job.PolicyStates = make([]JobPolicyState, len(states))
+	for i, s := range states {
+		job.PolicyStates[i] = JobPolicyState{Retries: s.retries, Delay: s.delay}
+	}
+}
@@ -0,0 +1,220 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	RegisterPolicyType("test-queue", []Policy{{DelayDuration: time.Second, RetryLimit: 2, Backoff: LinearBackoff}})
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	calls := map[string]int{}
+	q := NewQueue(store, func(ctx context.Context, job Job) error {
+		calls[string(job.Payload)]++
+		if string(job.Payload) == "ok" && calls["ok"] == 2 {
+			return nil
+		}
+		return errTestSentinel
+	})
+	q.clock = clock
+	var given []Job
+	q.OnGiveUp = func(ctx context.Context, job Job, err error) {
+		assert.Equal(t, errTestSentinel, err)
+		given = append(given, job)
+	}
+	ctx := context.Background()
+	_, err := q.Enqueue(ctx, Job{ID: "ok", Payload: []byte("ok"), Policy: "test-queue"})
+	assert.Equal(t, true, err == nil)
+	_, err = q.Enqueue(ctx, Job{ID: "ko", Payload: []byte("ko"), Policy: "test-queue"})
+	assert.Equal(t, true, err == nil)
+
+	n, err := q.RunDue(ctx)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 2, n)
+	jobs := store.Jobs()
+	assert.Equal(t, 2, len(jobs))
+	assert.Equal(t, 1, jobs[0].Attempts)
+	assert.Equal(t, "sentinel", jobs[0].LastError)
+	assert.Equal(t, clock.now.Add(time.Second), jobs[0].NextAttempt)
+
+	// nothing is due before the delay
+	n, _ = q.RunDue(ctx)
+	assert.Equal(t, 0, n)
+	clock.now = clock.now.Add(time.Second)
+	n, _ = q.RunDue(ctx)
+	assert.Equal(t, 2, n)
+	jobs = store.Jobs()
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, "ko", jobs[0].ID)
+	assert.Equal(t, time.Second*2, jobs[0].Delay)
+
+	// the third failure exhausts the retry limit
+	clock.now = clock.now.Add(time.Second * 2)
+	_, _ = q.RunDue(ctx)
+	assert.Equal(t, 0, len(store.Jobs()))
+	assert.Equal(t, 1, len(given))
+	assert.Equal(t, 3, given[0].Attempts)
+	assert.Equal(t, 3, calls["ko"])
+}
+
+func TestQueueRandSource(t *testing.T) {
+	policy := Policy{DelayDuration: time.Second, RetryLimit: 2, Backoff: ExponentialBackoff, Jitter: FullJitter}
+	RegisterPolicyType("test-queue-rand", []Policy{policy})
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	q := NewQueue(store, func(ctx context.Context, job Job) error {
+		return errTestSentinel
+	}, WithRandSource(rand.NewSource(1)), WithClock(clock))
+	ctx := context.Background()
+	_, err := q.Enqueue(ctx, Job{ID: "ko", Policy: "test-queue-rand"})
+	assert.Equal(t, true, err == nil)
+
+	var delays []time.Duration
+	for i := 0; i < 2; i++ {
+		clock.now = clock.now.Add(time.Hour)
+		_, _ = q.RunDue(ctx)
+		delays = append(delays, store.Jobs()[0].Delay)
+	}
+	assert.Equal(t, policy.JitteredSchedule(2, rand.NewSource(1)), delays)
+}
+
+func TestQueuePolicyStates(t *testing.T) {
+	errShort, errLong := errors.New("short"), errors.New("long")
+	RegisterPolicyType("test-queue-states", []Policy{
+		{MatchError: errShort, DelayDuration: time.Second, RetryLimit: 1},
+		{MatchError: errLong, DelayDuration: time.Minute, RetryLimit: 3, Backoff: LinearBackoff},
+	})
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "jobs.json"))
+	assert.Equal(t, true, err == nil)
+	errs := []error{errShort, errLong, errLong, errLong, errLong}
+	var calls int
+	q := NewQueue(store, func(ctx context.Context, job Job) error {
+		calls++
+		return errs[calls-1]
+	}, WithClock(clock))
+	var given []Job
+	q.OnGiveUp = func(ctx context.Context, job Job, err error) {
+		given = append(given, job)
+	}
+	ctx := context.Background()
+	_, err = q.Enqueue(ctx, Job{ID: "job", Policy: "test-queue-states"})
+	assert.Equal(t, true, err == nil)
+
+	// each policy counts its own retries, the retry of errShort doesn't use up the ones of errLong
+	var delays []time.Duration
+	for i := 0; i < 4; i++ {
+		_, _ = q.RunDue(ctx)
+		jobs, _ := store.Due(ctx, clock.now.Add(time.Hour), 10)
+		assert.Equal(t, 1, len(jobs))
+		delays = append(delays, jobs[0].Delay)
+		clock.now = jobs[0].NextAttempt
+	}
+	assert.Equal(t, []time.Duration{time.Second, time.Minute, time.Minute * 2, time.Minute * 3}, delays)
+
+	// the accounting survives a restart of the store
+	store, err = NewFileStore(store.path)
+	assert.Equal(t, true, err == nil)
+	jobs, _ := store.Due(ctx, clock.now, 10)
+	assert.Equal(t, []JobPolicyState{{Retries: 1, Delay: time.Second}, {Retries: 3, Delay: time.Minute * 3}}, jobs[0].PolicyStates)
+	q.store = store
+	_, _ = q.RunDue(ctx)
+	assert.Equal(t, 1, len(given))
+	assert.Equal(t, 5, given[0].Attempts)
+}
+
+func TestQueueLegacyJob(t *testing.T) {
+	RegisterPolicyType("test-queue-legacy", []Policy{{DelayDuration: time.Second, RetryLimit: 2, Backoff: LinearBackoff}})
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	q := NewQueue(store, func(ctx context.Context, job Job) error {
+		return errTestSentinel
+	}, WithClock(clock))
+	// a job saved without PolicyStates after one retry
+	_, err := q.Enqueue(context.Background(), Job{ID: "job", Policy: "test-queue-legacy", Attempts: 1, Delay: time.Second})
+	assert.Equal(t, true, err == nil)
+	_, _ = q.RunDue(context.Background())
+	jobs := store.Jobs()
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, time.Second*2, jobs[0].Delay)
+	assert.Equal(t, []JobPolicyState{{Retries: 2, Delay: time.Second * 2}}, jobs[0].PolicyStates)
+}
+
+func TestQueueUnknownPolicy(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	q := NewQueue(store, func(ctx context.Context, job Job) error {
+		return errTestSentinel
+	}, WithClock(clock))
+	q.OnGiveUp = func(ctx context.Context, job Job, err error) {
+		t.Error("a job whose policy type isn't registered is given up on")
+	}
+	var reported error
+	q.OnError = func(ctx context.Context, job Job, err error) {
+		reported = err
+	}
+	_, err := q.Enqueue(context.Background(), Job{ID: "job", Policy: "test-queue-unknown"})
+	assert.Equal(t, true, err == nil)
+	_, err = q.RunDue(context.Background())
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, `retry: unknown policy type "test-queue-unknown"`, reported.Error())
+	// the job is kept as is without being run, and tried again after PollInterval
+	jobs := store.Jobs()
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, 0, jobs[0].Attempts)
+	assert.Equal(t, clock.now.Add(DefaultPollInterval), jobs[0].NextAttempt)
+
+	// once the policy type is registered, the job is retried by it
+	RegisterPolicyType("test-queue-unknown", []Policy{{DelayDuration: time.Second, RetryLimit: 1}})
+	clock.now = clock.now.Add(DefaultPollInterval)
+	n, err := q.RunDue(context.Background())
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 1, n)
+	jobs = store.Jobs()
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, 1, jobs[0].Attempts)
+	assert.Equal(t, time.Second, jobs[0].Delay)
+}
+
+func TestQueueRunDueBatch(t *testing.T) {
+	RegisterPolicyType("test-queue-batch", []Policy{{RetryLimit: 1000}})
+	store := NewMemoryStore()
+	q := NewQueue(store, func(ctx context.Context, job Job) error {
+		return errTestSentinel
+	})
+	q.BatchSize = 2
+	for i := 0; i < 3; i++ {
+		_, err := q.Enqueue(context.Background(), Job{Policy: "test-queue-batch"})
+		assert.Equal(t, true, err == nil)
+	}
+	// the jobs retried without delay are due again right away, they're left to the next call
+	n, err := q.RunDue(context.Background())
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 2, n)
+	attempts := 0
+	for _, job := range store.Jobs() {
+		attempts += job.Attempts
+	}
+	assert.Equal(t, 2, attempts)
+}
+
+func TestQueueRun(t *testing.T) {
+	store := NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	q := NewQueue(store, func(ctx context.Context, job Job) error {
+		cancel()
+		return nil
+	})
+	q.PollInterval = time.Millisecond
+	_, err := q.Enqueue(ctx, Job{})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, context.Canceled, q.Run(ctx))
+	assert.Equal(t, 0, len(store.Jobs()))
+}
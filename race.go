@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"context"
+)
+
+// ExecutorRace runs the alternatives fns concurrently, i.e: the same call against different replicas
+// or regions, and returns nil as soon as one of them succeeds. The context of the others is then
+// cancelled with the ErrRaceWon cause. If they all fail, the one that failed first is retried alone
+// according to DefaultPolicies, its failure in the race counting as its first attempt.
+// If ctx is done during the race, ctx.Err() is returned without waiting for the alternatives
+func ExecutorRace(ctx context.Context, fns ...FuncContext) error {
+	return executeRace(ctx, fns, withCompiled(compiledDefaults(), nil))
+}
+
+// ExecutorRaceWithPolicies is ExecutorRace retrying the fastest failing alternative according to
+// retryPolicies and opts, as ExecutorWithPoliciesContext does
+func ExecutorRaceWithPolicies(ctx context.Context, retryPolicies []Policy, fns []FuncContext, opts ...Option) error {
+	return executeRace(ctx, fns, withPolicies(retryPolicies, opts))
+}
+
+// executeRace runs the race of fns, and retries the fastest failing one as Do does with opts
+func executeRace(ctx context.Context, fns []FuncContext, opts []Option) error {
+	if len(fns) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	raceCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	type outcome struct {
+		index int
+		err   error
+	}
+	results := make(chan outcome, len(fns))
+	for i, fn := range fns {
+		go func(i int, fn FuncContext) {
+			results <- outcome{index: i, err: fn(raceCtx)}
+		}(i, fn)
+	}
+	first := outcome{index: -1}
+	for range fns {
+		select {
+		case out := <-results:
+			if out.err == nil {
+				cancel(ErrRaceWon)
+				return nil
+			}
+			if first.index < 0 {
+				first = out
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// every alternative failed, the fastest failing one is retried
+	fn := fns[first.index]
+	raced := false
+	return Do(ctx, func(ctx context.Context) error {
+		if !raced {
+			raced = true
+			return first.err
+		}
+		return fn(ctx)
+	}, opts...)
+}
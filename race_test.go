@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutorRace(t *testing.T) {
+	causes := make(chan error, 1)
+	err := ExecutorRace(context.Background(),
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return ctx.Err()
+		},
+		func(ctx context.Context) error {
+			return nil
+		},
+	)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, ErrRaceWon, <-causes)
+}
+
+func TestExecutorRaceFallback(t *testing.T) {
+	errFast := errors.New("fast timed out")
+	var fast, slow int32
+	policies := []Policy{{ErrorCodeString: "timed out", DelayDuration: time.Millisecond, RetryLimit: 2}}
+	err := ExecutorRaceWithPolicies(context.Background(), policies, []FuncContext{
+		func(ctx context.Context) error {
+			time.Sleep(time.Millisecond * 20)
+			atomic.AddInt32(&slow, 1)
+			return errors.New("slow timed out")
+		},
+		func(ctx context.Context) error {
+			if atomic.AddInt32(&fast, 1) < 3 {
+				return errFast
+			}
+			return nil
+		},
+	})
+	assert.Equal(t, true, err == nil)
+	// the race is the first attempt of the fastest failing alternative
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fast))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slow))
+
+	err = ExecutorRaceWithPolicies(context.Background(), policies, []FuncContext{
+		func(ctx context.Context) error {
+			return errFast
+		},
+	})
+	assert.Equal(t, &ExhaustedError{Attempts: 3, LastErr: errFast}, err)
+}
+
+func TestExecutorRaceContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	block := make(chan struct{})
+	defer close(block)
+	err := ExecutorRace(ctx, func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
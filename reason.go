@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Reason is a typed code telling why an attempt failed, see ReasonOf, or why an operation isn't retried,
+// see Decision.Reason, so automation can branch on the why rather than on error messages
+type Reason string
+
+// The reasons of a failure, see ReasonOf. The failed responses have the reason of their status, see StatusReason
+const (
+	ReasonStatus429       Reason = "status 429"
+	ReasonStatus500       Reason = "status 500"
+	ReasonStatus502       Reason = "status 502"
+	ReasonStatus503       Reason = "status 503"
+	ReasonStatus504       Reason = "status 504"
+	ReasonTimeout         Reason = "timeout"
+	ReasonConnRefused     Reason = "connection refused"
+	ReasonConnReset       Reason = "connection reset"
+	ReasonTransport       Reason = "transport error"
+	ReasonBreakerOpen     Reason = "breaker open"
+	ReasonRetryableResult Reason = "retryable result"
+	// ReasonError is any other error
+	ReasonError Reason = "error"
+)
+
+// StatusReason returns the reason of a failed response with the given status code, i.e: ReasonStatus503
+func StatusReason(code int) Reason {
+	return Reason(fmt.Sprintf("status %d", code))
+}
+
+// Reasoner is implemented by the errors that know their Reason, ReasonOf returns it
+type Reasoner interface {
+	RetryReason() Reason
+}
+
+// ReasonOf classifies err, the error of an attempt, of an Event, or returned by an executor. The errors
+// wrapped by the executors when they give up, such as ErrRetryBudgetExhausted, prevail over the error
+// they wrap. It returns an empty Reason for a nil err
+func ReasonOf(err error) Reason {
+	if err == nil {
+		return ""
+	}
+	var r Reasoner
+	if errors.As(err, &r) {
+		return r.RetryReason()
+	}
+	for _, sentinel := range reasonSentinels {
+		if errors.Is(err, sentinel.err) {
+			return sentinel.reason
+		}
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return StatusReason(se.resp.StatusCode)
+	}
+	var netErr net.Error
+	if errors.Is(err, ErrAttemptTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return ReasonTimeout
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ReasonCanceled
+	case IsConnRefused(err):
+		return ReasonConnRefused
+	case IsConnReset(err):
+		return ReasonConnReset
+	case IsTransportError(err):
+		return ReasonTransport
+	}
+	return ReasonError
+}
+
+// reasonSentinels are the errors the executors wrap the error of the last attempt with, and their reason
+var reasonSentinels = []struct {
+	err    error
+	reason Reason
+}{
+	{ErrRetryBudgetExhausted, ReasonBudgetExhausted},
+	{ErrBreakerOpen, ReasonBreakerOpen},
+	{ErrMaxElapsedTime, ReasonMaxElapsedTime},
+	{ErrDeadlineWouldExceed, ReasonDeadlineWouldExceed},
+	{ErrMaxConcurrentRetries, ReasonMaxConcurrentRetries},
+	{ErrStopped, ReasonStopped},
+	{ErrRetryerClosed, ReasonRetryerClosed},
+	{ErrRetryableResult, ReasonRetryableResult},
+}
+
+// causeReason returns the reason of err, returned by a wait on ctx, from the cause of ctx if it's done,
+// i.e: ReasonMaxElapsedTime
+func causeReason(ctx context.Context, err error) Reason {
+	if cause := context.Cause(ctx); cause != nil {
+		return ReasonOf(cause)
+	}
+	return ReasonOf(err)
+}
@@ -0,0 +1,109 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reasonedError struct{}
+
+func (reasonedError) Error() string       { return "quota" }
+func (reasonedError) RetryReason() Reason { return "quota" }
+
+func TestReasonOf(t *testing.T) {
+	status := func(code int) error {
+		return &statusError{resp: &http.Response{StatusCode: code, Status: http.StatusText(code)}}
+	}
+	for _, tc := range []struct {
+		err  error
+		want Reason
+	}{
+		{nil, ""},
+		{errTestSentinel, ReasonError},
+		{status(503), ReasonStatus503},
+		{status(429), ReasonStatus429},
+		{status(418), Reason("status 418")},
+		{fmt.Errorf("wrapped: %w", status(502)), ReasonStatus502},
+		{budgetExhaustedError(status(503)), ReasonBudgetExhausted},
+		{fmt.Errorf("%w: %w", ErrBreakerOpen, errTestSentinel), ReasonBreakerOpen},
+		{fmt.Errorf("%w: %w", ErrDeadlineWouldExceed, status(500)), ReasonDeadlineWouldExceed},
+		{fmt.Errorf("%w: %w", ErrStopped, errTestSentinel), ReasonStopped},
+		{ErrRetryableResult, ReasonRetryableResult},
+		{ErrAttemptTimeout, ReasonTimeout},
+		{context.DeadlineExceeded, ReasonTimeout},
+		{&net.DNSError{Err: "i/o timeout", IsTimeout: true}, ReasonTimeout},
+		{context.Canceled, ReasonCanceled},
+		{&TransportError{Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, ReasonConnRefused},
+		{&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, ReasonConnReset},
+		{&TransportError{Err: errTestSentinel}, ReasonTransport},
+		{&ExhaustedError{Attempts: 3, LastErr: status(504)}, ReasonStatus504},
+		{fmt.Errorf("wrapped: %w", reasonedError{}), "quota"},
+	} {
+		assert.Equal(t, tc.want, ReasonOf(tc.err), fmt.Sprint(tc.err))
+	}
+	assert.Equal(t, ReasonStatus500, StatusReason(500))
+}
+
+func TestReasonStopDecision(t *testing.T) {
+	fail := func(ctx context.Context) error { return errTestSentinel }
+	policies := []Policy{{MatchError: errTestSentinel, DelayDuration: time.Millisecond, RetryLimit: 3}}
+
+	budget := NewRetryBudget(0.1, 1)
+	res, err := DoResult(context.Background(), fail, WithPolicies(policies), WithRetryBudget(budget))
+	assert.Equal(t, ReasonBudgetExhausted, ReasonOf(err))
+	assert.Equal(t, false, res.LastDecision.Retry)
+	assert.Equal(t, ReasonBudgetExhausted, res.LastDecision.Reason)
+
+	res, err = DoResult(context.Background(), fail, WithPolicies(policies), WithMaxElapsedTime(time.Millisecond*2))
+	assert.Equal(t, ReasonMaxElapsedTime, res.LastDecision.Reason)
+	assert.Equal(t, true, errors.Is(err, errTestSentinel))
+
+	var calls int
+	res, err = DoResult(context.Background(), fail, WithPolicies(policies), WithStopFunc(func() bool {
+		calls++
+		return calls > 1
+	}))
+	assert.Equal(t, ReasonStopped, ReasonOf(err))
+	assert.Equal(t, ReasonStopped, res.LastDecision.Reason)
+
+	res, _ = DoResult(context.Background(), fail, WithPolicies(policies))
+	assert.Equal(t, ReasonLimitReached, res.LastDecision.Reason)
+}
+
+func TestReasonEvents(t *testing.T) {
+	r := NewRetryer(WithPolicies([]Policy{{StatusCodes: []int{503}, DelayDuration: time.Millisecond, RetryLimit: 1}}))
+	events := r.Subscribe()
+	_ = r.Run(context.Background(), func(ctx context.Context) error {
+		return &statusError{resp: &http.Response{StatusCode: 503, Status: "503 Service Unavailable"}}
+	})
+	r.Unsubscribe(events)
+	var reasons []Reason
+	for e := range events {
+		reasons = append(reasons, e.Reason)
+	}
+	assert.Equal(t, []Reason{"", ReasonStatus503, "", "", ReasonStatus503, ReasonStatus503}, reasons)
+}
+
+func TestReasonTransportBreakerOpen(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+	breakers := NewHostBreakers(1, time.Minute)
+	client := &http.Client{Transport: &Transport{Policies: []Policy{{MatchErrorType: IsTransportError, RetryLimit: 1}}, Breakers: breakers}}
+
+	_, err := client.Get(url)
+	assert.Equal(t, ReasonConnRefused, ReasonOf(err))
+	// the breaker of the host is open now
+	_, err = client.Get(url)
+	assert.Equal(t, ReasonBreakerOpen, ReasonOf(err))
+	assert.Equal(t, true, IsConnRefused(err))
+}
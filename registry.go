@@ -0,0 +1,129 @@
+package retry
+
+import (
+	"fmt"
+	"sync"
+)
+
+// firstCustomPolicyType is the first PolicyType handed out by RegisterPolicyType,
+// so registered types never collide with the built-in ones
+const firstCustomPolicyType PolicyType = 1 << 10
+
+var registry = struct {
+	sync.RWMutex
+	types    map[string]PolicyType
+	names    map[PolicyType]string
+	policies map[PolicyType][]Policy
+	// compiled caches the compiled policies of the types, see compiledPolicyType
+	compiled map[PolicyType]*CompiledPolicies
+	next     PolicyType
+}{
+	types: map[string]PolicyType{
+		"http":        HTTPPolicy,
+		"standard":    StandardPolicy,
+		"strict-http": StrictHTTPPolicy,
+		"network":     NetworkPolicy,
+		"database":    DatabasePolicy,
+		"cloud":       CloudThrottlePolicy,
+		"reconnect":   ReconnectPolicy,
+		"io":          IOPolicy,
+	},
+	names: map[PolicyType]string{
+		HTTPPolicy:          "http",
+		StandardPolicy:      "standard",
+		StrictHTTPPolicy:    "strict-http",
+		NetworkPolicy:       "network",
+		DatabasePolicy:      "database",
+		CloudThrottlePolicy: "cloud",
+		ReconnectPolicy:     "reconnect",
+		IOPolicy:            "io",
+	},
+	policies: map[PolicyType][]Policy{},
+	compiled: map[PolicyType]*CompiledPolicies{},
+	next:     firstCustomPolicyType,
+}
+
+// RegisterPolicyType registers policies under name and returns the PolicyType that selects them,
+// so they can be used with GetRetryPolicies, WithPolicyType and the Executor functions.
+// Registering a name again replaces its policies and keeps its PolicyType, which also allows
+// replacing the policies of the built-in types.
+// It's safe to call from multiple goroutines
+func RegisterPolicyType(name string, policies []Policy) PolicyType {
+	registry.Lock()
+	defer registry.Unlock()
+	policyType, ok := registry.types[name]
+	if !ok {
+		policyType = registry.next
+		registry.next++
+		registry.types[name] = policyType
+		registry.names[policyType] = name
+	}
+	registry.policies[policyType] = append([]Policy(nil), policies...)
+	delete(registry.compiled, policyType)
+	return policyType
+}
+
+// compiledPolicyType returns GetRetryPolicies(policyType) compiled, the policies of a type are compiled
+// once and again when the type is registered again. It's what the executors given a PolicyType use
+func compiledPolicyType(policyType PolicyType) *CompiledPolicies {
+	registry.RLock()
+	c, ok := registry.compiled[policyType]
+	registry.RUnlock()
+	if ok {
+		return c
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if c, ok := registry.compiled[policyType]; ok {
+		return c
+	}
+	policies, ok := registry.policies[policyType]
+	if !ok {
+		policies = builtinPolicies(policyType)
+	}
+	c = CompilePolicies(policies)
+	if ok || policies != nil {
+		// an unknown type isn't cached, so the cache doesn't grow with them
+		registry.compiled[policyType] = c
+	}
+	return c
+}
+
+// LookupPolicyType returns the PolicyType registered under name, built-in types are registered
+// as "http", "standard", "strict-http", "network", "database", "cloud", "reconnect" and "io"
+func LookupPolicyType(name string) (PolicyType, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	policyType, ok := registry.types[name]
+	return policyType, ok
+}
+
+// GetRetryPoliciesByName returns the policies of the PolicyType registered under name
+func GetRetryPoliciesByName(name string) ([]Policy, bool) {
+	policyType, ok := LookupPolicyType(name)
+	if !ok {
+		return nil, false
+	}
+	return GetRetryPolicies(policyType), true
+}
+
+// String returns the name the policy type is registered under
+func (p PolicyType) String() string {
+	registry.RLock()
+	defer registry.RUnlock()
+	if name, ok := registry.names[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("PolicyType(%d)", int(p))
+}
+
+// registeredPolicies returns a copy of the policies registered for policyType
+func registeredPolicies(policyType PolicyType) ([]Policy, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	policies, ok := registry.policies[policyType]
+	if !ok {
+		return nil, false
+	}
+	return append([]Policy(nil), policies...), true
+}
@@ -0,0 +1,81 @@
+package retry
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterPolicyType(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeString: "deadlock",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      5,
+		},
+	}
+	database := RegisterPolicyType("test-database", policies)
+	assert.Equal(t, true, database >= firstCustomPolicyType)
+	assert.Equal(t, "test-database", database.String())
+	assert.Equal(t, policies, GetRetryPolicies(database))
+
+	lookedUp, ok := LookupPolicyType("test-database")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, database, lookedUp)
+
+	byName, ok := GetRetryPoliciesByName("test-database")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, policies, byName)
+
+	// registering again replaces the policies but keeps the type
+	policies[0].RetryLimit = 1
+	assert.Equal(t, 5, GetRetryPolicies(database)[0].RetryLimit)
+	assert.Equal(t, database, RegisterPolicyType("test-database", policies))
+	assert.Equal(t, 1, GetRetryPolicies(database)[0].RetryLimit)
+}
+
+func TestLookupPolicyTypeBuiltin(t *testing.T) {
+	policyType, ok := LookupPolicyType("http")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, HTTPPolicy, policyType)
+	assert.Equal(t, "standard", StandardPolicy.String())
+
+	_, ok = LookupPolicyType("unknown")
+	assert.Equal(t, false, ok)
+	_, ok = GetRetryPoliciesByName("unknown")
+	assert.Equal(t, false, ok)
+}
+
+func TestRegisterPolicyTypeConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("test-concurrent-%d", i%5)
+			policyType := RegisterPolicyType(name, []Policy{{ErrorCodeString: name}})
+			assert.Equal(t, name, GetRetryPolicies(policyType)[0].ErrorCodeString)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestExecutorWithRegisteredPolicyType(t *testing.T) {
+	grpc := RegisterPolicyType("test-grpc", []Policy{
+		{
+			ErrorCodeString: "unavailable",
+			DelayDuration:   time.Millisecond * 10,
+			RetryLimit:      2,
+		},
+	})
+	var calls int
+	err := ExecutorWithPolicyType(grpc, func() error {
+		calls++
+		return fmt.Errorf("rpc error: code = Unavailable")
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, calls)
+}
@@ -0,0 +1,47 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Result reports how a retry went, see DoResult
+type Result struct {
+	// Attempts is the number of attempts made, zero if ctx was already done
+	Attempts int
+	// TotalDelay is the sum of the delays waited between the attempts
+	TotalDelay time.Duration
+	// LastError is the error of the last attempt, nil if it succeeded
+	LastError error
+	// PerAttemptErrors holds every failed attempt in order
+	PerAttemptErrors []AttemptError
+	// LastDecision is the decision for the last failed attempt, with the policy matching its error
+	// and the reason it wasn't retried, empty if a timing option or the budget stopped the retry.
+	// Zero if no attempt failed
+	LastDecision Decision
+	// StartedAt is when the retry started
+	StartedAt time.Time
+	// EndedAt is when the retry returned
+	EndedAt time.Time
+}
+
+// Duration is how long the retry took
+func (r Result) Duration() time.Duration {
+	return r.EndedAt.Sub(r.StartedAt)
+}
+
+// DoResult is like Do, and also returns how the retry went, whether it succeeded or not
+func DoResult(ctx context.Context, fn FuncContext, opts ...Option) (Result, error) {
+	var res Result
+	_, err := executeResult(ctx, newOptions(opts), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, &res)
+	return res, err
+}
+
+// ExecutorTResult is the generic version of DoResult
+func ExecutorTResult[T any](ctx context.Context, fn FuncTContext[T], opts ...Option) (T, Result, error) {
+	var res Result
+	value, err := executeResult(ctx, newOptions(opts), fn, &res)
+	return value, res, err
+}
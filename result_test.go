@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClock advances on Sleep instead of waiting, see retrytest.FakeClock
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+func TestDoResult(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var calls int
+	res, err := DoResult(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTestSentinel
+		}
+		return nil
+	}, WithDelay(time.Second), WithBackoff(LinearBackoff), WithClock(clock))
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, res.Attempts)
+	assert.Equal(t, time.Second*3, res.TotalDelay)
+	assert.Equal(t, nil, res.LastError)
+	assert.Equal(t, 2, len(res.PerAttemptErrors))
+	assert.Equal(t, 2, res.PerAttemptErrors[1].Attempt)
+	assert.Equal(t, time.Second*3, res.Duration())
+}
+
+func TestDoResultExhausted(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	res, err := DoResult(context.Background(), func(ctx context.Context) error {
+		return errTestSentinel
+	}, WithAttempts(2), WithClock(clock))
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: errTestSentinel}, err)
+	assert.Equal(t, 2, res.Attempts)
+	assert.Equal(t, DefaultDelay, res.TotalDelay)
+	assert.Equal(t, errTestSentinel, res.LastError)
+	assert.Equal(t, 2, len(res.PerAttemptErrors))
+}
+
+func TestDoResultCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := DoResult(ctx, func(ctx context.Context) error {
+		return nil
+	})
+	assert.Equal(t, true, errors.Is(err, context.Canceled))
+	assert.Equal(t, 0, res.Attempts)
+}
+
+func TestExecutorTResult(t *testing.T) {
+	value, res, err := ExecutorTResult(context.Background(), func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 42, value)
+	assert.Equal(t, 1, res.Attempts)
+	assert.Equal(t, 0, len(res.PerAttemptErrors))
+}
@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxRetryAfter is the default cap of a delay taken from a Retry-After header
+const DefaultMaxRetryAfter = time.Minute
+
+// WithMaxRetryAfter caps the delay the HTTP executors take from the Retry-After header
+// of a 429 or 503 response, default is DefaultMaxRetryAfter.
+// A zero or negative max ignores Retry-After and always uses the policy delay
+func WithMaxRetryAfter(max time.Duration) Option {
+	return func(o *options) {
+		o.maxRetryAfter = max
+	}
+}
+
+// retryAfterDelay returns the delay requested by the server through the Retry-After header,
+// if err is a 429 or 503 HTTP status failure carrying a valid one
+func retryAfterDelay(err error, max time.Duration, now time.Time) (time.Duration, bool) {
+	var se *statusError
+	if max <= 0 || !errors.As(err, &se) {
+		return 0, false
+	}
+	if se.resp.StatusCode != http.StatusTooManyRequests && se.resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	delay, ok := parseRetryAfter(se.resp.Header.Get("Retry-After"), now)
+	if !ok {
+		return 0, false
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay, true
+}
+
+// parseRetryAfter parses a Retry-After header value, either in delay-seconds or in HTTP-date form.
+// A date in the past results in a zero delay
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		if seconds > int64(maxDuration/time.Second) {
+			return maxDuration, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	delay := date.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfterSeconds(t *testing.T) {
+	d, ok := parseRetryAfter("120", time.Now())
+	assert.Equal(t, true, ok)
+	assert.Equal(t, time.Minute*2, d)
+
+	_, ok = parseRetryAfter("-1", time.Now())
+	assert.Equal(t, false, ok)
+
+	_, ok = parseRetryAfter("soon", time.Now())
+	assert.Equal(t, false, ok)
+
+	_, ok = parseRetryAfter("", time.Now())
+	assert.Equal(t, false, ok)
+}
+
+func TestParseRetryAfterDate(t *testing.T) {
+	now := time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC)
+	d, ok := parseRetryAfter("Wed, 01 Jan 2020 10:00:30 GMT", now)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, time.Second*30, d)
+
+	// a date in the past means retry right away
+	d, ok = parseRetryAfter("Wed, 01 Jan 2020 09:00:00 GMT", now)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, time.Duration(0), d)
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"10"}}}
+	d, ok := retryAfterDelay(&statusError{resp: resp}, time.Minute, time.Now())
+	assert.Equal(t, true, ok)
+	assert.Equal(t, time.Second*10, d)
+
+	// capped by max
+	d, ok = retryAfterDelay(&statusError{resp: resp}, time.Second*3, time.Now())
+	assert.Equal(t, true, ok)
+	assert.Equal(t, time.Second*3, d)
+
+	// ignored when disabled
+	_, ok = retryAfterDelay(&statusError{resp: resp}, 0, time.Now())
+	assert.Equal(t, false, ok)
+
+	// only 429 and 503 are honored
+	resp.StatusCode = http.StatusBadGateway
+	_, ok = retryAfterDelay(&statusError{resp: resp}, time.Minute, time.Now())
+	assert.Equal(t, false, ok)
+}
+
+func TestExecutorHTTPWithPoliciesContextRetryAfter(t *testing.T) {
+	policies := []Policy{
+		{
+			ErrorCodeNumber: http.StatusServiceUnavailable,
+			ErrorCodeString: http.StatusText(http.StatusServiceUnavailable),
+			DelayDuration:   time.Hour,
+			RetryLimit:      3,
+		},
+	}
+	var calls int
+	var delays []time.Duration
+	err := ExecutorHTTPWithPoliciesContext(context.Background(), policies, func(ctx context.Context) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Status:     http.StatusText(http.StatusServiceUnavailable),
+				Header:     http.Header{"Retry-After": []string{"0"}},
+			}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Status: http.StatusText(http.StatusOK)}, nil
+	}, WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		delays = append(delays, nextDelay)
+	}))
+	// the server asked to retry right away, so the hour long policy delay is not used
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, []time.Duration{0}, delays)
+}
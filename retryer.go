@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// ExecutorIface is the interface of Retryer, so the code using it can be given a test double
+// such as retrytest.NoRetry or retrytest.AlwaysFail
+type ExecutorIface interface {
+	// Run executes fn and retries it, see Retryer.Run
+	Run(ctx context.Context, fn FuncContext) error
+	// RunHTTP executes fn and retries it, see Retryer.RunHTTP
+	RunHTTP(ctx context.Context, fn FuncHTTPContext) error
+	// RunHTTPResponse executes fn, retries it and returns the successful response, see Retryer.RunHTTPResponse
+	RunHTTPResponse(ctx context.Context, fn FuncHTTPContext) (*http.Response, error)
+}
+
+var _ ExecutorIface = (*Retryer)(nil)
+
+// Retryer retries operations with a configuration that is built once and shared by every call.
+// A Retryer is safe for concurrent use by multiple goroutines
+type Retryer struct {
+	opts *options
+
+	mu     sync.Mutex
+	closed bool
+	// running counts the operations in flight, see Close
+	running sync.WaitGroup
+	// shutdown is cancelled when Close gives up waiting, which gives up on the operations in flight
+	shutdown context.Context
+	abort    context.CancelCauseFunc
+}
+
+// NewRetryer returns a Retryer configured by opts, see Do for the defaults
+func NewRetryer(opts ...Option) *Retryer {
+	o := newOptions(opts)
+	if o.matcher == nil {
+		o.matcher = CompilePolicies(o.policies)
+	}
+	o.events = newEventHub()
+	for _, b := range o.breakerEvents {
+		b.watch(o.events)
+	}
+	o.stats = newPolicyStats()
+	o.burn = newBurnTracker(o.burnAlerts)
+	r := &Retryer{opts: o}
+	r.shutdown, r.abort = context.WithCancelCause(context.Background())
+	o.shutdown = r.shutdown
+	return r
+}
+
+// Run executes fn, inspect the error, and do retry as configured by the Retryer
+func (r *Retryer) Run(ctx context.Context, fn FuncContext) error {
+	if !r.enter() {
+		return ErrRetryerClosed
+	}
+	defer r.running.Done()
+	return executeVoid(ctx, r.opts, fn)
+}
+
+// RunHTTP executes fn, inspect the http response, and do retry as configured by the Retryer
+func (r *Retryer) RunHTTP(ctx context.Context, fn FuncHTTPContext) error {
+	if !r.enter() {
+		return ErrRetryerClosed
+	}
+	defer r.running.Done()
+	_, err := executeHTTP(ctx, false, r.opts, fn)
+	return err
+}
+
+// RunHTTPResponse is like RunHTTP but returns the successful response, see ExecutorHTTPResponse
+func (r *Retryer) RunHTTPResponse(ctx context.Context, fn FuncHTTPContext) (*http.Response, error) {
+	if !r.enter() {
+		return nil, ErrRetryerClosed
+	}
+	defer r.running.Done()
+	return executeHTTP(ctx, true, r.opts, fn)
+}
+
+// Close stops the Retryer: the operations started afterwards fail right away with ErrRetryerClosed,
+// and Close waits for the ones in flight to finish, then closes the channels of the subscribers.
+// If ctx is done first, the operations in flight are given up on once their current attempt returns:
+// their sleeps are cut short and wrap the error of their last attempt with ErrRetryerClosed, the ones
+// parked by Go are cancelled. The channels of the subscribers are closed and ctx's error is returned
+// without waiting for them any longer, their last events are dropped.
+// A Scheduler set with WithScheduler is left running, since it may be shared
+func (r *Retryer) Close(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		r.closeEvents()
+		return nil
+	case <-ctx.Done():
+		r.abort(ErrRetryerClosed)
+		r.closeEvents()
+		return ctx.Err()
+	}
+}
+
+// closeEvents stops the breaker events and closes the channels of the subscribers
+func (r *Retryer) closeEvents() {
+	for _, b := range r.opts.breakerEvents {
+		b.unwatch(r.opts.events)
+	}
+	r.opts.events.close()
+}
+
+// enter registers an operation in flight, it reports false once the Retryer is closed.
+// The operation calls r.running.Done once it's finished
+func (r *Retryer) enter() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	r.running.Add(1)
+	return true
+}
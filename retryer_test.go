@@ -0,0 +1,179 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryerRun(t *testing.T) {
+	r := NewRetryer(WithAttempts(3), WithDelay(time.Millisecond*10))
+	var calls int
+	err := r.Run(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("something else")
+	})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryerRunConcurrent(t *testing.T) {
+	r := NewRetryer(WithAttempts(3), WithDelay(time.Millisecond*10))
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var n int
+			err := r.Run(context.Background(), func(ctx context.Context) error {
+				atomic.AddInt32(&calls, 1)
+				n++
+				if n < 2 {
+					return errors.New("something else")
+				}
+				return nil
+			})
+			assert.Equal(t, true, err == nil)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(20), atomic.LoadInt32(&calls))
+}
+
+func TestRetryerRunHTTP(t *testing.T) {
+	r := NewRetryer(WithPolicyType(HTTPPolicy))
+	indexTestTimedout = 1
+	// HTTPPolicy waits 2s before retrying, so only check it recovers once
+	err := r.RunHTTP(context.Background(), func(ctx context.Context) (*http.Response, error) {
+		return testHTTPRetryable(1)
+	})
+	assert.Equal(t, true, err == nil)
+}
+
+func TestRetryerClose(t *testing.T) {
+	r := NewRetryer(WithAttempts(2), WithDelay(time.Millisecond*10))
+	events := r.Subscribe()
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		var calls int
+		done <- r.Run(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls == 1 {
+				close(started)
+				return errors.New("something else")
+			}
+			return nil
+		})
+	}()
+	<-started
+	err := r.Close(context.Background())
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, true, <-done == nil)
+	for range events {
+		// the channel is closed once the operations are finished
+	}
+
+	err = r.Run(context.Background(), func(ctx context.Context) error {
+		return nil
+	})
+	assert.Equal(t, ErrRetryerClosed, err)
+	assert.Equal(t, ErrRetryerClosed, r.Go(context.Background(), func(ctx context.Context) error {
+		return nil
+	}).Err())
+}
+
+func TestRetryerCloseCancelsSleeps(t *testing.T) {
+	r := NewRetryer(WithAttempts(2), WithDelay(time.Hour))
+	events := r.Subscribe()
+	errFailed := errors.New("something else")
+	started := make(chan struct{})
+	run := func(ctx context.Context) error {
+		close(started)
+		return errFailed
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(context.Background(), run)
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	err := r.Close(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	// the channels of the subscribers are closed even though an operation is still in flight
+	for range events {
+	}
+	_, open := <-r.Subscribe()
+	assert.Equal(t, false, open)
+	err = <-done
+	assert.Equal(t, true, errors.Is(err, ErrRetryerClosed))
+	assert.Equal(t, true, errors.Is(err, errFailed))
+}
+
+func TestRetryerRunAllocs(t *testing.T) {
+	r := NewRetryer()
+	fn := func(ctx context.Context) error {
+		return nil
+	}
+	plain := func() error {
+		return nil
+	}
+	plainT := func() (int, error) {
+		return 1, nil
+	}
+	fnT := func(context.Context) (int, error) {
+		return 1, nil
+	}
+	// every call is given its own context, as the callers serving requests do
+	const runs = 100
+	type requestKey struct{}
+	ctxs := make([]context.Context, runs+1)
+	for i := range ctxs {
+		ctxs[i] = context.WithValue(context.Background(), requestKey{}, i)
+	}
+	var i int
+	ctx := func() context.Context {
+		i++
+		return ctxs[i%len(ctxs)]
+	}
+	// a first attempt that succeeds only allocates the context carrying its AttemptInfo, and nothing
+	// at all without a context, whichever entry point calls it
+	for _, test := range []struct {
+		name   string
+		call   func()
+		allocs float64
+	}{
+		{"Run", func() { _ = r.Run(ctx(), fn) }, 1},
+		{"Do", func() { _ = Do(ctx(), fn) }, 1},
+		{"Executor", func() { _ = Executor(plain) }, 0},
+		{"ExecutorWithContext", func() { _ = ExecutorWithContext(ctx(), fn) }, 1},
+		{"ExecutorWithPolicyType", func() { _ = ExecutorWithPolicyType(StandardPolicy, plain) }, 0},
+		{"ExecutorWithPolicyTypeContext", func() { _ = ExecutorWithPolicyTypeContext(ctx(), StandardPolicy, fn) }, 1},
+		{"ExecutorT", func() { _, _ = ExecutorT(plainT) }, 0},
+		{"ExecutorTWithContext", func() { _, _ = ExecutorTWithContext(ctx(), fnT) }, 1},
+	} {
+		allocs := testing.AllocsPerRun(runs, test.call)
+		assert.Equal(t, test.allocs, allocs, test.name)
+	}
+}
+
+func TestRetryerCloseGo(t *testing.T) {
+	r := NewRetryer(WithAttempts(2), WithDelay(time.Hour))
+	f := r.Go(context.Background(), func(ctx context.Context) error {
+		return errors.New("something else")
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	err := r.Close(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, true, errors.Is(f.Err(), context.Canceled))
+	assert.Equal(t, nil, r.Close(context.Background()))
+}
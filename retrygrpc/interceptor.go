@@ -0,0 +1,221 @@
+// Package retrygrpc provides gRPC client interceptors that retry calls with the retry package
+package retrygrpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/elumbantoruan/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultCodes are the status codes retried by the interceptors by default
+var DefaultCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded}
+
+// Policy returns a retry policy matching the errors that carry one of the given status codes
+func Policy(delay time.Duration, retryLimit int, retryCodes ...codes.Code) retry.Policy {
+	return retry.Policy{
+		RetryIf:       CodeIn(retryCodes...),
+		DelayDuration: delay,
+		RetryLimit:    retryLimit,
+	}
+}
+
+// DefaultPolicies returns the policies used by the interceptors by default: DefaultCodes are retried
+// 3 times with an exponential backoff starting at 100ms
+func DefaultPolicies() []retry.Policy {
+	p := Policy(time.Millisecond*100, 3, DefaultCodes...)
+	p.Backoff = retry.ExponentialBackoff
+	p.MaxDelay = time.Second * 5
+	return []retry.Policy{p}
+}
+
+// GRPCPolicy selects DefaultPolicies in the retry package, i.e: retry.WithPolicyType(retrygrpc.GRPCPolicy),
+// they're registered as "grpc" so they can also be looked up by name with retry.LookupPolicyType
+var GRPCPolicy = retry.RegisterPolicyType("grpc", DefaultPolicies())
+
+// CodeIn returns a predicate that reports whether an error carries one of the given status codes.
+// The code is extracted with status.FromError, so an error wrapping a status error matches too
+func CodeIn(retryCodes ...codes.Code) func(error) bool {
+	return func(err error) bool {
+		s, ok := status.FromError(err)
+		if !ok {
+			return false
+		}
+		for _, c := range retryCodes {
+			if s.Code() == c {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Option configures the interceptors
+type Option func(*config)
+
+type config struct {
+	policies       []retry.Policy
+	methodPolicies map[string][]retry.Policy
+	retryOptions   []retry.Option
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		policies:       DefaultPolicies(),
+		methodPolicies: map[string][]retry.Policy{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// optionsFor returns the retry options of the call to method
+func (c *config) optionsFor(method string) []retry.Option {
+	policies, ok := c.methodPolicies[method]
+	if !ok {
+		policies = c.policies
+	}
+	return append([]retry.Option{retry.WithPolicies(policies)}, c.retryOptions...)
+}
+
+// WithPolicies replaces DefaultPolicies for every method
+func WithPolicies(policies []retry.Policy) Option {
+	return func(c *config) {
+		c.policies = policies
+	}
+}
+
+// WithMethodPolicies overrides the policies of a single method, given by its full name
+// such as "/package.Service/Method". Empty policies disable retries for the method
+func WithMethodPolicies(fullMethod string, policies []retry.Policy) Option {
+	return func(c *config) {
+		c.methodPolicies[fullMethod] = policies
+	}
+}
+
+// WithRetryOptions adds options, such as retry.WithOnRetry, to every retried call
+func WithRetryOptions(opts ...retry.Option) Option {
+	return func(c *config) {
+		c.retryOptions = append(c.retryOptions, opts...)
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor that retries unary calls. Every attempt uses
+// the context of the call, so its deadline and cancellation bound the whole retry sequence,
+// and a call whose context is done while waiting fails with codes.DeadlineExceeded or codes.Canceled
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		err := retry.Do(ctx, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}, c.optionsFor(method)...)
+		return statusError(err)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that retries establishing client streams.
+// Once a stream is established, errors returned by its SendMsg and RecvMsg are not retried.
+// A stream outlives the attempt that opens it, so it's opened with the context of the call:
+// retry.WithAttemptTimeout and retry.WithMaxElapsedTime bound how long it takes to establish
+// the stream, not how long the stream lasts
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		var mu sync.Mutex
+		var stream *clientStream
+		over := false
+		err := retry.Do(ctx, func(attemptCtx context.Context) error {
+			streamCtx, cancel := context.WithCancel(ctx)
+			// the attempt is canceled while the stream is being established, not once it is
+			stop := context.AfterFunc(attemptCtx, cancel)
+			s, err := streamer(streamCtx, desc, cc, method, callOpts...)
+			if !stop() {
+				// the stream is canceled with the attempt
+				err = context.Cause(attemptCtx)
+			}
+			if err != nil {
+				cancel()
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if over {
+				// the attempt is abandoned and the call is over
+				cancel()
+				return context.Cause(attemptCtx)
+			}
+			if stream != nil {
+				// the stream of an attempt that's been given up on after it established it
+				stream.release()
+			}
+			stream = &clientStream{ClientStream: s, cancel: cancel}
+			return nil
+		}, c.optionsFor(method)...)
+		mu.Lock()
+		defer mu.Unlock()
+		over = true
+		if err != nil {
+			if stream != nil {
+				stream.release()
+			}
+			return nil, statusError(err)
+		}
+		if stream.ClientStream == nil {
+			stream.release()
+			return nil, nil
+		}
+		return stream, nil
+	}
+}
+
+// clientStream is a stream established by StreamClientInterceptor, its context is released
+// once the stream is over, as grpc.ClientStream documents
+type clientStream struct {
+	grpc.ClientStream
+	once   sync.Once
+	cancel context.CancelFunc
+}
+
+func (s *clientStream) release() {
+	s.once.Do(s.cancel)
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.release()
+	}
+	return md, err
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		s.release()
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.release()
+	}
+	return err
+}
+
+// statusError converts the context errors returned by the retry package while waiting for
+// the next attempt into their gRPC status, other errors are returned as is
+func statusError(err error) error {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return status.FromContextError(err).Err()
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package retrygrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/retry"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testPolicies = []retry.Policy{Policy(time.Millisecond*10, 3, DefaultCodes...)}
+
+func TestCodeIn(t *testing.T) {
+	retryable := CodeIn(codes.Unavailable)
+	assert.Equal(t, true, retryable(status.Error(codes.Unavailable, "down")))
+	assert.Equal(t, true, retryable(fmt.Errorf("wrapped: %w", status.Error(codes.Unavailable, "down"))))
+	assert.Equal(t, false, retryable(status.Error(codes.NotFound, "missing")))
+	assert.Equal(t, false, retryable(errors.New("plain")))
+}
+
+func TestGRPCPolicy(t *testing.T) {
+	policyType, ok := retry.LookupPolicyType("grpc")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, GRPCPolicy, policyType)
+
+	var calls int
+	err := retry.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return status.Error(codes.Unavailable, "down")
+		}
+		return status.Error(codes.PermissionDenied, "denied")
+	}, retry.WithPolicyType(GRPCPolicy))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, 2, calls)
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	interceptor := UnaryClientInterceptor(WithPolicies(testPolicies))
+	var calls int
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		if calls <= 2 {
+			return status.Error(codes.Unavailable, "down")
+		}
+		return nil
+	}
+	err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 3, calls)
+}
+
+func TestUnaryClientInterceptorNonRetryable(t *testing.T) {
+	interceptor := UnaryClientInterceptor(WithPolicies(testPolicies))
+	var calls int
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.InvalidArgument, "bad request")
+	}
+	err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestUnaryClientInterceptorMethodPolicies(t *testing.T) {
+	interceptor := UnaryClientInterceptor(WithPolicies(testPolicies), WithMethodPolicies("/test.Service/Create", nil))
+	var calls int
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.Unavailable, "down")
+	}
+	err := interceptor(context.Background(), "/test.Service/Create", nil, nil, nil, invoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestUnaryClientInterceptorContext(t *testing.T) {
+	policies := []retry.Policy{Policy(time.Hour, 3, DefaultCodes...)}
+	interceptor := UnaryClientInterceptor(WithPolicies(policies))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.Unavailable, "down")
+	}
+	err := interceptor(ctx, "/test.Service/Get", nil, nil, nil, invoker)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	interceptor := StreamClientInterceptor(WithPolicies(testPolicies))
+	var calls int
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		calls++
+		if calls <= 1 {
+			return nil, status.Error(codes.ResourceExhausted, "slow down")
+		}
+		return nil, nil
+	}
+	_, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil, "/test.Service/Watch", streamer)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, 2, calls)
+}
+
+// testClientStream is a stream whose context is the one it's opened with
+type testClientStream struct {
+	grpc.ClientStream
+	ctx context.Context
+}
+
+func (s *testClientStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testClientStream) RecvMsg(m interface{}) error {
+	return io.EOF
+}
+
+func TestStreamClientInterceptorOutlivesAttempt(t *testing.T) {
+	interceptor := StreamClientInterceptor(WithPolicies(testPolicies), WithRetryOptions(retry.WithAttemptTimeout(time.Second), retry.WithMaxElapsedTime(time.Second)))
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &testClientStream{ctx: ctx}, nil
+	}
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil, "/test.Service/Watch", streamer)
+	assert.Equal(t, true, err == nil)
+	// the stream is still alive once the retry is over
+	assert.Equal(t, true, stream.Context().Err() == nil)
+	// and it's released once it's over
+	assert.Equal(t, io.EOF, stream.RecvMsg(nil))
+	assert.Equal(t, true, stream.Context().Err() != nil)
+}
+
+func TestStreamClientInterceptorAttemptTimeout(t *testing.T) {
+	interceptor := StreamClientInterceptor(WithPolicies([]retry.Policy{{MatchError: retry.ErrAttemptTimeout, DelayDuration: time.Millisecond, RetryLimit: 1}}),
+		WithRetryOptions(retry.WithAttemptTimeout(time.Millisecond*20)))
+	var calls atomic.Int32
+	var mu sync.Mutex
+	var first context.Context
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if calls.Add(1) == 1 {
+			// establishing the stream is still bound to the attempt
+			mu.Lock()
+			first = ctx
+			mu.Unlock()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &testClientStream{ctx: ctx}, nil
+	}
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil, "/test.Service/Watch", streamer)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, int32(2), calls.Load())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, true, first.Err() != nil)
+	assert.Equal(t, true, stream.Context().Err() == nil)
+}
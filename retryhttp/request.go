@@ -0,0 +1,138 @@
+// Package retryhttp retries HTTP requests made with an http.Client with the retry package
+package retryhttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/elumbantoruan/retry"
+)
+
+// DefaultMaxBodyBuffer is the size of the largest request body DoRequest buffers by default
+const DefaultMaxBodyBuffer = 1 << 20
+
+// Option configures DoRequest
+type Option func(*config)
+
+type config struct {
+	maxBodyBuffer int64
+	retryOptions  []retry.Option
+	beforeRetry   func(req *http.Request, resp *http.Response) error
+}
+
+// WithMaxBodyBuffer sets the size of the largest request body without GetBody that DoRequest
+// buffers in memory so it can be sent again, DefaultMaxBodyBuffer by default. Zero disables buffering
+func WithMaxBodyBuffer(n int64) Option {
+	return func(c *config) {
+		c.maxBodyBuffer = n
+	}
+}
+
+// WithRetryOptions adds options, such as retry.WithOnRetry, to the retry of the request
+func WithRetryOptions(opts ...retry.Option) Option {
+	return func(c *config) {
+		c.retryOptions = append(c.retryOptions, opts...)
+	}
+}
+
+// WithBeforeRetry sets a hook called before every retry with the request about to be sent, a clone
+// of req, and the failed response of the previous attempt, whose body is already closed, nil if the
+// previous attempt didn't get a response. It may change the request, i.e: to refresh an OAuth token
+// after a 401 or to rotate the API key after a 403, provided a policy matches these statuses.
+// The retry is abandoned with its error, see retry.Transport.BeforeRetry
+func WithBeforeRetry(fn func(req *http.Request, resp *http.Response) error) Option {
+	return func(c *config) {
+		c.beforeRetry = fn
+	}
+}
+
+// DoRequest sends req with client, and sends it again as long as its response matches policies,
+// GetRetryPolicies(retry.HTTPPolicy) if nil. Every attempt sends a clone of req with a fresh body,
+// got from req.GetBody, or from a copy of the body buffered in memory if it's not larger than
+// the buffer, see WithMaxBodyBuffer. A body that can't be rewound is sent once, without retries.
+// Like retry.ExecutorHTTPResponse, the successful response is returned and the caller must close
+// its body, and the bodies of the failed responses are drained and closed
+func DoRequest(client *http.Client, req *http.Request, policies []retry.Policy, opts ...Option) (*http.Response, error) {
+	c := &config{maxBodyBuffer: DefaultMaxBodyBuffer}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if policies == nil {
+		policies = retry.GetRetryPolicies(retry.HTTPPolicy)
+	}
+	getBody, err := rewinder(req, c.maxBodyBuffer)
+	if err != nil {
+		return nil, err
+	}
+	if getBody == nil {
+		// the body can't be rewound so the request can only be sent once
+		return client.Do(req)
+	}
+	// last is the response of the attempt with the highest number so far, an attempt abandoned by
+	// retry.WithAttemptTimeout may still return after the following ones
+	var mu sync.Mutex
+	var last *http.Response
+	lastAttempt := 0
+	return retry.ExecutorHTTPResponseWithPoliciesContext(req.Context(), policies, func(ctx context.Context) (*http.Response, error) {
+		attempt := retry.AttemptFromContext(ctx)
+		r := req.Clone(ctx)
+		body, err := getBody()
+		if err != nil {
+			return nil, retry.Unrecoverable(err)
+		}
+		r.Body = body
+		if attempt > 1 && c.beforeRetry != nil {
+			mu.Lock()
+			var prev *http.Response
+			if lastAttempt == attempt-1 {
+				prev = last
+			}
+			mu.Unlock()
+			if err := c.beforeRetry(r, prev); err != nil {
+				_ = body.Close()
+				return nil, retry.Unrecoverable(err)
+			}
+		}
+		resp, err := client.Do(r)
+		mu.Lock()
+		if attempt > lastAttempt {
+			last, lastAttempt = resp, attempt
+		}
+		mu.Unlock()
+		return resp, err
+	}, c.retryOptions...)
+}
+
+// rewinder returns a func returning a fresh copy of the body of req, or nil if the body can't be
+// rewound. In that case the body of req is restored so req can still be sent once
+func rewinder(req *http.Request, maxBuffer int64) (func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() (io.ReadCloser, error) { return http.NoBody, nil }, nil
+	}
+	if req.GetBody != nil {
+		return req.GetBody, nil
+	}
+	if maxBuffer <= 0 || req.ContentLength > maxBuffer {
+		return nil, nil
+	}
+	buf, err := io.ReadAll(io.LimitReader(req.Body, maxBuffer+1))
+	if err != nil {
+		_ = req.Body.Close()
+		return nil, err
+	}
+	if int64(len(buf)) > maxBuffer {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+		return nil, nil
+	}
+	_ = req.Body.Close()
+	req.ContentLength = int64(len(buf))
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}, nil
+}
@@ -0,0 +1,148 @@
+package retryhttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPolicies = []retry.Policy{
+	{
+		ErrorCodeNumber: http.StatusServiceUnavailable,
+		ErrorCodeString: http.StatusText(http.StatusServiceUnavailable),
+		DelayDuration:   time.Millisecond * 10,
+		RetryLimit:      3,
+	},
+}
+
+func testServer(failures int, bodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		*bodies = append(*bodies, string(b))
+		if len(*bodies) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+}
+
+func TestDoRequest(t *testing.T) {
+	var bodies []string
+	server := testServer(2, &bodies)
+	defer server.Close()
+
+	// the body is a plain io.Reader, so it has no GetBody and is buffered
+	req, _ := http.NewRequest(http.MethodPut, server.URL, io.NopCloser(strings.NewReader("payload")))
+	resp, err := DoRequest(http.DefaultClient, req, testPolicies)
+	assert.Equal(t, true, err == nil)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(b))
+	assert.Equal(t, []string{"payload", "payload", "payload"}, bodies)
+}
+
+func TestDoRequestGetBody(t *testing.T) {
+	var bodies []string
+	server := testServer(1, &bodies)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+	resp, err := DoRequest(http.DefaultClient, req, testPolicies, WithMaxBodyBuffer(0))
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestDoRequestNonRewindable(t *testing.T) {
+	var bodies []string
+	server := testServer(1, &bodies)
+	defer server.Close()
+
+	// the body is larger than the buffer, so the request is sent once
+	req, _ := http.NewRequest(http.MethodPost, server.URL, io.NopCloser(strings.NewReader("payload")))
+	resp, err := DoRequest(http.DefaultClient, req, testPolicies, WithMaxBodyBuffer(4))
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, []string{"payload"}, bodies)
+}
+
+func TestDoRequestExhausted(t *testing.T) {
+	var bodies []string
+	server := testServer(10, &bodies)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	var retries int
+	resp, err := DoRequest(http.DefaultClient, req, testPolicies, WithRetryOptions(retry.WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		retries++
+	})))
+	assert.Equal(t, true, resp == nil)
+	var exhausted *retry.ExhaustedError
+	assert.Equal(t, true, errors.As(err, &exhausted))
+	assert.Equal(t, http.StatusServiceUnavailable, exhausted.StatusCode)
+	assert.Equal(t, 4, len(bodies))
+	assert.Equal(t, 3, retries)
+}
+
+func TestDoRequestBeforeRetry(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("X-Api-Key"))
+		if r.Header.Get("X-Api-Key") != "second" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	policies := []retry.Policy{{StatusCodes: []int{http.StatusForbidden}, DelayDuration: time.Millisecond, RetryLimit: 2}}
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+	req.Header.Set("X-Api-Key", "first")
+	var statuses []int
+	resp, err := DoRequest(http.DefaultClient, req, policies, WithBeforeRetry(func(req *http.Request, resp *http.Response) error {
+		statuses = append(statuses, resp.StatusCode)
+		req.Header.Set("X-Api-Key", "second")
+		return nil
+	}))
+	assert.Equal(t, true, err == nil)
+	resp.Body.Close()
+	assert.Equal(t, []string{"first", "second"}, keys)
+	assert.Equal(t, []int{http.StatusForbidden}, statuses)
+}
+
+// testRoundTripFunc is an http.RoundTripper calling itself
+type testRoundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f testRoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDoRequestBeforeRetryError(t *testing.T) {
+	var calls int
+	client := &http.Client{Transport: testRoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	})}
+	policies := []retry.Policy{{RetryIf: func(err error) bool { return true }, DelayDuration: time.Millisecond, RetryLimit: 3}}
+	errRefresh := errors.New("refresh failed")
+	var resps []*http.Response
+	req, _ := http.NewRequest(http.MethodGet, "http://example.invalid", nil)
+	_, err := DoRequest(client, req, policies, WithBeforeRetry(func(req *http.Request, resp *http.Response) error {
+		resps = append(resps, resp)
+		return errRefresh
+	}))
+	assert.Equal(t, true, errors.Is(err, errRefresh))
+	assert.Equal(t, 1, calls)
+	// the hook is called after a transport error too, like retry.Transport.BeforeRetry
+	assert.Equal(t, []*http.Response{nil}, resps)
+}
@@ -0,0 +1,69 @@
+// Package retryotel provides an OpenTelemetry implementation of retry.Tracer
+package retryotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/elumbantoruan/retry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys set on the spans
+const (
+	AttemptKey = attribute.Key("retry.attempt")
+	DelayKey   = attribute.Key("retry.delay_ms")
+)
+
+// Tracer is a retry.Tracer creating a span named after the operation for the whole retry
+// sequence and a child span for every attempt. Delays are recorded as events of the operation span
+type Tracer struct {
+	tracer trace.Tracer
+	name   string
+}
+
+var _ retry.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a Tracer creating spans named name with tracer
+func NewTracer(tracer trace.Tracer, name string) *Tracer {
+	return &Tracer{tracer: tracer, name: name}
+}
+
+// WithTracer is a shortcut for retry.WithTracer(NewTracer(tracer, name))
+func WithTracer(tracer trace.Tracer, name string) retry.Option {
+	return retry.WithTracer(NewTracer(tracer, name))
+}
+
+// StartOperation implements retry.Tracer
+func (t *Tracer) StartOperation(ctx context.Context) (context.Context, func(err error)) {
+	ctx, span := t.tracer.Start(ctx, t.name)
+	return ctx, func(err error) {
+		end(span, err)
+	}
+}
+
+// StartAttempt implements retry.Tracer
+func (t *Tracer) StartAttempt(ctx context.Context, attempt int) (context.Context, func(err error)) {
+	ctx, span := t.tracer.Start(ctx, t.name+" attempt", trace.WithAttributes(AttemptKey.Int(attempt)))
+	return ctx, func(err error) {
+		end(span, err)
+	}
+}
+
+// Delay implements retry.Tracer
+func (t *Tracer) Delay(ctx context.Context, attempt int, delay time.Duration) {
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+		AttemptKey.Int(attempt),
+		DelayKey.Int64(delay.Milliseconds()),
+	))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,48 @@
+package retryotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/retry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	var calls int
+	err := retry.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 2 {
+			return errors.New("down")
+		}
+		return nil
+	}, retry.WithDelay(time.Millisecond*10), WithTracer(provider.Tracer("test"), "fetch"))
+	assert.Equal(t, true, err == nil)
+
+	spans := recorder.Ended()
+	assert.Equal(t, 3, len(spans))
+	first, second, operation := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "fetch attempt", first.Name())
+	assert.Equal(t, codes.Error, first.Status().Code)
+	assert.Equal(t, AttemptKey.Int(1), first.Attributes()[0])
+	assert.Equal(t, operation.SpanContext().SpanID(), first.Parent().SpanID())
+
+	assert.Equal(t, "fetch attempt", second.Name())
+	assert.Equal(t, codes.Unset, second.Status().Code)
+	assert.Equal(t, AttemptKey.Int(2), second.Attributes()[0])
+
+	assert.Equal(t, "fetch", operation.Name())
+	assert.Equal(t, codes.Unset, operation.Status().Code)
+	assert.Equal(t, 1, len(operation.Events()))
+	assert.Equal(t, "retry", operation.Events()[0].Name)
+	assert.Equal(t, DelayKey.Int64(10), operation.Events()[0].Attributes[1])
+}
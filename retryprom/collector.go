@@ -0,0 +1,104 @@
+// Package retryprom provides a Prometheus implementation of retry.MetricsCollector
+package retryprom
+
+import (
+	"time"
+
+	"github.com/elumbantoruan/retry"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a retry.MetricsCollector exporting Prometheus metrics.
+// It's a prometheus.Collector too, so it must be registered to be exported
+type Collector struct {
+	attempts  *prometheus.CounterVec
+	successes prometheus.Counter
+	exhausted prometheus.Counter
+	retries   prometheus.Histogram
+	delays    prometheus.Histogram
+}
+
+var _ retry.MetricsCollector = (*Collector)(nil)
+
+// NewCollector returns a Collector whose metrics are prefixed by namespace and carry constLabels,
+// i.e: a "dependency" label to tell the Retryers apart
+func NewCollector(namespace string, constLabels prometheus.Labels) *Collector {
+	return &Collector{
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "retry_attempts_total",
+			Help:        "Number of attempts, by result.",
+			ConstLabels: constLabels,
+		}, []string{"result"}),
+		successes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "retry_successes_total",
+			Help:        "Number of operations that eventually succeeded.",
+			ConstLabels: constLabels,
+		}),
+		exhausted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "retry_exhausted_total",
+			Help:        "Number of operations that failed after all retries.",
+			ConstLabels: constLabels,
+		}),
+		retries: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "retry_attempts_per_operation",
+			Help:        "Number of attempts made by every operation.",
+			ConstLabels: constLabels,
+			Buckets:     []float64{1, 2, 3, 4, 5, 7, 10, 20},
+		}),
+		delays: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "retry_delay_seconds",
+			Help:        "Delay before every retry.",
+			ConstLabels: constLabels,
+			Buckets:     prometheus.ExponentialBuckets(0.01, 2, 12),
+		}),
+	}
+}
+
+// RecordAttempt implements retry.MetricsCollector
+func (c *Collector) RecordAttempt(attempt int, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	c.attempts.WithLabelValues(result).Inc()
+}
+
+// RecordSuccess implements retry.MetricsCollector
+func (c *Collector) RecordSuccess(attempts int) {
+	c.successes.Inc()
+	c.retries.Observe(float64(attempts))
+}
+
+// RecordExhausted implements retry.MetricsCollector
+func (c *Collector) RecordExhausted(attempts int, err error) {
+	c.exhausted.Inc()
+	c.retries.Observe(float64(attempts))
+}
+
+// ObserveDelay implements retry.MetricsCollector
+func (c *Collector) ObserveDelay(delay time.Duration) {
+	c.delays.Observe(delay.Seconds())
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.attempts.Describe(ch)
+	c.successes.Describe(ch)
+	c.exhausted.Describe(ch)
+	c.retries.Describe(ch)
+	c.delays.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.attempts.Collect(ch)
+	c.successes.Collect(ch)
+	c.exhausted.Collect(ch)
+	c.retries.Collect(ch)
+	c.delays.Collect(ch)
+}
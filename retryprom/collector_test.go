@@ -0,0 +1,39 @@
+package retryprom
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector("test", prometheus.Labels{"dependency": "storage"})
+	reg := prometheus.NewPedanticRegistry()
+	assert.Equal(t, true, reg.Register(c) == nil)
+
+	r := retry.NewRetryer(retry.WithAttempts(3), retry.WithDelay(time.Millisecond*10), retry.WithMetrics(c))
+	_ = r.Run(context.Background(), func(ctx context.Context) error {
+		return errors.New("down")
+	})
+
+	err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP test_retry_attempts_total Number of attempts, by result.
+# TYPE test_retry_attempts_total counter
+test_retry_attempts_total{dependency="storage",result="failure"} 3
+# HELP test_retry_exhausted_total Number of operations that failed after all retries.
+# TYPE test_retry_exhausted_total counter
+test_retry_exhausted_total{dependency="storage"} 1
+# HELP test_retry_successes_total Number of operations that eventually succeeded.
+# TYPE test_retry_successes_total counter
+test_retry_successes_total{dependency="storage"} 0
+`), "test_retry_attempts_total", "test_retry_exhausted_total", "test_retry_successes_total")
+	assert.Equal(t, true, err == nil, err)
+	assert.Equal(t, 1, testutil.CollectAndCount(c, "test_retry_delay_seconds"))
+}
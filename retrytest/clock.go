@@ -0,0 +1,59 @@
+// Package retrytest provides test helpers for code using the retry package
+package retrytest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/elumbantoruan/retry"
+)
+
+// FakeClock is a retry.Clock whose Sleep returns right away and moves the clock forward,
+// so tests don't wait for the retry delays and can assert on them.
+// It's safe for concurrent use
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+var _ retry.Clock = (*FakeClock)(nil)
+
+// NewFakeClock returns a FakeClock set to now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements retry.Clock
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep implements retry.Clock, it records delay and advances the clock by it
+func (c *FakeClock) Sleep(ctx context.Context, delay time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, delay)
+	c.now = c.now.Add(delay)
+	return nil
+}
+
+// Advance moves the clock forward by d, i.e: to simulate a slow attempt
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Sleeps returns every delay passed to Sleep so far
+func (c *FakeClock) Sleeps() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.sleeps...)
+}
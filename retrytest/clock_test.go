@@ -0,0 +1,55 @@
+package retrytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elumbantoruan/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC))
+	start := time.Now()
+	err := retry.Do(context.Background(), func(ctx context.Context) error {
+		return errors.New("down")
+	},
+		retry.WithAttempts(4),
+		retry.WithDelay(time.Hour),
+		retry.WithBackoff(retry.ExponentialBackoff),
+		retry.WithClock(clock),
+	)
+	assert.Equal(t, true, err != nil)
+	// no real time was spent waiting
+	assert.Equal(t, true, time.Since(start) < time.Second)
+	assert.Equal(t, []time.Duration{time.Hour, time.Hour * 2, time.Hour * 4}, clock.Sleeps())
+	assert.Equal(t, time.Date(2020, time.January, 1, 7, 0, 0, 0, time.UTC), clock.Now())
+}
+
+func TestFakeClockMaxElapsedTime(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	var calls int
+	err := retry.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		clock.Advance(time.Minute)
+		return errors.New("down")
+	},
+		retry.WithAttempts(100),
+		retry.WithDelay(time.Minute),
+		retry.WithMaxElapsedTime(time.Minute*10),
+		retry.WithClock(clock),
+	)
+	assert.Equal(t, true, err != nil)
+	// every attempt takes a minute plus a minute of delay
+	assert.Equal(t, 5, calls)
+}
+
+func TestFakeClockContextCancelled(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, clock.Sleep(ctx, time.Second))
+	assert.Equal(t, 0, len(clock.Sleeps()))
+}
@@ -0,0 +1,83 @@
+package retrytest
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/elumbantoruan/retry"
+)
+
+// NoRetry is a retry.ExecutorIface running every operation once, so tests see the first
+// failure right away. A failed response is reported as the retry executors do.
+// It's safe for concurrent use
+type NoRetry struct {
+	calls atomic.Int64
+}
+
+var _ retry.ExecutorIface = (*NoRetry)(nil)
+
+// noPolicies matches no error, so nothing is retried
+var noPolicies = []retry.Policy{}
+
+// Run implements retry.ExecutorIface
+func (n *NoRetry) Run(ctx context.Context, fn retry.FuncContext) error {
+	n.calls.Add(1)
+	return retry.Do(ctx, fn, retry.WithPolicies(noPolicies))
+}
+
+// RunHTTP implements retry.ExecutorIface
+func (n *NoRetry) RunHTTP(ctx context.Context, fn retry.FuncHTTPContext) error {
+	n.calls.Add(1)
+	return retry.ExecutorHTTPWithPoliciesContext(ctx, noPolicies, fn)
+}
+
+// RunHTTPResponse implements retry.ExecutorIface
+func (n *NoRetry) RunHTTPResponse(ctx context.Context, fn retry.FuncHTTPContext) (*http.Response, error) {
+	n.calls.Add(1)
+	return retry.ExecutorHTTPResponseWithPoliciesContext(ctx, noPolicies, fn)
+}
+
+// Calls returns the number of operations run so far
+func (n *NoRetry) Calls() int {
+	return int(n.calls.Load())
+}
+
+// AlwaysFail is a retry.ExecutorIface failing every operation with Err without running it,
+// i.e: to test the handling of exhausted retries. It's safe for concurrent use
+type AlwaysFail struct {
+	// Err is the error returned for every operation
+	Err error
+
+	calls atomic.Int64
+}
+
+var _ retry.ExecutorIface = (*AlwaysFail)(nil)
+
+// NewAlwaysFail returns an AlwaysFail failing with err
+func NewAlwaysFail(err error) *AlwaysFail {
+	return &AlwaysFail{Err: err}
+}
+
+// Run implements retry.ExecutorIface
+func (f *AlwaysFail) Run(ctx context.Context, fn retry.FuncContext) error {
+	f.calls.Add(1)
+	return f.Err
+}
+
+// RunHTTP implements retry.ExecutorIface
+func (f *AlwaysFail) RunHTTP(ctx context.Context, fn retry.FuncHTTPContext) error {
+	f.calls.Add(1)
+	return f.Err
+}
+
+// RunHTTPResponse implements retry.ExecutorIface
+func (f *AlwaysFail) RunHTTPResponse(ctx context.Context, fn retry.FuncHTTPContext) (*http.Response, error) {
+	f.calls.Add(1)
+	return nil, f.Err
+}
+
+// Calls returns the number of operations failed so far
+func (f *AlwaysFail) Calls() int {
+	return int(f.calls.Load())
+}
@@ -0,0 +1,48 @@
+package retrytest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/elumbantoruan/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+// fetch is code under test depending on a retry.ExecutorIface
+func fetch(ctx context.Context, r retry.ExecutorIface, calls *int) error {
+	return r.RunHTTP(ctx, func(ctx context.Context) (*http.Response, error) {
+		*calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Body: http.NoBody}, nil
+	})
+}
+
+func TestNoRetry(t *testing.T) {
+	n := &NoRetry{}
+	var calls int
+	err := fetch(context.Background(), n, &calls)
+	assert.Equal(t, "ERROR: httpStatusCode: 503, httpStatus: 503 Service Unavailable", err.Error())
+	assert.Equal(t, 1, calls)
+
+	down := errors.New("down")
+	err = n.Run(context.Background(), func(ctx context.Context) error {
+		calls++
+		return down
+	})
+	assert.Equal(t, down, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, n.Calls())
+}
+
+func TestAlwaysFail(t *testing.T) {
+	down := errors.New("down")
+	f := NewAlwaysFail(down)
+	var calls int
+	assert.Equal(t, down, fetch(context.Background(), f, &calls))
+	resp, err := f.RunHTTPResponse(context.Background(), nil)
+	assert.Equal(t, true, resp == nil)
+	assert.Equal(t, down, err)
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, 2, f.Calls())
+}
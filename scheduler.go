@@ -0,0 +1,328 @@
+package retry
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// Scheduler parks the operations of Retryer.Go waiting for their next attempt, and wakes them up
+// with a single goroutine and timer. Sleeping a goroutine per retry gets expensive with thousands
+// of concurrent retries, parked operations only hold their state in the scheduler.
+// It's safe for concurrent use
+type Scheduler struct {
+	mu      sync.Mutex
+	entries scheduledEntries
+	wake    chan struct{}
+	done    chan struct{}
+	stop    sync.Once
+}
+
+// defaultScheduler is the Scheduler of the Retryers without WithScheduler
+var defaultScheduler = struct {
+	once sync.Once
+	s    *Scheduler
+}{}
+
+// NewScheduler returns a Scheduler and starts its goroutine, Stop stops it
+func NewScheduler() *Scheduler {
+	s := &Scheduler{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+// WithScheduler sets the Scheduler parking the operations of Retryer.Go between attempts,
+// a Scheduler shared by the package by default
+func WithScheduler(s *Scheduler) Option {
+	return func(o *options) {
+		o.scheduler = s
+	}
+}
+
+// Len returns the number of parked operations
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Stop stops the goroutine of the scheduler, the parked operations are never woken up,
+// so they must be cancelled before
+func (s *Scheduler) Stop() {
+	s.stop.Do(func() {
+		close(s.done)
+	})
+}
+
+// after parks fn until delay passes, then runs it in a new goroutine.
+// The returned entry can be cancelled until then
+func (s *Scheduler) after(delay time.Duration, fn func()) *scheduledEntry {
+	e := &scheduledEntry{at: time.Now().Add(delay), fn: fn}
+	s.mu.Lock()
+	heap.Push(&s.entries, e)
+	first := e.index == 0
+	s.mu.Unlock()
+	if first {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return e
+}
+
+// cancel removes e, and reports whether it was still parked so its fn will never run
+func (s *Scheduler) cancel(e *scheduledEntry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.index < 0 {
+		return false
+	}
+	heap.Remove(&s.entries, e.index)
+	return true
+}
+
+// loop runs the due entries and waits for the next one
+func (s *Scheduler) loop() {
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		var due []*scheduledEntry
+		for len(s.entries) > 0 && !s.entries[0].at.After(now) {
+			due = append(due, heap.Pop(&s.entries).(*scheduledEntry))
+		}
+		wait := time.Duration(-1)
+		if len(s.entries) > 0 {
+			wait = s.entries[0].at.Sub(now)
+		}
+		s.mu.Unlock()
+		for _, e := range due {
+			go e.fn()
+		}
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			fire = timer.C
+		}
+		select {
+		case <-fire:
+		case <-s.wake:
+		case <-s.done:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+	}
+}
+
+// scheduledEntry is an operation parked in a Scheduler until at
+type scheduledEntry struct {
+	at time.Time
+	fn func()
+	// index is the position of the entry in the heap, -1 once it's removed
+	index int
+}
+
+// scheduledEntries is a min-heap of entries by time, see container/heap
+type scheduledEntries []*scheduledEntry
+
+func (h scheduledEntries) Len() int {
+	return len(h)
+}
+
+func (h scheduledEntries) Less(i, j int) bool {
+	return h[i].at.Before(h[j].at)
+}
+
+func (h scheduledEntries) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduledEntries) Push(x any) {
+	e := x.(*scheduledEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *scheduledEntries) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*h = old[:len(old)-1]
+	return e
+}
+
+// Go is like Run but returns right away, the outcome is collected with the returned Future.
+// Between attempts the operation is parked in the Scheduler of the Retryer instead of in a
+// sleeping goroutine, every attempt runs in a new goroutine. The retries are decided as Run
+// does them, but the delays are waited on the timer of the Scheduler rather than with the Clock
+// of the Retryer, and a WithStopChannel closed while the operation is parked only stops it
+// once its delay has passed
+func (r *Retryer) Go(ctx context.Context, fn FuncContext) *Future[struct{}] {
+	if !r.enter() {
+		f := &Future[struct{}]{done: make(chan struct{}), cancel: func() {}, err: ErrRetryerClosed}
+		close(f.done)
+		return f
+	}
+	return goScheduled(ctx, r.opts, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, r.running.Done)
+}
+
+// scheduledOperation is an operation of Retryer.Go, it drives its execution by parking it between attempts
+type scheduledOperation[T any] struct {
+	scheduler *Scheduler
+	e         execution[T]
+	future    *Future[T]
+
+	mu sync.Mutex
+	// parked is the entry of the operation while it waits for its next attempt
+	parked *scheduledEntry
+	// stopCancel stops watching the context of the execution
+	stopCancel func() bool
+	// finished is called once the operation is finished
+	finished func()
+}
+
+// goScheduled starts fn as configured by o, and calls finished once it's finished, see Retryer.Go
+func goScheduled[T any](ctx context.Context, o *options, fn func(context.Context) (T, error), finished func()) *Future[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	f := &Future[T]{done: make(chan struct{}), cancel: cancel}
+	if o.shutdown != nil {
+		// the operation is cancelled when its Retryer gives up on it, see Retryer.Close
+		stop := context.AfterFunc(o.shutdown, cancel)
+		done := finished
+		finished = func() {
+			stop()
+			if done != nil {
+				done()
+			}
+		}
+	}
+	s := &scheduledOperation[T]{scheduler: o.scheduler, future: f, finished: finished}
+	if s.scheduler == nil {
+		defaultScheduler.once.Do(func() {
+			defaultScheduler.s = NewScheduler()
+		})
+		s.scheduler = defaultScheduler.s
+	}
+	e := &s.e
+	if !e.init(ctx, o, operation[T]{fn: fn}, nil) {
+		s.finish()
+		return f
+	}
+	s.mu.Lock()
+	s.stopCancel = context.AfterFunc(e.ctx, s.cancelled)
+	s.mu.Unlock()
+	if o.initialDelay > 0 {
+		if !s.park(o.initialDelay, s.first) {
+			e.abort(e.ctx.Err())
+			s.finish()
+		}
+		return f
+	}
+	go s.first()
+	return f
+}
+
+// first makes the first attempt
+func (s *scheduledOperation[T]) first() {
+	s.e.begin()
+	s.attempt()
+}
+
+// attempt makes the current attempt, then parks the operation until the next one or finishes it
+func (s *scheduledOperation[T]) attempt() {
+	e := &s.e
+	e.call()
+	if e.err == nil {
+		e.succeed()
+		s.finish()
+		return
+	}
+	if !e.decide() {
+		s.finish()
+		return
+	}
+	if !s.park(e.decision.Delay, s.resume) {
+		e.interrupted(e.ctx.Err())
+		s.finish()
+	}
+}
+
+// resume makes the next attempt once the operation is woken up
+func (s *scheduledOperation[T]) resume() {
+	if !s.e.resume() {
+		s.finish()
+		return
+	}
+	s.attempt()
+}
+
+// park parks the operation until delay passes and then runs next, or reports false if its context is done
+func (s *scheduledOperation[T]) park(delay time.Duration, next func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.e.ctx.Err() != nil {
+		// cancelled was called while the operation wasn't parked
+		return false
+	}
+	s.parked = s.scheduler.after(delay, func() {
+		s.mu.Lock()
+		s.parked = nil
+		s.mu.Unlock()
+		next()
+	})
+	return true
+}
+
+// cancelled finishes the operation when the context of its execution is done while it's parked
+func (s *scheduledOperation[T]) cancelled() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.parked == nil || !s.scheduler.cancel(s.parked) {
+		return
+	}
+	s.parked = nil
+	if !s.e.begun {
+		// parked for the initial delay, no attempt was made
+		s.e.abort(s.e.ctx.Err())
+	} else {
+		s.e.interrupted(s.e.ctx.Err())
+	}
+	s.finish()
+}
+
+// finish completes the future of the operation with the outcome of its execution
+func (s *scheduledOperation[T]) finish() {
+	if s.stopCancel != nil {
+		s.stopCancel()
+	}
+	s.e.end()
+	s.future.result, s.future.err = s.e.result, s.e.err
+	s.future.cancel()
+	if s.finished != nil {
+		s.finished()
+	}
+	close(s.future.done)
+}
+
+// zeroOf returns the zero value of T
+func zeroOf[T any]() T {
+	var zero T
+	return zero
+}
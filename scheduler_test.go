@@ -0,0 +1,109 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryerGo(t *testing.T) {
+	s := NewScheduler()
+	defer s.Stop()
+	r := NewRetryer(WithAttempts(3), WithDelay(time.Millisecond*5), WithScheduler(s))
+	const n = 100
+	var calls int32
+	futures := make([]*Future[struct{}], n)
+	for i := range futures {
+		futures[i] = r.Go(context.Background(), func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			if AttemptFromContext(ctx) < 3 {
+				return errTestSentinel
+			}
+			return nil
+		})
+	}
+	for _, f := range futures {
+		assert.Equal(t, true, f.Err() == nil)
+	}
+	assert.Equal(t, int32(n*3), atomic.LoadInt32(&calls))
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestRetryerGoExhausted(t *testing.T) {
+	r := NewRetryer(WithAttempts(2), WithDelay(time.Millisecond))
+	err := r.Go(context.Background(), func(ctx context.Context) error {
+		return errTestSentinel
+	}).Err()
+	assert.Equal(t, &ExhaustedError{Attempts: 2, LastErr: errTestSentinel}, err)
+}
+
+func TestRetryerGoCancelParked(t *testing.T) {
+	s := NewScheduler()
+	defer s.Stop()
+	r := NewRetryer(WithDelay(time.Hour), WithScheduler(s))
+	f := r.Go(context.Background(), func(ctx context.Context) error {
+		return errTestSentinel
+	})
+	for s.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	f.Cancel()
+	assert.Equal(t, context.Canceled, f.Err())
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	s := NewScheduler()
+	defer s.Stop()
+	fired := make(chan int, 3)
+	s.after(time.Millisecond*30, func() { fired <- 3 })
+	s.after(time.Millisecond*10, func() { fired <- 1 })
+	e := s.after(time.Millisecond*20, func() { fired <- 2 })
+	assert.Equal(t, true, s.cancel(e))
+	assert.Equal(t, false, s.cancel(e))
+	assert.Equal(t, 1, <-fired)
+	assert.Equal(t, 3, <-fired)
+}
+
+func TestRetryerGoDecidesAsRun(t *testing.T) {
+	blocking := func(ctx context.Context, calls *int32) error {
+		atomic.AddInt32(calls, 1)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	failing := func(ctx context.Context, calls *int32) error {
+		atomic.AddInt32(calls, 1)
+		return errTestSentinel
+	}
+	var stopAfter int32
+	for _, tc := range []struct {
+		name string
+		fn   func(ctx context.Context, calls *int32) error
+		opts []Option
+	}{
+		{"attempt timeout", blocking, []Option{WithAttempts(3), WithDelay(time.Millisecond), WithAttemptTimeout(time.Millisecond * 5)}},
+		{"max elapsed time", failing, []Option{WithDelay(time.Hour), WithMaxElapsedTime(time.Millisecond * 20)}},
+		{"stop func", failing, []Option{WithAttempts(5), WithDelay(time.Millisecond), WithStopFunc(func() bool {
+			return atomic.LoadInt32(&stopAfter) >= 2
+		})}},
+		{"max total attempts", failing, []Option{WithAttempts(5), WithDelay(time.Millisecond), WithMaxTotalAttempts(2)}},
+	} {
+		var runCalls, goCalls int32
+		atomic.StoreInt32(&stopAfter, 0)
+		r := NewRetryer(tc.opts...)
+		runErr := r.Run(context.Background(), func(ctx context.Context) error {
+			atomic.AddInt32(&stopAfter, 1)
+			return tc.fn(ctx, &runCalls)
+		})
+		atomic.StoreInt32(&stopAfter, 0)
+		goErr := r.Go(context.Background(), func(ctx context.Context) error {
+			atomic.AddInt32(&stopAfter, 1)
+			return tc.fn(ctx, &goCalls)
+		}).Err()
+		assert.Equal(t, runErr, goErr, tc.name)
+		assert.Equal(t, atomic.LoadInt32(&runCalls), atomic.LoadInt32(&goCalls), tc.name)
+	}
+}
@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"sync"
+)
+
+// SingleFlight shares the retries of concurrent identical operations, like golang.org/x/sync/singleflight:
+// the callers running an operation of the same key while one is in flight wait for it and all get its
+// result, so N goroutines don't retry a down dependency in parallel. The operations are retried as
+// configured by the options it's created with, see Do. It's safe for concurrent use
+type SingleFlight[T any] struct {
+	opts *options
+
+	mu      sync.Mutex
+	flights map[string]*flight[T]
+}
+
+// flight is an operation in flight and its callers
+type flight[T any] struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	// callers is the number of callers of the operation, waiters the ones still waiting for it,
+	// they're guarded by the mutex of the SingleFlight
+	callers int
+	waiters int
+	value   T
+	err     error
+}
+
+// NewSingleFlight returns a SingleFlight retrying its operations as configured by opts
+func NewSingleFlight[T any](opts ...Option) *SingleFlight[T] {
+	o := newOptions(opts)
+	if o.matcher == nil {
+		o.matcher = CompilePolicies(o.policies)
+	}
+	return &SingleFlight[T]{opts: o, flights: map[string]*flight[T]{}}
+}
+
+// Do runs fn with retries, unless an operation of key is already in flight, in which case it waits for
+// that operation instead. Every caller gets the same value and error, and shared reports whether they
+// went to another caller too. The operation runs with the values of the context of the caller that started
+// it, and is cancelled once every caller has returned, so a caller whose ctx is done returns ctx.Err()
+// right away without cutting the retries of the others short
+func (s *SingleFlight[T]) Do(ctx context.Context, key string, fn FuncTContext[T]) (value T, shared bool, err error) {
+	s.mu.Lock()
+	f, ok := s.flights[key]
+	if !ok {
+		f = &flight[T]{done: make(chan struct{})}
+		var flightCtx context.Context
+		flightCtx, f.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		s.flights[key] = f
+		go s.run(flightCtx, key, f, fn)
+	}
+	f.callers++
+	f.waiters++
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		// no caller joins once the operation is done
+		s.mu.Lock()
+		shared = f.callers > 1
+		f.waiters--
+		s.mu.Unlock()
+		return f.value, shared, f.err
+	case <-ctx.Done():
+		s.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// nobody waits for the operation anymore, the next caller starts a new one
+			f.cancel()
+			if s.flights[key] == f {
+				delete(s.flights, key)
+			}
+		}
+		s.mu.Unlock()
+		var zero T
+		return zero, false, ctx.Err()
+	}
+}
+
+// run retries fn, then hands its result to the callers waiting for f
+func (s *SingleFlight[T]) run(ctx context.Context, key string, f *flight[T], fn FuncTContext[T]) {
+	defer f.cancel()
+	f.value, f.err = execute(ctx, s.opts, fn)
+	s.mu.Lock()
+	if s.flights[key] == f {
+		delete(s.flights, key)
+	}
+	s.mu.Unlock()
+	close(f.done)
+}
+
+// Forget makes the next caller of key start a new operation instead of waiting for the one in flight
+func (s *SingleFlight[T]) Forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.flights, key)
+}
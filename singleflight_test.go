@@ -0,0 +1,161 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSingleFlightShares(t *testing.T) {
+	s := NewSingleFlight[string](WithAttempts(3), WithDelay(time.Millisecond*10))
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			<-release
+			return "", errTestSentinel
+		}
+		return "value", nil
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	values := make([]string, callers)
+	shared := make([]bool, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], shared[i], errs[i] = s.Do(context.Background(), "key", fn)
+		}(i)
+	}
+	// wait for every caller to join the flight
+	for {
+		s.mu.Lock()
+		f := s.flights["key"]
+		joined := f != nil && f.callers == callers
+		s.mu.Unlock()
+		if joined {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	for i := 0; i < callers; i++ {
+		assert.Equal(t, "value", values[i])
+		assert.Equal(t, true, shared[i])
+		assert.Equal(t, nil, errs[i])
+	}
+	assert.Equal(t, int32(2), calls.Load())
+
+	// the next caller starts a new operation
+	value, isShared, err := s.Do(context.Background(), "key", fn)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, false, isShared)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestSingleFlightCallerCancelled(t *testing.T) {
+	s := NewSingleFlight[int](WithAttempts(1))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var flightErr error
+	fn := func(ctx context.Context) (int, error) {
+		close(started)
+		select {
+		case <-release:
+			return 1, nil
+		case <-ctx.Done():
+			flightErr = ctx.Err()
+			return 0, ctx.Err()
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var first int
+	var firstErr error
+	go func() {
+		defer wg.Done()
+		first, _, firstErr = s.Do(ctx, "key", fn)
+	}()
+	<-started
+
+	// the caller that started the operation leaves, the other one still gets its result
+	done := make(chan int)
+	go func() {
+		value, _, _ := s.Do(context.Background(), "key", fn)
+		done <- value
+	}()
+	for {
+		s.mu.Lock()
+		joined := s.flights["key"].callers == 2
+		s.mu.Unlock()
+		if joined {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+	assert.Equal(t, 0, first)
+	assert.Equal(t, context.Canceled, firstErr)
+	close(release)
+	assert.Equal(t, 1, <-done)
+	assert.Equal(t, nil, flightErr)
+}
+
+func TestSingleFlightCancelledWhenAbandoned(t *testing.T) {
+	s := NewSingleFlight[int](WithAttempts(1))
+	cancelled := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		cancel()
+	}()
+	_, _, err := s.Do(ctx, "key", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return 0, ctx.Err()
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, <-cancelled)
+
+	// the abandoned operation isn't joined
+	value, _, err := s.Do(context.Background(), "key", func(ctx context.Context) (int, error) { return 2, nil })
+	assert.Equal(t, 2, value)
+	assert.Equal(t, nil, err)
+}
+
+func TestSingleFlightForget(t *testing.T) {
+	s := NewSingleFlight[int]()
+	release := make(chan struct{})
+	go func() {
+		_, _, _ = s.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+			<-release
+			return 1, nil
+		})
+	}()
+	for {
+		s.mu.Lock()
+		_, ok := s.flights["key"]
+		s.mu.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Forget("key")
+	value, shared, err := s.Do(context.Background(), "key", func(ctx context.Context) (int, error) { return 2, nil })
+	assert.Equal(t, 2, value)
+	assert.Equal(t, false, shared)
+	assert.Equal(t, nil, err)
+	close(release)
+}
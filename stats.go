@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// PolicyStats are the counters of a policy accumulated by a Retryer, see Retryer.Stats
+type PolicyStats struct {
+	// Matches is the number of failed attempts the policy matched
+	Matches int64
+	// RetriedSuccesses is the number of operations that succeeded after the policy retried them
+	RetriedSuccesses int64
+	// Exhaustions is the number of operations given up on while the policy matched their error,
+	// because its RetryLimit, a timing option or the budget stopped the retry
+	Exhaustions int64
+	// TotalDelay is the sum of the delays waited before the retries of the policy
+	TotalDelay time.Duration
+}
+
+// policyStats accumulates the PolicyStats of a Retryer by policy name
+type policyStats struct {
+	mu     sync.Mutex
+	byName map[string]*PolicyStats
+}
+
+func newPolicyStats() *policyStats {
+	return &policyStats{byName: map[string]*PolicyStats{}}
+}
+
+// update applies fn to the counters of the policy named name
+func (s *policyStats) update(name string, fn func(*PolicyStats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.byName[name]
+	if !ok {
+		stats = &PolicyStats{}
+		s.byName[name] = stats
+	}
+	fn(stats)
+}
+
+// Stats returns the counters of the policies of the Retryer by Policy.Name, since it was created,
+// so the failures dominating the retries can be spotted without a MetricsCollector.
+// The policies without a name are counted together under ""
+func (r *Retryer) Stats() map[string]PolicyStats {
+	s := r.opts.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]PolicyStats, len(s.byName))
+	for name, st := range s.byName {
+		stats[name] = *st
+	}
+	return stats
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryerStats(t *testing.T) {
+	r := NewRetryer(WithPolicies([]Policy{
+		{Name: "throttled", ErrorCodeString: "throttled", RetryLimit: 2, DelayDuration: time.Millisecond},
+		{ErrorCodeString: "unavailable", RetryLimit: 1, DelayDuration: time.Millisecond},
+	}))
+	var calls int
+	err := r.Run(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 2 {
+			return errors.New("throttled")
+		}
+		return nil
+	})
+	assert.Equal(t, true, err == nil)
+	err = r.Run(context.Background(), func(ctx context.Context) error {
+		return errors.New("throttled")
+	})
+	assert.Equal(t, true, err != nil)
+	err = r.Run(context.Background(), func(ctx context.Context) error {
+		return errors.New("unavailable")
+	})
+	assert.Equal(t, true, err != nil)
+	err = r.Run(context.Background(), func(ctx context.Context) error {
+		return errors.New("something else")
+	})
+	assert.Equal(t, true, err != nil)
+
+	stats := r.Stats()
+	assert.Equal(t, 2, len(stats))
+	throttled := stats["throttled"]
+	assert.Equal(t, int64(4), throttled.Matches)
+	assert.Equal(t, int64(1), throttled.RetriedSuccesses)
+	assert.Equal(t, int64(1), throttled.Exhaustions)
+	assert.Equal(t, 3*time.Millisecond, throttled.TotalDelay)
+	unnamed := stats[""]
+	assert.Equal(t, int64(2), unnamed.Matches)
+	assert.Equal(t, int64(1), unnamed.Exhaustions)
+	assert.Equal(t, time.Millisecond, unnamed.TotalDelay)
+}
+
+func TestRetryerStatsGo(t *testing.T) {
+	r := NewRetryer(WithPolicies([]Policy{
+		{Name: "throttled", ErrorCodeString: "throttled", RetryLimit: 2, DelayDuration: time.Millisecond},
+	}))
+	var calls int
+	_, err := r.Go(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("throttled")
+		}
+		return nil
+	}).Wait()
+	assert.Equal(t, true, err == nil)
+	stats := r.Stats()["throttled"]
+	assert.Equal(t, int64(2), stats.Matches)
+	assert.Equal(t, int64(1), stats.RetriedSuccesses)
+	assert.Equal(t, int64(0), stats.Exhaustions)
+}
@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// stepStateVersion is the first byte of the states returned by Retryer.Step, version 1 didn't
+// have the state of the policy retrying the results
+const stepStateVersion = 2
+
+// Step is the outcome of a failed attempt driven by Retryer.Step
+type Step struct {
+	// Retry reports whether the operation is retried
+	Retry bool
+	// Delay is the delay before the next attempt
+	Delay time.Duration
+	// Attempt is the number of the attempt that was evaluated, starting at 1
+	Attempt int
+	// Decision tells which policy matched the error, and why the operation isn't retried
+	Decision Decision
+}
+
+// Step evaluates err, the error of the attempt of an operation whose state is given, and returns whether
+// and when to retry along with the state to give to the Step of the next attempt. It doesn't run nor wait
+// for anything, so frameworks with their own schedulers, i.e: game loops or actor systems, can drive the
+// retries without goroutines or sleeps. The state is an opaque blob that can be stored with the operation,
+// it's nil for the first attempt and once the operation isn't retried. A state that can't be decoded, or
+// that was returned by a Retryer with another number of policies, starts a new sequence. A nil err ends
+// the operation. The Stats, the events and the burn rates only count the operations the Retryer runs
+func (r *Retryer) Step(state []byte, err error) (Step, []byte) {
+	e := &PolicyEvaluator{o: r.opts, attempt: 1}
+	decodeStepState(e, state)
+	step := Step{Attempt: e.attempt}
+	if err == nil {
+		step.Decision.PolicyIndex = -1
+		return step, nil
+	}
+	step.Decision = e.Evaluate(err)
+	if !step.Decision.Retry {
+		return step, nil
+	}
+	step.Retry, step.Delay = true, step.Decision.Delay
+	return step, encodeStepState(e)
+}
+
+// encodeStepState returns the attempt, the policy states and the state of the policy retrying the
+// results of e as a blob
+func encodeStepState(e *PolicyEvaluator) []byte {
+	b := make([]byte, 0, 4+(len(e.states)+1)*4)
+	b = append(b, stepStateVersion)
+	b = binary.AppendUvarint(b, uint64(e.attempt))
+	b = binary.AppendUvarint(b, uint64(len(e.states)))
+	for _, s := range e.states {
+		b = appendPolicyState(b, s)
+	}
+	return appendPolicyState(b, e.result)
+}
+
+// appendPolicyState appends s to b
+func appendPolicyState(b []byte, s policyState) []byte {
+	b = binary.AppendUvarint(b, uint64(s.retries))
+	return binary.AppendVarint(b, int64(s.delay))
+}
+
+// decodeStepState restores the attempt and the policy states of e from b, e is left as is if b is invalid
+func decodeStepState(e *PolicyEvaluator, b []byte) {
+	if len(b) == 0 || b[0] != stepStateVersion {
+		return
+	}
+	b = b[1:]
+	next := func() (uint64, bool) {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, false
+		}
+		b = b[n:]
+		return v, true
+	}
+	nextState := func() (policyState, bool) {
+		retries, ok := next()
+		if !ok {
+			return policyState{}, false
+		}
+		delay, n := binary.Varint(b)
+		if n <= 0 {
+			return policyState{}, false
+		}
+		b = b[n:]
+		return policyState{retries: int(retries), delay: time.Duration(delay)}, true
+	}
+	attempt, ok := next()
+	if !ok || attempt < 1 {
+		return
+	}
+	count, ok := next()
+	if !ok || count != uint64(len(e.o.policies)) {
+		return
+	}
+	states := make([]policyState, count)
+	for i := range states {
+		if states[i], ok = nextState(); !ok {
+			return
+		}
+	}
+	result, ok := nextState()
+	if !ok || len(b) != 0 {
+		return
+	}
+	e.attempt, e.states, e.result = int(attempt), states, result
+}
@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryerStep(t *testing.T) {
+	r := NewRetryer(WithPolicies([]Policy{
+		{ErrorCodeString: "timed out", DelayDuration: time.Second, RetryLimit: 2, Backoff: ExponentialBackoff},
+		{ErrorCodeString: "busy", DelayDuration: time.Millisecond, RetryLimit: 1},
+	}))
+	errTimeout := errors.New("timed out")
+
+	step, state := r.Step(nil, errTimeout)
+	assert.Equal(t, Step{Retry: true, Delay: time.Second, Attempt: 1, Decision: step.Decision}, step)
+	assert.Equal(t, 0, step.Decision.PolicyIndex)
+	assert.Equal(t, true, state != nil)
+
+	// the state is a value, the same one evaluates the same way
+	again, _ := r.Step(state, errTimeout)
+	step, state = r.Step(state, errTimeout)
+	assert.Equal(t, again, step)
+	assert.Equal(t, true, step.Retry)
+	assert.Equal(t, time.Second*2, step.Delay)
+	assert.Equal(t, 2, step.Attempt)
+
+	step, busy := r.Step(state, errors.New("busy"))
+	assert.Equal(t, true, step.Retry)
+	assert.Equal(t, 1, step.Decision.PolicyIndex)
+	assert.Equal(t, 3, step.Attempt)
+
+	step, state = r.Step(busy, errTimeout)
+	assert.Equal(t, false, step.Retry)
+	assert.Equal(t, ReasonLimitReached, step.Decision.Reason)
+	assert.Equal(t, 4, step.Attempt)
+	assert.Equal(t, []byte(nil), state)
+
+	step, state = r.Step(busy, nil)
+	assert.Equal(t, Step{Attempt: 4, Decision: Decision{PolicyIndex: -1}}, step)
+	assert.Equal(t, []byte(nil), state)
+
+	step, _ = r.Step(nil, errors.New("other"))
+	assert.Equal(t, false, step.Retry)
+	assert.Equal(t, ReasonNoPolicy, step.Decision.Reason)
+}
+
+func TestRetryerStepInvalidState(t *testing.T) {
+	r := NewRetryer(WithPolicies([]Policy{{ErrorCodeString: "busy", DelayDuration: time.Millisecond, RetryLimit: 1}}))
+	_, state := r.Step(nil, errors.New("busy"))
+	other := NewRetryer(WithPolicies([]Policy{{ErrorCodeString: "busy"}, {ErrorCodeString: "down"}}))
+
+	for _, invalid := range [][]byte{
+		{0xff, 1, 1},
+		state[:len(state)-1],
+		append(append([]byte(nil), state...), 0),
+	} {
+		step, _ := r.Step(invalid, errors.New("busy"))
+		assert.Equal(t, true, step.Retry)
+		assert.Equal(t, 1, step.Attempt)
+	}
+	step, _ := other.Step(state, errors.New("busy"))
+	assert.Equal(t, 1, step.Attempt)
+}
+
+func TestRetryerStepRetryIfResult(t *testing.T) {
+	r := NewRetryer(WithPolicies([]Policy{{ErrorCodeString: "busy", DelayDuration: time.Millisecond, RetryLimit: 5}}),
+		WithRetryIfResult(func(n int) bool { return n == 0 }), WithAttempts(2))
+
+	// the results are retried by the default policy, whose state is carried by the state too
+	step, state := r.Step(nil, ErrRetryableResult)
+	assert.Equal(t, true, step.Retry)
+	assert.Equal(t, -1, step.Decision.PolicyIndex)
+	step, state = r.Step(state, ErrRetryableResult)
+	assert.Equal(t, false, step.Retry)
+	assert.Equal(t, ReasonLimitReached, step.Decision.Reason)
+	assert.Equal(t, 2, step.Attempt)
+	assert.Equal(t, []byte(nil), state)
+
+	// a state of the previous version starts a new sequence
+	step, _ = r.Step([]byte{1, 2, 1, 0, 0}, ErrRetryableResult)
+	assert.Equal(t, true, step.Retry)
+	assert.Equal(t, 1, step.Attempt)
+}
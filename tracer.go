@@ -0,0 +1,27 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer traces retry sequences, so distributed traces show the attempts of an operation.
+// See the retryotel package for an OpenTelemetry implementation
+type Tracer interface {
+	// StartOperation is called before the first attempt, the returned context is the parent of
+	// every attempt and end is called with the error returned by the executor
+	StartOperation(ctx context.Context) (context.Context, func(err error))
+	// StartAttempt is called before every attempt with its number starting at 1, the returned
+	// context is passed to the attempt and end is called with its error
+	StartAttempt(ctx context.Context, attempt int) (context.Context, func(err error))
+	// Delay is called with the context returned by StartOperation before waiting delay
+	// after the failed attempt
+	Delay(ctx context.Context, attempt int, delay time.Duration)
+}
+
+// WithTracer traces every retry sequence with t
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
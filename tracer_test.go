@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTraceKey struct{}
+
+type testTracer struct {
+	events []string
+}
+
+func (t *testTracer) StartOperation(ctx context.Context) (context.Context, func(err error)) {
+	t.events = append(t.events, "start operation")
+	return context.WithValue(ctx, testTraceKey{}, "operation"), func(err error) {
+		t.events = append(t.events, fmt.Sprintf("end operation: %v", err))
+	}
+}
+
+func (t *testTracer) StartAttempt(ctx context.Context, attempt int) (context.Context, func(err error)) {
+	t.events = append(t.events, fmt.Sprintf("start attempt %d in %v", attempt, ctx.Value(testTraceKey{})))
+	return context.WithValue(ctx, testTraceKey{}, fmt.Sprintf("attempt %d", attempt)), func(err error) {
+		t.events = append(t.events, fmt.Sprintf("end attempt %d: %v", attempt, err))
+	}
+}
+
+func (t *testTracer) Delay(ctx context.Context, attempt int, delay time.Duration) {
+	t.events = append(t.events, fmt.Sprintf("delay %v after attempt %d in %v", delay, attempt, ctx.Value(testTraceKey{})))
+}
+
+func TestWithTracer(t *testing.T) {
+	tracer := &testTracer{}
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		assert.Equal(t, fmt.Sprintf("attempt %d", calls), ctx.Value(testTraceKey{}))
+		return errors.New("down")
+	}, WithAttempts(2), WithDelay(time.Millisecond*10), WithTracer(tracer))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, []string{
+		"start operation",
+		"start attempt 1 in operation",
+		"end attempt 1: down",
+		"delay 10ms after attempt 1 in operation",
+		"start attempt 2 in operation",
+		"end attempt 2: down",
+		"end operation: down",
+	}, tracer.events)
+}
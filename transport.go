@@ -0,0 +1,115 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that retries the requests made through it according to Policies.
+// The response of the last attempt is returned as is, so the http.Client and the caller
+// see a regular response even when the retries are exhausted.
+// A request with a body is only retried if its GetBody is set, which http.NewRequest does
+// for the common body types
+type Transport struct {
+	// Base is the RoundTripper used to make the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+	// Policies are the retry policies, GetRetryPolicies(HTTPPolicy) if nil
+	Policies []Policy
+	// Options are applied to every retry sequence
+	Options []Option
+	// Breakers stops retrying the requests to the hosts that keep failing, nil means every
+	// request is retried
+	Breakers *HostBreakers
+	// BeforeRetry is called before every retry with the request about to be sent, a clone of the
+	// original one, and the failed response of the previous attempt, whose body is already closed,
+	// nil if the previous attempt didn't get a response. It may change the request, i.e: to refresh
+	// an OAuth token after a 401 or to rotate the API key after a 403, provided a policy matches these
+	// statuses. The retry is abandoned with its error
+	BeforeRetry func(req *http.Request, resp *http.Response) error
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	policies := WithPolicies(t.Policies)
+	if t.Policies == nil {
+		policies = WithCompiledPolicies(compiledPolicyType(HTTPPolicy))
+	}
+	opts := newOptions(append([]Option{policies}, t.Options...))
+	if t.Breakers == nil {
+		return t.roundTrip(req, base, opts)
+	}
+	host := req.URL.Host
+	var resp *http.Response
+	var err error
+	if t.Breakers.allow(host) {
+		resp, err = t.roundTrip(req, base, opts)
+	} else {
+		resp, err = base.RoundTrip(req)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrBreakerOpen, err)
+		}
+	}
+	t.Breakers.record(host, err != nil || opts.failed(resp) && matchesResponse(opts, resp))
+	return resp, err
+}
+
+// matchesResponse reports whether resp is a failure matching the policies of o
+func matchesResponse(o *options, resp *http.Response) bool {
+	_, ok := o.matchPolicy(&statusError{resp: resp})
+	return ok
+}
+
+// roundTrip sends req with base, and retries it according to opts
+func (t *Transport) roundTrip(req *http.Request, base http.RoundTripper, opts *options) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// the body can't be rewound so the request can only be sent once
+		return base.RoundTrip(req)
+	}
+	if opts.idempotentOnly && !IsRetryableRequest(req) {
+		return base.RoundTrip(req)
+	}
+
+	call := &httpCall{drainLimit: opts.drainLimit, classify: opts.statusClass}
+	call.fn = func(ctx context.Context) (*http.Response, error) {
+		// every attempt is bound to its own context, so an attempt timeout cancels its request
+		r := req.Clone(ctx)
+		if AttemptFromContext(ctx) == 1 {
+			return base.RoundTrip(r)
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		if t.BeforeRetry != nil {
+			if err := t.BeforeRetry(r, call.previous()); err != nil {
+				if r.Body != nil {
+					_ = r.Body.Close()
+				}
+				return nil, Unrecoverable(err)
+			}
+		}
+		return base.RoundTrip(r)
+	}
+	call.peekBodies(opts)
+	// the response is read by the caller, so the contexts of the attempt are released with its body
+	resp, err := executeOperation(req.Context(), opts, operation[*http.Response]{fn: call.attempt, bind: bindBody}, nil)
+	var se *statusError
+	if errors.As(err, &se) && call.isLast(se.resp) {
+		// the retries are exhausted or the failure is fatal, the failed response is handed back as is
+		return se.resp, nil
+	}
+	call.close()
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,333 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testTransportPolicies = []Policy{
+	{
+		ErrorCodeNumber: http.StatusServiceUnavailable,
+		ErrorCodeString: http.StatusText(http.StatusServiceUnavailable),
+		DelayDuration:   time.Millisecond * 10,
+		RetryLimit:      3,
+	},
+}
+
+type testCloseTracker struct {
+	io.ReadCloser
+	closed *int32
+}
+
+func (c testCloseTracker) Close() error {
+	atomic.AddInt32(c.closed, 1)
+	return c.ReadCloser.Close()
+}
+
+type testTrackingTransport struct {
+	base   http.RoundTripper
+	closed int32
+}
+
+func (t *testTrackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		resp.Body = testCloseTracker{ReadCloser: resp.Body, closed: &t.closed}
+	}
+	return resp, err
+}
+
+type testRoundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f testRoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransportAttemptTimeout(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []int
+	var first context.Context
+	base := testRoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		attempts = append(attempts, AttemptFromContext(req.Context()))
+		n := len(attempts)
+		if n == 1 {
+			first = req.Context()
+		}
+		mu.Unlock()
+		if n == 1 {
+			// the first attempt is bound to its own context too, so it's canceled by its timeout
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return &http.Response{StatusCode: http.StatusOK, Status: http.StatusText(http.StatusOK), Body: http.NoBody}, nil
+	})
+	client := &http.Client{Transport: &Transport{
+		Base:     base,
+		Policies: []Policy{{RetryIf: func(err error) bool { return true }, DelayDuration: time.Millisecond, RetryLimit: 1}},
+		Options:  []Option{WithAttemptTimeout(time.Millisecond * 20)},
+	}}
+	resp, err := client.Get("http://example.invalid")
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, ErrAttemptTimeout, context.Cause(first))
+}
+
+func TestTransportRecovered(t *testing.T) {
+	var calls int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("unavailable"))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tracking := &testTrackingTransport{base: http.DefaultTransport}
+	client := &http.Client{Transport: &Transport{Base: tracking, Policies: testTransportPolicies}}
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	assert.Equal(t, true, err == nil)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", string(b))
+	// the body is rewound on every attempt
+	assert.Equal(t, []string{"payload", "payload", "payload"}, bodies)
+	// both failed responses are closed
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tracking.closed))
+}
+
+func TestTransportNotRecovered(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Policies: testTransportPolicies}}
+	resp, err := client.Get(server.URL)
+	assert.Equal(t, true, err == nil)
+	defer resp.Body.Close()
+	// the last response is handed back to the caller as is
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+}
+
+func TestTransportNonRewindableBody(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, io.NopCloser(strings.NewReader("payload")))
+	client := &http.Client{Transport: &Transport{Policies: testTransportPolicies}}
+	resp, err := client.Do(req)
+	assert.Equal(t, true, err == nil)
+	defer resp.Body.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestTransportContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policies := []Policy{
+		{
+			ErrorCodeNumber: http.StatusServiceUnavailable,
+			ErrorCodeString: http.StatusText(http.StatusServiceUnavailable),
+			DelayDuration:   time.Hour,
+			RetryLimit:      3,
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	client := &http.Client{Transport: &Transport{Policies: policies}}
+	_, err := client.Do(req)
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, true, strings.Contains(err.Error(), context.DeadlineExceeded.Error()))
+}
+
+func TestTransportBeforeRetry(t *testing.T) {
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var statuses []int
+	client := &http.Client{Transport: &Transport{
+		Policies: []Policy{{StatusCodes: []int{http.StatusUnauthorized}, DelayDuration: time.Millisecond, RetryLimit: 1}},
+		BeforeRetry: func(req *http.Request, resp *http.Response) error {
+			statuses = append(statuses, resp.StatusCode)
+			req.Header.Set("Authorization", "Bearer fresh")
+			return nil
+		},
+	}}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Authorization", "Bearer expired")
+	resp, err := client.Do(req)
+	assert.Equal(t, true, err == nil)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"Bearer expired", "Bearer fresh"}, auths)
+	assert.Equal(t, []int{http.StatusUnauthorized}, statuses)
+	// the original request isn't changed
+	assert.Equal(t, "Bearer expired", req.Header.Get("Authorization"))
+}
+
+func TestTransportBeforeRetryError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	errRefresh := errors.New("refresh failed")
+	client := &http.Client{Transport: &Transport{
+		Policies: testTransportPolicies,
+		BeforeRetry: func(req *http.Request, resp *http.Response) error {
+			return errRefresh
+		},
+	}}
+	_, err := client.Get(server.URL)
+	assert.Equal(t, true, errors.Is(err, errRefresh))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestTransportBodyPeek(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			_, _ = w.Write([]byte(`{"error": "ThrottlingException"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"result": "ok"}`))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{
+		Policies: []Policy{{BodyContains: "ThrottlingException", DelayDuration: time.Millisecond, RetryLimit: 1}},
+		Options:  []Option{WithBodyPeek(64)},
+	}}
+	resp, err := client.Get(server.URL)
+	assert.Equal(t, true, err == nil)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"result": "ok"}`, string(b))
+	assert.Equal(t, 2, calls)
+}
+
+// testStreamServer is a server whose response body is streamed: the first part is flushed with
+// the headers, the rest once release is closed
+func testStreamServer(t *testing.T, release chan struct{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("head"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+			_, _ = w.Write([]byte("-tail"))
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTransportStreamedBody(t *testing.T) {
+	release := make(chan struct{})
+	server := testStreamServer(t, release)
+	var mu sync.Mutex
+	var attemptCtx context.Context
+	base := testRoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		attemptCtx = req.Context()
+		mu.Unlock()
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	client := &http.Client{Transport: &Transport{Base: base, Options: []Option{WithAttemptTimeout(time.Second)}}}
+	resp, err := client.Get(server.URL)
+	assert.Equal(t, true, err == nil)
+	// the body is read after RoundTrip returned, its attempt is still alive
+	close(release)
+	b, err := io.ReadAll(resp.Body)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, "head-tail", string(b))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, true, attemptCtx.Err() == nil)
+	// closing the body releases the context of the attempt
+	_ = resp.Body.Close()
+	assert.Equal(t, true, attemptCtx.Err() != nil)
+}
+
+func TestTransportMaxElapsedTimeStreamedBody(t *testing.T) {
+	release := make(chan struct{})
+	server := testStreamServer(t, release)
+	client := &http.Client{Transport: &Transport{Options: []Option{WithMaxElapsedTime(time.Second)}}}
+	resp, err := client.Get(server.URL)
+	assert.Equal(t, true, err == nil)
+	// the context of WithMaxElapsedTime lives on with the body
+	close(release)
+	b, err := io.ReadAll(resp.Body)
+	assert.Equal(t, true, err == nil)
+	assert.Equal(t, "head-tail", string(b))
+	_ = resp.Body.Close()
+}
+
+func TestTransportBeforeRetryErrorNotRetried(t *testing.T) {
+	var calls int32
+	base := testRoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("connection refused")
+	})
+	errRefresh := errors.New("refresh failed")
+	var hooks int
+	var resps []*http.Response
+	client := &http.Client{Transport: &Transport{
+		Base:     base,
+		Policies: []Policy{{RetryIf: func(err error) bool { return true }, DelayDuration: time.Millisecond, RetryLimit: 3}},
+		BeforeRetry: func(req *http.Request, resp *http.Response) error {
+			hooks++
+			resps = append(resps, resp)
+			return errRefresh
+		},
+	}}
+	_, err := client.Get("http://example.invalid")
+	assert.Equal(t, true, errors.Is(err, errRefresh))
+	// the error of the hook abandons the retry whatever the policies match
+	assert.Equal(t, 1, hooks)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	// the previous attempt didn't get a response
+	assert.Equal(t, []*http.Response{nil}, resps)
+}
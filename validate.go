@@ -0,0 +1,118 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPolicy is wrapped by the errors of Policy.Validate and ValidatePolicies
+var ErrInvalidPolicy = errors.New("retry: invalid policy")
+
+// Validate reports the settings of the policy that make no sense, i.e: a negative delay, a
+// RetryLimit that never retries, or no match criteria, which matches every error.
+// A policy meant to match every error sets RetryIf. The policies loaded from a configuration
+// are best validated at startup, since an invalid policy silently never retries at runtime
+func (p Policy) Validate() error {
+	if problems := p.validate(); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidPolicy, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidatePolicies validates every policy, see Policy.Validate, and also reports the policies
+// that can never match because an earlier one matches all their errors
+func ValidatePolicies(policies []Policy) error {
+	var errs []error
+	for i, p := range policies {
+		problems := p.validate()
+		for j := 0; j < i; j++ {
+			if policies[j].shadows(p) {
+				problems = append(problems, fmt.Sprintf("never matches, policy %d matches its errors first", j))
+				break
+			}
+		}
+		if len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("%w %d: %s", ErrInvalidPolicy, i, strings.Join(problems, "; ")))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate returns the problems of the policy
+func (p Policy) validate() []string {
+	var problems []string
+	if !p.hasMatchers() {
+		if p.ErrorCodeString == "" {
+			problems = append(problems, "no match criteria, the policy matches every error, set RetryIf to do so on purpose")
+		}
+		if p.ErrorCodeNumber < 0 {
+			problems = append(problems, "negative ErrorCodeNumber")
+		}
+		if p.MatchMode == MatchRegex {
+			if _, err := compileCodePattern(p.ErrorCodeString); err != nil {
+				problems = append(problems, "invalid ErrorCodeString: "+err.Error())
+			}
+		}
+	}
+	if _, ok := matchModeNames[p.MatchMode]; !ok {
+		problems = append(problems, "unknown "+p.MatchMode.String())
+	}
+	for _, r := range p.StatusRanges {
+		if r.From > r.To {
+			problems = append(problems, "empty StatusRange "+r.String())
+		}
+	}
+	if p.RetryLimit < 1 {
+		problems = append(problems, "RetryLimit below 1, the policy never retries")
+	}
+	if p.DelayDuration < 0 {
+		problems = append(problems, "negative DelayDuration")
+	}
+	if p.MaxDelay < 0 {
+		problems = append(problems, "negative MaxDelay")
+	}
+	if p.MaxDelay > 0 && p.MaxDelay < p.DelayDuration {
+		problems = append(problems, "MaxDelay shorter than DelayDuration")
+	}
+	if p.MinDelay < 0 {
+		problems = append(problems, "negative MinDelay")
+	}
+	if p.MaxDelay > 0 && p.MinDelay > p.MaxDelay {
+		problems = append(problems, "MinDelay longer than MaxDelay")
+	}
+	if _, ok := backoffNames[p.Backoff]; !ok {
+		problems = append(problems, "unknown "+p.Backoff.String())
+	}
+	if _, ok := jitterNames[p.Jitter]; !ok {
+		problems = append(problems, "unknown "+p.Jitter.String())
+	}
+	if p.Multiplier != 0 {
+		if p.Backoff != ExponentialBackoff {
+			problems = append(problems, "Multiplier set without ExponentialBackoff")
+		}
+		if p.Multiplier < 1 {
+			problems = append(problems, "Multiplier below 1 shrinks the delay")
+		}
+	}
+	return problems
+}
+
+// hasMatchers reports whether the policy is matched with its match criteria rather than
+// its ErrorCodeNumber and ErrorCodeString, see Policy.matches
+func (p Policy) hasMatchers() bool {
+	return p.ErrorPattern != nil || len(p.StatusCodes) > 0 || len(p.StatusRanges) > 0 ||
+		p.MatchError != nil || p.MatchErrorType != nil || p.RetryIf != nil || p.RetryIfResponse != nil ||
+		p.matchesOnBody()
+}
+
+// shadows reports whether p matches every error other matches. Only the policies matched on
+// a substring of their ErrorCodeString are compared: p matches the errors and statuses containing its
+// ErrorCodeString, so it matches all those of other when other's contains p's
+func (p Policy) shadows(other Policy) bool {
+	if p.hasMatchers() || other.hasMatchers() || p.ErrorCodeString == "" ||
+		p.MatchMode != MatchSubstring || other.MatchMode != MatchSubstring {
+		return false
+	}
+	return strings.Contains(strings.ToLower(other.ErrorCodeString), strings.ToLower(p.ErrorCodeString))
+}
@@ -0,0 +1,44 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	valid := Policy{ErrorCodeString: "timed out", DelayDuration: time.Second, RetryLimit: 3}
+	assert.Equal(t, nil, valid.Validate())
+	assert.Equal(t, nil, Policy{RetryIf: func(error) bool { return true }, RetryLimit: 1}.Validate())
+
+	err := Policy{DelayDuration: -time.Second, MaxDelay: time.Millisecond}.Validate()
+	assert.Equal(t, true, errors.Is(err, ErrInvalidPolicy))
+	assert.Equal(t, "retry: invalid policy: no match criteria, the policy matches every error, set RetryIf to do so on purpose; "+
+		"RetryLimit below 1, the policy never retries; negative DelayDuration", err.Error())
+
+	err = Policy{ErrorCodeString: "timed out", RetryLimit: 1, DelayDuration: time.Second, MaxDelay: time.Millisecond,
+		Backoff: LinearBackoff, Multiplier: 0.5, Jitter: JitterType(9)}.Validate()
+	assert.Equal(t, "retry: invalid policy: MaxDelay shorter than DelayDuration; unknown JitterType(9); "+
+		"Multiplier set without ExponentialBackoff; Multiplier below 1 shrinks the delay", err.Error())
+
+	err = Policy{ErrorCodeString: "timed out", RetryLimit: 1, DelayDuration: time.Second, MaxDelay: time.Second, MinDelay: time.Minute}.Validate()
+	assert.Equal(t, "retry: invalid policy: MinDelay longer than MaxDelay", err.Error())
+	err = Policy{ErrorCodeString: "timed out", RetryLimit: 1, MinDelay: -time.Second}.Validate()
+	assert.Equal(t, "retry: invalid policy: negative MinDelay", err.Error())
+}
+
+func TestValidatePolicies(t *testing.T) {
+	for _, policyType := range []PolicyType{HTTPPolicy, StandardPolicy, StrictHTTPPolicy, NetworkPolicy, DatabasePolicy, CloudThrottlePolicy, ReconnectPolicy, IOPolicy} {
+		assert.Equal(t, nil, ValidatePolicies(GetRetryPolicies(policyType)), policyType.String())
+	}
+	err := ValidatePolicies([]Policy{
+		{ErrorCodeString: "timeout", RetryLimit: 1},
+		{ErrorCodeNumber: 504, ErrorCodeString: "Gateway Timeout", RetryLimit: 1},
+		{ErrorCodeString: "refused"},
+	})
+	assert.Equal(t, true, errors.Is(err, ErrInvalidPolicy))
+	assert.Equal(t, "retry: invalid policy 1: never matches, policy 0 matches its errors first\n"+
+		"retry: invalid policy 2: RetryLimit below 1, the policy never retries", err.Error())
+}
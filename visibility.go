@@ -0,0 +1,38 @@
+package retry
+
+import (
+	"time"
+)
+
+// MaxVisibilityTimeout is the longest visibility timeout of an Amazon SQS message,
+// NextVisibilityTimeoutSeconds doesn't exceed it
+const MaxVisibilityTimeout = time.Hour * 12
+
+// NextVisibilityTimeout returns the delay before redelivering a message whose attempt-th delivery
+// failed, starting at 1, i.e: the ApproximateReceiveCount of an SQS message. It's the delay the
+// executors wait before the retry of the same attempt, so the retries done by a broker follow the
+// same curve as the ones done in process. A broker doesn't keep the previous delay DecorrelatedJitter
+// is drawn from, the exponential growth of DelayDuration is used instead.
+// The RetryLimit isn't enforced, the broker's redrive policy usually does
+func (p Policy) NextVisibilityTimeout(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	var prev time.Duration
+	if p.Jitter == DecorrelatedJitter && attempt > 1 {
+		exponential := p
+		exponential.Backoff = ExponentialBackoff
+		prev = exponential.backoffDelay(attempt - 1)
+	}
+	return p.nextDelay(attempt, prev)
+}
+
+// NextVisibilityTimeoutSeconds is NextVisibilityTimeout in whole seconds, rounded up and capped by
+// MaxVisibilityTimeout, as the VisibilityTimeout of the SQS ChangeMessageVisibility API expects
+func (p Policy) NextVisibilityTimeoutSeconds(attempt int) int32 {
+	timeout := p.NextVisibilityTimeout(attempt)
+	if timeout > MaxVisibilityTimeout {
+		timeout = MaxVisibilityTimeout
+	}
+	return int32((timeout + time.Second - 1) / time.Second)
+}
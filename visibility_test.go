@@ -0,0 +1,31 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextVisibilityTimeout(t *testing.T) {
+	p := Policy{DelayDuration: time.Second * 10, Backoff: ExponentialBackoff, MaxDelay: time.Minute}
+	assert.Equal(t, time.Second*10, p.NextVisibilityTimeout(0))
+	assert.Equal(t, time.Second*10, p.NextVisibilityTimeout(1))
+	assert.Equal(t, time.Second*20, p.NextVisibilityTimeout(2))
+	assert.Equal(t, time.Second*40, p.NextVisibilityTimeout(3))
+	assert.Equal(t, time.Minute, p.NextVisibilityTimeout(4))
+
+	p = Policy{DelayDuration: time.Second, Jitter: DecorrelatedJitter, MaxDelay: time.Minute}
+	for i := 0; i < 100; i++ {
+		// drawn between DelayDuration and 3 times the exponential delay of the previous attempt
+		timeout := p.NextVisibilityTimeout(4)
+		assert.Equal(t, true, timeout >= time.Second && timeout <= time.Second*12)
+	}
+}
+
+func TestNextVisibilityTimeoutSeconds(t *testing.T) {
+	p := Policy{DelayDuration: time.Millisecond * 1500}
+	assert.Equal(t, int32(2), p.NextVisibilityTimeoutSeconds(1))
+	p = Policy{DelayDuration: time.Hour, Backoff: ExponentialBackoff}
+	assert.Equal(t, int32(43200), p.NextVisibilityTimeoutSeconds(10))
+}
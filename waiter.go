@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+)
+
+// Waiter realizes the delays between attempts, so the caller chooses how the executor waits:
+// on a timer, blocked in a sleep, spinning, or handing control over to an event loop, see QueueWaiter.
+// Without WithWaiter the Clock waits
+type Waiter interface {
+	// Wait waits for delay, or returns early with ctx.Err() when ctx is done
+	Wait(ctx context.Context, delay time.Duration) error
+}
+
+// WaiterFunc is a func implementing Waiter
+type WaiterFunc func(ctx context.Context, delay time.Duration) error
+
+// Wait implements Waiter
+func (f WaiterFunc) Wait(ctx context.Context, delay time.Duration) error {
+	return f(ctx, delay)
+}
+
+// WithWaiter sets the Waiter realizing the delays between attempts of Do, the executors, Forever
+// and the Retryer. The Clock still tells the time. Retryer.Go parks its operations in a Scheduler instead
+func WithWaiter(w Waiter) Option {
+	return func(o *options) {
+		o.waiter = w
+	}
+}
+
+// wait waits for d with the waiter, or the clock without one
+func (o *options) wait(ctx context.Context, d time.Duration) error {
+	if o.waiter != nil {
+		return o.waiter.Wait(ctx, d)
+	}
+	return o.clock.Sleep(ctx, d)
+}
+
+// TimerWaiter waits on a timer and returns as soon as ctx is done, as the wall clock does
+var TimerWaiter Waiter = WaiterFunc(realClock{}.Sleep)
+
+// SleepWaiter blocks in time.Sleep for the whole delay, even when ctx is done, then returns ctx.Err().
+// It spares the timer of TimerWaiter, for the callers that never cancel
+var SleepWaiter Waiter = WaiterFunc(func(ctx context.Context, delay time.Duration) error {
+	time.Sleep(delay)
+	return ctx.Err()
+})
+
+// SpinWaiter yields the processor to the other goroutines in a loop until the delay has passed or ctx is done.
+// It's more precise than a timer for the delays of a few microseconds, at the cost of a busy CPU
+var SpinWaiter Waiter = WaiterFunc(func(ctx context.Context, delay time.Duration) error {
+	deadline := time.Now().Add(delay)
+	for time.Now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		runtime.Gosched()
+	}
+	return ctx.Err()
+})
+
+// errWaitCancelled is returned by QueueWaiter.Wait when its token is cancelled
+var errWaitCancelled = errors.New("retry: wait cancelled")
+
+// QueueWaiter hands every delay over to an event loop as a WaitToken, sent on Tokens, and waits for
+// the loop to resume it. The loop decides when the next attempt is made, i.e: on the tick of a game
+// loop once the token is Due. It's safe for concurrent use
+type QueueWaiter struct {
+	tokens chan *WaitToken
+}
+
+// NewQueueWaiter returns a QueueWaiter whose Tokens channel has a buffer of size tokens
+func NewQueueWaiter(size int) *QueueWaiter {
+	return &QueueWaiter{tokens: make(chan *WaitToken, size)}
+}
+
+// Tokens returns the channel the tokens of the waiting operations are sent on
+func (q *QueueWaiter) Tokens() <-chan *WaitToken {
+	return q.tokens
+}
+
+// Wait implements Waiter, it blocks until the token is sent and resumed, or until ctx is done
+func (q *QueueWaiter) Wait(ctx context.Context, delay time.Duration) error {
+	token := &WaitToken{Delay: delay, Due: time.Now().Add(delay), resume: make(chan error, 1)}
+	select {
+	case q.tokens <- token:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-token.resume:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitToken is an operation waiting for its next attempt in a QueueWaiter
+type WaitToken struct {
+	// Delay is the delay the operation asked for
+	Delay time.Duration
+	// Due is when the delay is over
+	Due    time.Time
+	resume chan error
+}
+
+// Resume wakes up the operation for its next attempt, whether or not the token is Due.
+// Only the first call of Resume or Cancel counts
+func (t *WaitToken) Resume() {
+	t.wake(nil)
+}
+
+// Cancel wakes up the operation and gives up on it, the executor returns the error of its last attempt
+func (t *WaitToken) Cancel() {
+	t.wake(errWaitCancelled)
+}
+
+func (t *WaitToken) wake(err error) {
+	select {
+	case t.resume <- err:
+	default:
+	}
+}
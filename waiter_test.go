@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithWaiter(t *testing.T) {
+	var delays []time.Duration
+	waiter := WaiterFunc(func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	})
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("flaky")
+	}, WithAttempts(3), WithDelay(time.Hour), WithWaiter(waiter))
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Hour, time.Hour}, delays)
+}
+
+func TestWaiters(t *testing.T) {
+	for _, w := range []Waiter{TimerWaiter, SleepWaiter, SpinWaiter} {
+		start := time.Now()
+		assert.Equal(t, nil, w.Wait(context.Background(), time.Millisecond*5))
+		assert.Equal(t, true, time.Since(start) >= time.Millisecond*5)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, w := range []Waiter{TimerWaiter, SpinWaiter} {
+		assert.Equal(t, context.Canceled, w.Wait(ctx, time.Hour))
+	}
+	assert.Equal(t, context.Canceled, SleepWaiter.Wait(ctx, time.Millisecond))
+}
+
+func TestQueueWaiter(t *testing.T) {
+	q := NewQueueWaiter(1)
+	var calls int
+	done := make(chan error, 1)
+	go func() {
+		done <- Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return errors.New("flaky")
+			}
+			return nil
+		}, WithAttempts(3), WithDelay(time.Hour), WithWaiter(q))
+	}()
+
+	// the event loop resumes the operation long before its delay is over
+	for i := 0; i < 2; i++ {
+		token := <-q.Tokens()
+		assert.Equal(t, time.Hour, token.Delay)
+		assert.Equal(t, true, token.Due.After(time.Now()))
+		token.Resume()
+	}
+	assert.Equal(t, nil, <-done)
+	assert.Equal(t, 3, calls)
+
+	errFailed := errors.New("failed")
+	go func() {
+		done <- Do(context.Background(), func(ctx context.Context) error {
+			return errFailed
+		}, WithAttempts(3), WithDelay(time.Hour), WithWaiter(q))
+	}()
+	token := <-q.Tokens()
+	token.Cancel()
+	token.Resume()
+	assert.Equal(t, errFailed, <-done)
+}
@@ -0,0 +1,50 @@
+package retry
+
+import (
+	"context"
+)
+
+// Wrap returns fn retried as configured by opts, see Do, so a pre-wrapped func can be handed to the
+// code calling it, i.e: a worker pool, instead of wrapping it at every call site. The options are
+// evaluated and the policies compiled once, the returned func is safe for concurrent use if fn is
+func Wrap(fn Func, opts ...Option) Func {
+	wrapped := WrapContext(func(context.Context) error {
+		return fn()
+	}, opts...)
+	return func() error {
+		return wrapped(context.Background())
+	}
+}
+
+// WrapContext is the context-aware version of Wrap, the retry stops as soon as the ctx given to the
+// returned func is done
+func WrapContext(fn FuncContext, opts ...Option) FuncContext {
+	wrapped := WrapTContext(func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts...)
+	return func(ctx context.Context) error {
+		_, err := wrapped(ctx)
+		return err
+	}
+}
+
+// WrapT is the generic version of Wrap, the returned func returns the value of the last attempt
+func WrapT[T any](fn FuncT[T], opts ...Option) FuncT[T] {
+	wrapped := WrapTContext(func(context.Context) (T, error) {
+		return fn()
+	}, opts...)
+	return func() (T, error) {
+		return wrapped(context.Background())
+	}
+}
+
+// WrapTContext is the generic version of WrapContext
+func WrapTContext[T any](fn FuncTContext[T], opts ...Option) FuncTContext[T] {
+	o := newOptions(opts)
+	if o.matcher == nil {
+		o.matcher = CompilePolicies(o.policies)
+	}
+	return func(ctx context.Context) (T, error) {
+		return execute(ctx, o, fn)
+	}
+}
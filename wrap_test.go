@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrap(t *testing.T) {
+	var calls int
+	fn := Wrap(func() error {
+		calls++
+		if calls%3 != 0 {
+			return errors.New("flaky")
+		}
+		return nil
+	}, WithAttempts(3), WithDelay(time.Millisecond))
+
+	// every call of the wrapped func is a retry sequence of its own
+	assert.Equal(t, nil, fn())
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, nil, fn())
+	assert.Equal(t, 6, calls)
+}
+
+func TestWrapContext(t *testing.T) {
+	fn := WrapContext(func(ctx context.Context) error {
+		return errors.New("flaky")
+	}, WithAttempts(5), WithDelay(time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err := fn(ctx)
+	assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestWrapT(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[int]int{}
+	fn := WrapTContext(func(ctx context.Context) (int, error) {
+		info, _ := AttemptInfoFromContext(ctx)
+		mu.Lock()
+		attempts[info.Attempt]++
+		mu.Unlock()
+		if info.Attempt < 2 {
+			return 0, errors.New("flaky")
+		}
+		return info.Attempt, nil
+	}, WithAttempts(3), WithDelay(time.Millisecond))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := fn(context.Background())
+			assert.Equal(t, nil, err)
+			assert.Equal(t, 2, v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, map[int]int{1: 4, 2: 4}, attempts)
+
+	v, err := WrapT(func() (string, error) { return "ok", nil })()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "ok", v)
+}